@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"Ttracker/internal/check"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	baselineFile   string
+	baselinePolicy string
+	baselineForce  bool
+)
+
+// baselineCmd represents the baseline command
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Snapshot the TODOs of a directory so tt check only flags new ones",
+	Long: `Baseline writes the TODOs of a directory to a file meant to be committed next
+to them. tt check --baseline then accepts those TODOs and only flags the ones
+added since, so a legacy codebase can adopt the check without fixing every TODO
+first.
+
+TODOs are recorded by a fingerprint of their file, keyword and text, so they
+stay matched while code around them moves. Editing a TODO's text makes it new.
+
+The baseline is written to .ttbaseline.json in the directory unless --file is
+given. Keywords are read from the directory's .ttcheck.json like tt check does.
+
+Example:
+  tt baseline create                 # Snapshot the TODOs of the current directory
+  tt baseline update                 # Drop the TODOs resolved since
+  tt check --baseline .ttbaseline.json`,
+}
+
+var baselineCreateCmd = &cobra.Command{
+	Use:   "create [dir]",
+	Short: "Write a baseline of the current TODOs",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   baselineCreateRun,
+}
+
+var baselineUpdateCmd = &cobra.Command{
+	Use:   "update [dir]",
+	Short: "Drop resolved TODOs from the baseline, without adding new ones",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   baselineUpdateRun,
+}
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineCreateCmd, baselineUpdateCmd)
+
+	baselineCmd.PersistentFlags().StringVarP(&baselineFile, "file", "f", "", "Baseline file (default <dir>/.ttbaseline.json)")
+	baselineCmd.PersistentFlags().StringVar(&baselinePolicy, "policy", "", "Policy file to read keywords from instead of .ttcheck.json")
+	baselineCreateCmd.Flags().BoolVar(&baselineForce, "force", false, "Replace an existing baseline")
+}
+
+func baselineCreateRun(cmd *cobra.Command, args []string) {
+	dir, path := baselinePaths(args)
+	if _, err := os.Stat(path); err == nil && !baselineForce {
+		fmt.Printf("Baseline %s already exists. Use 'tt baseline update' to shrink it, or --force to replace it.\n", path)
+		return
+	}
+
+	report, err := baselineScan(dir)
+	if err != nil {
+		fmt.Printf("Error scanning %s: %v\n", dir, err)
+		return
+	}
+
+	if err := check.NewBaseline(report.Todos).Save(path); err != nil {
+		fmt.Printf("Error writing baseline: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote %d TODOs from %d files to %s\n", len(report.Todos), report.Files, path)
+}
+
+func baselineUpdateRun(cmd *cobra.Command, args []string) {
+	dir, path := baselinePaths(args)
+	baseline, err := check.LoadBaseline(path)
+	if os.IsNotExist(err) {
+		fmt.Printf("No baseline at %s. Use 'tt baseline create' to write one.\n", path)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error loading baseline: %v\n", err)
+		return
+	}
+
+	report, err := baselineScan(dir)
+	if err != nil {
+		fmt.Printf("Error scanning %s: %v\n", dir, err)
+		return
+	}
+
+	resolved := baseline.Update(report.Todos)
+	if err := baseline.Save(path); err != nil {
+		fmt.Printf("Error writing baseline: %v\n", err)
+		return
+	}
+
+	for _, entry := range resolved {
+		fmt.Printf("  %s %s\n", cyan(fmt.Sprintf("%s:%d", entry.File, entry.Line)), strings.Join(strings.Fields(entry.Comment), " "))
+	}
+	fmt.Printf("Dropped %d resolved TODOs, %d remain in %s\n", len(resolved), len(baseline.Entries), path)
+}
+
+// baselinePaths returns the directory to scan and the baseline file
+func baselinePaths(args []string) (string, string) {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	path := baselineFile
+	if path == "" {
+		path = filepath.Join(dir, check.BaselineFile)
+	}
+	return dir, path
+}
+
+// baselineScan finds the TODOs of dir the way tt check does
+func baselineScan(dir string) (*check.Report, error) {
+	policy, err := readCheckPolicy(dir, baselinePolicy)
+	if err != nil {
+		return nil, err
+	}
+	// Only the keywords matter, the TODOs found do not depend on other rules
+	return check.Run(dir, check.Policy{Keywords: policy.Keywords}, check.Options{})
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"Ttracker/internal/check"
+	"Ttracker/internal/sarif"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkPolicy        string
+	checkForbid        []string
+	checkForbidOn      []string
+	checkBranch        string
+	checkRequireOwner  bool
+	checkNoOverdue     bool
+	checkMaxPerPackage int
+	checkToday         string
+	checkFormat        string
+	checkBaseline      string
+)
+
+// Exit codes of tt check
+const (
+	checkExitViolations = 1
+	checkExitError      = 2
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check [dir]",
+	Short: "Check the TODOs of a directory against policies, for CI",
+	Long: `Check scans a directory, the current one by default, and fails when its TODOs
+break a policy. Nothing is registered or stored, and neither the tt
+configuration, the installed plugins nor git's excludes files (core.excludesFile
+and .git/info/exclude) are read, so a check gives the same result on every
+machine. Files are parsed by the parsers built into tt, files of other
+languages are read with their comment syntax like tt diff does, and files are
+ignored following the .ttignore and .gitignore files of the repository.
+
+Policies are read from .ttcheck.json at the root of the directory, or from the
+file given with --policy, and flags add to them:
+
+  {
+    "keywords": {"keywords": [{"name": "TODO"}, {"name": "FIXME", "severity": "high"}]},
+    "forbid": [{"keywords": ["FIXME"], "branches": ["main", "release/*"]}],
+    "require_owner": true,
+    "no_overdue": true,
+    "max_per_package": 20
+  }
+
+Owners and due dates are read from the TODOs themselves, e.g.
+TODO(alice, 2025-03-01): ..., TODO(@alice): ... or TODO: ... owner:alice due:2025-03-01.
+Packages are directories. Forbid rules without branches apply everywhere, the
+branch is detected from the CI environment or git unless --branch is given.
+
+The exit status is 0 when every policy holds, 1 when some are broken and 2 when
+the check could not run. --format json prints a machine-readable report, and
+--format sarif the violations as SARIF 2.1.0 for code scanning services.
+
+With --baseline, only TODOs missing from the baseline file written by
+tt baseline create are checked, and each of them breaks the check. Baseline
+entries whose TODOs were resolved are listed, tt baseline update drops them.
+
+Example:
+  tt check                                  # Check the current directory
+  tt check --forbid FIXME --forbid-on main  # No FIXME on main
+  tt check --require-owner --no-overdue     # Every TODO owned and on time
+  tt check --max-per-package 10 src/        # At most 10 TODOs per directory
+  tt check --format json > tt-check.json    # Write a machine-readable report
+  tt check --format sarif > tt.sarif        # Write the violations as SARIF
+  tt check --baseline .ttbaseline.json      # Only fail on TODOs added since the baseline`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  checkRun,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().StringVar(&checkPolicy, "policy", "", "Policy file to use instead of .ttcheck.json")
+	checkCmd.Flags().StringSliceVar(&checkForbid, "forbid", nil, "Keywords that are not allowed")
+	checkCmd.Flags().StringSliceVar(&checkForbidOn, "forbid-on", nil, "Only forbid the --forbid keywords on these branches (patterns allowed)")
+	checkCmd.Flags().StringVar(&checkBranch, "branch", "", "Branch to check against instead of the detected one")
+	checkCmd.Flags().BoolVar(&checkRequireOwner, "require-owner", false, "Require every TODO to name an owner")
+	checkCmd.Flags().BoolVar(&checkNoOverdue, "no-overdue", false, "Fail on TODOs past their due date")
+	checkCmd.Flags().IntVar(&checkMaxPerPackage, "max-per-package", 0, "Most TODOs allowed in one directory, 0 for no limit")
+	checkCmd.Flags().StringVar(&checkToday, "today", "", "Date to check due dates against, YYYY-MM-DD (default today)")
+	checkCmd.Flags().StringVar(&checkFormat, "format", "text", "Output format: text, json or sarif")
+	checkCmd.Flags().StringVar(&checkBaseline, "baseline", "", "Baseline file listing the TODOs to accept")
+}
+
+func checkRun(cmd *cobra.Command, args []string) {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	if checkFormat != "text" && checkFormat != "json" && checkFormat != "sarif" {
+		fmt.Printf("Error: unknown format '%s', expected text, json or sarif\n", checkFormat)
+		os.Exit(checkExitError)
+	}
+
+	policy, err := loadCheckPolicy(cmd, dir)
+	if err != nil {
+		fmt.Printf("Error loading policy: %v\n", err)
+		os.Exit(checkExitError)
+	}
+
+	opts := check.Options{Branch: checkBranch}
+	if opts.Branch == "" {
+		opts.Branch = check.DetectBranch(dir)
+	}
+	if checkToday != "" {
+		if opts.Today, err = time.Parse("2006-01-02", checkToday); err != nil {
+			fmt.Printf("Error: invalid --today date '%s', expected YYYY-MM-DD\n", checkToday)
+			os.Exit(checkExitError)
+		}
+	}
+
+	if checkBaseline != "" {
+		if opts.Baseline, err = check.LoadBaseline(checkBaseline); err != nil {
+			fmt.Printf("Error loading baseline: %v\n", err)
+			os.Exit(checkExitError)
+		}
+	}
+
+	report, err := check.Run(dir, policy, opts)
+	if err != nil {
+		fmt.Printf("Error checking %s: %v\n", dir, err)
+		os.Exit(checkExitError)
+	}
+
+	switch checkFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	case "sarif":
+		log := sarif.NewLog()
+		run := log.AddRun(report.Root)
+		for _, v := range report.Violations {
+			run.AddViolation(v)
+		}
+		err = log.Write(os.Stdout)
+	default:
+		printCheckReport(report)
+	}
+	if err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		os.Exit(checkExitError)
+	}
+
+	if !report.Passed {
+		os.Exit(checkExitViolations)
+	}
+}
+
+// loadCheckPolicy reads the policy file and applies the policy flags on top
+func loadCheckPolicy(cmd *cobra.Command, dir string) (check.Policy, error) {
+	policy, err := readCheckPolicy(dir, checkPolicy)
+	if err != nil {
+		return policy, err
+	}
+
+	if len(checkForbid) > 0 {
+		policy.Forbid = append(policy.Forbid, check.ForbidRule{Keywords: checkForbid, Branches: checkForbidOn})
+	} else if len(checkForbidOn) > 0 {
+		return policy, fmt.Errorf("--forbid-on needs keywords to forbid with --forbid")
+	}
+	if checkRequireOwner {
+		policy.RequireOwner = true
+	}
+	if checkNoOverdue {
+		policy.NoOverdue = true
+	}
+	if cmd.Flags().Lookup("max-per-package").Changed {
+		policy.MaxPerPackage = checkMaxPerPackage
+	}
+	return policy, nil
+}
+
+// readCheckPolicy reads the policy file at path, or the one in dir when path
+// is empty. No policy file is an empty policy.
+func readCheckPolicy(dir, path string) (check.Policy, error) {
+	if path == "" {
+		path = filepath.Join(dir, check.PolicyFile)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return check.Policy{}, nil
+		}
+	}
+	return check.LoadPolicy(path)
+}
+
+// printCheckReport prints the violations of a check and a summary
+func printCheckReport(report *check.Report) {
+	for _, v := range report.Violations {
+		location := v.Package + "/"
+		if v.File != "" {
+			location = fmt.Sprintf("%s:%d", v.File, v.Line)
+		}
+		fmt.Printf("%s: %s %s\n", bold(location), yellow(v.Rule), v.Message)
+		if v.Item != nil {
+			fmt.Printf("    %s\n", strings.Join(strings.Fields(v.Item.Comment), " "))
+		}
+	}
+	if len(report.Violations) > 0 {
+		fmt.Println()
+	}
+
+	if len(report.Resolved) > 0 {
+		fmt.Println("Resolved since the baseline, 'tt baseline update' drops them from it:")
+		for _, entry := range report.Resolved {
+			fmt.Printf("  %s %s\n", cyan(fmt.Sprintf("%s:%d", entry.File, entry.Line)), strings.Join(strings.Fields(entry.Comment), " "))
+		}
+		fmt.Println()
+	}
+
+	summary := fmt.Sprintf("Checked %d files: %d TODOs, %d violations", report.Files, len(report.Todos), len(report.Violations))
+	if checkBaseline != "" {
+		summary = fmt.Sprintf("Checked %d files: %d new TODOs, %d in the baseline, %d violations",
+			report.Files, len(report.Todos), report.Baselined, len(report.Violations))
+	}
+	if report.Branch != "" {
+		summary += fmt.Sprintf(" on branch %s", report.Branch)
+	}
+	if report.Passed {
+		fmt.Println(green(summary))
+	} else {
+		fmt.Println(red(summary))
+	}
+}
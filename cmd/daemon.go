@@ -5,13 +5,16 @@ package cmd
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
-	"Ttracker/internal/config"
-	"Ttracker/internal/scan"
+	"Ttracker/internal/api"
+	"Ttracker/internal/daemon"
+	"Ttracker/internal/events"
 	"Ttracker/internal/watcher"
 
 	"github.com/spf13/cobra"
@@ -24,19 +27,152 @@ var daemonCmd = &cobra.Command{
 	Long: `Runs Ttracker as a daemon process that continuously monitors 
 tracked projects for changes and updates TODO list automatically.
 
+Without a subcommand the daemon runs in the foreground. "tt daemon start" detaches
+it and writes its output to the log file in the state directory, where its PID
+file and status report are kept as well.
+
 Example:
-  tt daemon  # Start Ttracker in daemon mode
+  tt daemon          # Run Ttracker in the foreground
+  tt daemon start    # Start the daemon in the background
+  tt daemon status   # Show uptime, watched projects and last scans
+  tt daemon restart  # Restart the background daemon
+  tt daemon stop     # Stop the background daemon
+
+The daemon picks up projects tracked or untracked, plugin changes and ignore
+rule changes while it runs. Sending it SIGHUP forces a full reload and rescan.
+
+While running, the daemon serves a local HTTP API on the daemon.sock Unix socket
+in the state directory. Other tt commands use it to track, untrack, list and
+rescan through the daemon, so only the daemon writes the TODO store.
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		runDaemon()
 	},
 }
 
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the daemon in the foreground",
+	Run: func(cmd *cobra.Command, args []string) {
+		runDaemon()
+	},
+}
+
+var daemonStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the daemon in the background",
+	Run: func(cmd *cobra.Command, args []string) {
+		startDaemon()
+	},
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the background daemon",
+	Run: func(cmd *cobra.Command, args []string) {
+		stopDaemon()
+	},
+}
+
+var daemonRestartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart the background daemon",
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, running := daemon.Running(); running && !stopDaemon() {
+			return
+		}
+		startDaemon()
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the daemon is running and what it is watching",
+	Run: func(cmd *cobra.Command, args []string) {
+		daemonStatus()
+	},
+}
+
+// how long start and stop wait for the daemon
+const daemonTimeout = 10 * time.Second
+
+// how often the running daemon refreshes its status report
+const statusInterval = 2 * time.Second
+
 func init() {
 	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonRunCmd, daemonStartCmd, daemonStopCmd, daemonRestartCmd, daemonStatusCmd)
+}
+
+func startDaemon() {
+	pid, err := daemon.Start(daemonTimeout)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Ttracker daemon started (pid %d), logging to %s\n", pid, daemon.LogFile())
+}
+
+// stopDaemon stops the background daemon and reports whether it stopped
+func stopDaemon() bool {
+	pid, err := daemon.Stop(daemonTimeout)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return false
+	}
+	fmt.Printf("Ttracker daemon stopped (pid %d)\n", pid)
+	return true
+}
+
+func daemonStatus() {
+	pid, running := daemon.Running()
+	if !running {
+		fmt.Println("Ttracker daemon is not running")
+		return
+	}
+
+	status, err := daemon.ReadStatus()
+	if err != nil {
+		fmt.Printf("Ttracker daemon is running (pid %d), no status available: %v\n", pid, err)
+		return
+	}
+
+	fmt.Printf("Ttracker daemon is running (pid %d)\n", pid)
+	fmt.Printf("  Uptime:      %s\n", status.Uptime())
+	fmt.Printf("  Directory:   %s\n", status.Dir)
+	fmt.Printf("  Log file:    %s\n", daemon.LogFile())
+	fmt.Printf("  Queue depth: %d\n", status.QueueDepth)
+	fmt.Printf("  Projects:    %d\n", len(status.Projects))
+	for _, project := range status.Projects {
+		lastScan := "never"
+		if project.LastScan != nil {
+			lastScan = fmt.Sprintf("%s (%s ago)", project.LastScan.Format("2006-01-02 15:04:05"), time.Since(*project.LastScan).Round(time.Second))
+		}
+		fmt.Printf("    %s: %s\n      Last scan: %s\n", project.Name, project.Path, lastScan)
+		if project.Polled {
+			fmt.Printf("      Polling:   %s\n", project.PollCause)
+		}
+		if project.LastError != "" {
+			fmt.Printf("      Last error: %s\n", project.LastError)
+		}
+	}
 }
 
 func runDaemon() {
+	// Only one daemon may run at a time
+	lock, err := daemon.Acquire()
+	if err != nil {
+		if err == daemon.ErrRunning {
+			pid, _ := daemon.Running()
+			fmt.Printf("Error: the Ttracker daemon is already running (pid %d)\n", pid)
+		} else {
+			fmt.Printf("Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+	defer lock.Release()
+	startedAt := time.Now()
+
 	fmt.Println("Starting Ttracker daemon...")
 
 	// Create required directories
@@ -68,56 +204,121 @@ func runDaemon() {
 		os.Exit(1)
 	}
 
-	// Scan all projects before starting the watcher to ensure we have up-to-date TODOs
-	fmt.Println("Scanning all projects for TODOs...")
-	if err := scanAllProjects(pluginConfigPath, storeFilePath); err != nil {
-		fmt.Printf("Error during initial scan: %v\n", err)
-		// Continue anyway - this isn't fatal
+	// Keep every event in the event log read by "tt watch --history"
+	eventLog, err := events.OpenLog(daemon.EventLogFile())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
+	defer eventLog.Close()
+	w.Events.SetLog(eventLog)
 
 	if err := w.Start(); err != nil {
 		fmt.Printf("Error starting watcher: %v\n", err)
+		lock.Release()
 		os.Exit(1)
 	}
 
-	// Handle graceful shutdown
+	// Serve the API the CLI uses while the daemon runs
+	server := api.NewServer(w, startedAt)
+	if err := server.Listen(daemon.SocketFile()); err != nil {
+		fmt.Printf("Error starting API: %v\n", err)
+		w.Stop()
+		lock.Release()
+		os.Exit(1)
+	}
+
+	// Bring the TODOs of every project up to date. The scans run in the
+	// background through the scheduler, so the daemon is ready right away and
+	// the scans never race the scans of file changes.
+	fmt.Println("Scanning all projects for TODOs...")
+	w.ScanAll()
+
+	// Keep the status report read by "tt daemon status" up to date
+	stopStatus := make(chan struct{})
+	go writeDaemonStatus(w, startedAt, stopStatus)
+
+	// Tell systemd the daemon is ready when it runs as a notify service
+	notifyService(fmt.Sprintf("READY=1\nSTATUS=Watching %d projects", len(w.Projects())))
+	if interval, ok := daemon.WatchdogInterval(); ok {
+		go pingWatchdog(w, interval, stopStatus)
+	}
+
+	// Handle graceful shutdown, SIGHUP reloads the configuration
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	fmt.Println("Ttracker daemon is running. Press Ctrl+C to stop.")
 
 	// Wait for termination signal
-	<-sigChan
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		fmt.Println("Reloading configuration...")
+		notifyService("RELOADING=1")
+		if err := w.Reload(); err != nil {
+			fmt.Printf("Error reloading configuration: %v\n", err)
+		}
+		notifyService(fmt.Sprintf("READY=1\nSTATUS=Watching %d projects", len(w.Projects())))
+	}
 	fmt.Println("\nShutting down Ttracker daemon...")
+	notifyService("STOPPING=1")
+	close(stopStatus)
+	server.Close()
 
 	// Stop the watcher
 	if err := w.Stop(); err != nil {
 		fmt.Printf("Error stopping watcher: %v\n", err)
+		lock.Release()
 		os.Exit(1)
 	}
+	w.Events.SetLog(nil)
 
 	fmt.Println("Ttracker daemon stopped.")
 }
 
-// scanAllProjects scans all tracked projects to ensure TODOs are up to date
-func scanAllProjects(pluginConfigPath, storeFilePath string) error {
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		return fmt.Errorf("error loading config: %v", err)
+// writeDaemonStatus refreshes the daemon's status report until stop is closed
+func writeDaemonStatus(w *watcher.ProjectWatcher, startedAt time.Time, stop chan struct{}) {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := daemon.WriteStatus(daemon.NewStatus(w, startedAt)); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
 	}
+}
 
-	if len(cfg.Projects) == 0 {
-		fmt.Println("No projects to scan.")
-		return nil
+// notifyService sends a state change to systemd, if it started the daemon
+func notifyService(state string) {
+	if _, err := daemon.Notify(state); err != nil {
+		log.Printf("Warning: %v", err)
 	}
+}
 
-	for name, path := range cfg.Projects {
-		fmt.Printf("Scanning project '%s'...\n", name)
-		if err := scan.RunScan(path, name, pluginConfigPath, storeFilePath); err != nil {
-			fmt.Printf("Error scanning project '%s': %v\n", name, err)
-			// Continue with other projects
+// pingWatchdog tells the systemd watchdog the daemon is alive, twice per
+// watchdog interval, as long as the watcher keeps handling events
+func pingWatchdog(w *watcher.ProjectWatcher, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if w.Alive(interval / 4) {
+				notifyService("WATCHDOG=1")
+			} else {
+				log.Printf("Warning: the file watcher is not responding")
+			}
+		case <-stop:
+			return
 		}
 	}
-
-	return nil
 }
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"Ttracker/internal/gitdiff"
+	"Ttracker/internal/ignore"
+	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFormat  string
+	diffSummary bool
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [<rev>..<rev> | -]",
+	Short: "Show the TODOs a change adds, removes or edits",
+	Long: `Diff compares the TODOs of two git revisions of the repository in the current
+directory. Files are read from git as they were at each revision, nothing is
+checked out, and only the files the change touches are scanned.
+
+Revisions are given as in git: A..B compares A with B, A...B compares B with
+where it branched off A, and A alone compares A with the working tree. Without
+a range the working tree, untracked files included, is compared with HEAD.
+
+With - a unified diff is read from standard input instead, e.g. from git diff,
+diff -u or a patch file. TODOs are then found in the lines of the diff only,
+using the comment syntax of each file's language.
+
+TODOs whose text is the same on both sides are unchanged, even when they moved.
+A TODO removed where another is added was edited. Keywords are read from
+.ttcheck.json at the root of the repository like tt check does, and like it
+only the parsers built into tt are used and git's excludes files are not read,
+so a diff gives the same result on every machine.
+
+Example:
+  tt diff                        # Uncommitted changes
+  tt diff main...HEAD            # The current branch since it left main
+  tt diff v1.2..v1.3 --format json
+  tt diff main...HEAD --summary  # "This change adds 3 TODOs and resolves 5."
+  git diff --cached | tt diff -  # A diff from standard input`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  diffRun,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format: text or json")
+	diffCmd.Flags().BoolVarP(&diffSummary, "summary", "s", false, "Only print a one-line summary")
+}
+
+func diffRun(cmd *cobra.Command, args []string) {
+	var spec string
+	if len(args) > 0 {
+		spec = args[0]
+	}
+	if diffFormat != "text" && diffFormat != "json" {
+		fmt.Printf("Error: unknown format '%s', expected text or json\n", diffFormat)
+		return
+	}
+
+	// Like tt check, only the parsers built into tt are used
+	mgr := scan.NewBuiltinManager()
+	mgr.SetQuiet(true)
+
+	var result gitdiff.Result
+	if spec == "-" {
+		if err := setDiffKeywords(mgr, "."); err != nil {
+			fmt.Printf("Error loading policy: %v\n", err)
+			return
+		}
+		files, err := gitdiff.ParsePatch(os.Stdin)
+		if err != nil {
+			fmt.Printf("Error reading diff: %v\n", err)
+			return
+		}
+		result = gitdiff.FromPatch(mgr, files)
+	} else {
+		repo, err := gitdiff.OpenRepo(".", mgr)
+		if err != nil {
+			fmt.Printf("Error: not in a git repository: %v\n", err)
+			return
+		}
+		if err := setDiffKeywords(mgr, repo.Root); err != nil {
+			fmt.Printf("Error loading policy: %v\n", err)
+			return
+		}
+		if repo.Ignore, err = ignore.NewRepoIgnoreManager(repo.Root); err != nil {
+			fmt.Printf("Error loading ignore files: %v\n", err)
+			return
+		}
+
+		from, to, err := repo.ResolveRange(spec)
+		if err != nil {
+			fmt.Printf("Error resolving %s: %v\n", spec, err)
+			return
+		}
+		if result, err = repo.Diff(from, to); err != nil {
+			fmt.Printf("Error comparing revisions: %v\n", err)
+			return
+		}
+	}
+
+	switch {
+	case diffFormat == "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fmt.Printf("Error writing result: %v\n", err)
+		}
+	case diffSummary:
+		fmt.Printf("This change %s.\n", result.Summary())
+	default:
+		printDiff(result)
+	}
+}
+
+// setDiffKeywords matches the keywords of the policy file in dir, if any
+func setDiffKeywords(mgr *scan.Manager, dir string) error {
+	policy, err := readCheckPolicy(dir, "")
+	if err != nil {
+		return err
+	}
+	return mgr.SetKeywords(policy.KeywordConfig())
+}
+
+// printDiff prints the TODOs of a change and a summary
+func printDiff(result gitdiff.Result) {
+	line := func(todo store.Todo) string {
+		location := cyan(fmt.Sprintf("%s:%d", todo.FilePath, todo.LineNumber))
+		text := strings.Join(strings.Fields(todo.Comment), " ")
+		if todo.Function != "" {
+			return fmt.Sprintf("%s %s (in %s)", location, text, todo.Function)
+		}
+		return fmt.Sprintf("%s %s", location, text)
+	}
+
+	for _, todo := range result.Added {
+		fmt.Printf("%s %s\n", green("+"), line(todo))
+	}
+	for _, edit := range result.Edited {
+		fmt.Printf("%s %s\n", yellow("~"), line(edit.After))
+		fmt.Printf("    was: %s\n", strings.Join(strings.Fields(edit.Before.Comment), " "))
+	}
+	for _, todo := range result.Removed {
+		fmt.Printf("%s %s\n", red("-"), line(todo))
+	}
+	if len(result.Added)+len(result.Edited)+len(result.Removed) > 0 {
+		fmt.Println()
+	}
+	fmt.Println(bold(fmt.Sprintf("This change %s.", result.Summary())))
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"Ttracker/internal/config"
+	"Ttracker/internal/ignore"
 
 	"github.com/spf13/cobra"
 )
@@ -19,7 +20,10 @@ var ignoreCmd = &cobra.Command{
 should be ignored when scanning for TODOs.
 
 The ignore patterns are stored in a .ttignore file in the project root directory.
-Patterns can be specified in a format similar to .gitignore:
+Patterns follow the .gitignore rules: the last matching pattern wins, "!" re-includes
+a path, a leading or middle "/" anchors a pattern to the directory of its .ttignore,
+"**" matches any number of directories and a trailing "/" only matches directories.
+Subdirectories may have their own .ttignore, which takes precedence over the ones above:
 
   # Ignore specific files
   *.log
@@ -33,7 +37,14 @@ Patterns can be specified in a format similar to .gitignore:
 
   # Ignore specific paths
   build/out/
-  test/data/
+  /test/data/
+  docs/**/*.tmp
+
+  # Keep a file that would otherwise be ignored
+  !keep.log
+
+Unless disabled with --git=false, the project's .gitignore files, .git/info/exclude and
+git's global excludes file are used as well, with .ttignore taking precedence over them.
 
 If no project name is provided or if "." is used, the command will use the default project.
 
@@ -43,7 +54,9 @@ Example:
   tt ignore my-project         # Show patterns for specific project
   tt ignore --add "*.log"      # Add pattern to default project
   tt ignore . --add "*.log"    # Add pattern to default project
-  tt ignore my-project --add "*.log"  # Add pattern to specific project`,
+  tt ignore my-project --add "*.log"  # Add pattern to specific project
+  tt ignore --explain build/out.go     # Show the rule ignoring a path
+  tt ignore my-project --git=false     # Stop using the project's git ignore rules`,
 	Run: ignoreRun,
 }
 
@@ -54,6 +67,8 @@ func init() {
 	ignoreCmd.Flags().StringP("remove", "r", "", "Remove a pattern")
 	ignoreCmd.Flags().BoolP("list", "l", false, "List all ignore patterns")
 	ignoreCmd.Flags().BoolP("clear", "c", false, "Clear all ignore patterns")
+	ignoreCmd.Flags().StringP("explain", "e", "", "Show which rule decides whether a path is ignored")
+	ignoreCmd.Flags().Bool("git", true, "Use the project's .gitignore and git exclude files (set with --git=false)")
 }
 
 func ignoreRun(cmd *cobra.Command, args []string) {
@@ -107,6 +122,31 @@ func ignoreRun(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if cmd.Flags().Lookup("git").Changed {
+		useGit, _ := cmd.Flags().GetBool("git")
+		if cfg.Settings == nil {
+			cfg.Settings = make(map[string]config.ProjectSettings)
+		}
+		settings := cfg.Settings[projectName]
+		settings.GitIgnore = &useGit
+		cfg.Settings[projectName] = settings
+		if err := config.SaveConfig(cfg); err != nil {
+			fmt.Printf("Error saving config: %v\n", err)
+			return
+		}
+		if useGit {
+			fmt.Printf("Project '%s' now uses its git ignore rules\n", projectName)
+		} else {
+			fmt.Printf("Project '%s' no longer uses its git ignore rules\n", projectName)
+		}
+		return
+	}
+
+	if explainPath, _ := cmd.Flags().GetString("explain"); explainPath != "" {
+		explainIgnore(cfg, projectName, projectPath, explainPath)
+		return
+	}
+
 	// Read existing patterns
 	patterns := make([]string, 0)
 	if content, err := os.ReadFile(ignoreFile); err == nil {
@@ -166,6 +206,41 @@ func ignoreRun(cmd *cobra.Command, args []string) {
 	}
 }
 
+// explainIgnore reports the rule deciding whether path is ignored in a project
+func explainIgnore(cfg config.Config, projectName, projectPath, path string) {
+	// Relative paths are looked up from the current directory, then the project
+	if !filepath.IsAbs(path) {
+		if _, err := os.Stat(path); err != nil {
+			path = filepath.Join(projectPath, path)
+		}
+	}
+	path, _ = filepath.Abs(path)
+	info, err := os.Stat(path)
+	isDir := err == nil && info.IsDir()
+
+	ignoreMgr, err := ignore.NewProjectIgnoreManager(projectPath, cfg.UseGitIgnore(projectName))
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	rule := ignoreMgr.Match(path, isDir)
+	switch {
+	case rule == nil:
+		fmt.Printf("%s is not ignored\n", path)
+	case rule.Negate:
+		fmt.Printf("%s is not ignored, it is re-included by %s\n", path, describeRule(rule))
+	default:
+		fmt.Printf("%s is ignored by %s\n", path, describeRule(rule))
+	}
+}
+
+func describeRule(rule *ignore.IgnorePattern) string {
+	if rule.Source == "" {
+		return "the built-in pattern " + rule.String()
+	}
+	return rule.String()
+}
+
 func filterPatterns(patterns []string) []string {
 	filtered := make([]string, 0)
 	for _, pattern := range patterns {
@@ -5,14 +5,16 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
+
+	"Ttracker/internal/daemon"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	installPath string
-	force       bool
+	installPath    string
+	force          bool
+	installService bool
 )
 
 // installCmd represents the install command
@@ -20,12 +22,19 @@ var installCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Install Ttracker to the system",
 	Long: `Install Ttracker to the system, making the 'tt' command available globally.
-This will copy the binary to /usr/local/bin (requires sudo) or $HOME/.local/bin.
+This will copy the binary to /usr/local/bin or $HOME/.local/bin. sudo is only used
+when the target directory is not writable by the current user.
+
+With --service a systemd user unit running "tt daemon" is installed, enabled and
+started as well. The daemon runs in the current directory, where its data files
+live, reports readiness and watchdog pings to systemd and logs to the journal
+(journalctl --user -u ttracker).
 
 Example:
   tt install              # Install to system (auto-detect location)
   tt install --path /usr/local/bin  # Install to specific location
-  tt install --force     # Force reinstall if already installed`,
+  tt install --force     # Force reinstall if already installed
+  tt install --service   # Also run the daemon as a systemd user service`,
 	Run: installRun,
 }
 
@@ -34,11 +43,13 @@ var uninstallCmd = &cobra.Command{
 	Use:   "uninstall",
 	Short: "Uninstall Ttracker from the system",
 	Long: `Remove Ttracker from the system, making the 'tt' command no longer available globally.
-This will remove the binary from /usr/local/bin or $HOME/.local/bin.
+This will remove the binary from /usr/local/bin or $HOME/.local/bin, and the
+systemd user unit installed with "tt install --service".
 
 Example:
   tt uninstall           # Uninstall from system (auto-detect location)
-  tt uninstall --path /usr/local/bin  # Uninstall from specific location`,
+  tt uninstall --path /usr/local/bin  # Uninstall from specific location
+  tt uninstall --service # Only remove the systemd user unit`,
 	Run: uninstallRun,
 }
 
@@ -49,9 +60,11 @@ func init() {
 	// Add flags to install command
 	installCmd.Flags().StringVarP(&installPath, "path", "p", "", "Installation path (default: auto-detect)")
 	installCmd.Flags().BoolVarP(&force, "force", "f", false, "Force reinstall if already installed")
+	installCmd.Flags().BoolVar(&installService, "service", false, "Install and start a systemd user service for the daemon")
 
 	// Add flags to uninstall command
 	uninstallCmd.Flags().StringVarP(&installPath, "path", "p", "", "Installation path (default: auto-detect)")
+	uninstallCmd.Flags().BoolVar(&installService, "service", false, "Only remove the systemd user service")
 }
 
 func installRun(cmd *cobra.Command, args []string) {
@@ -63,12 +76,10 @@ func installRun(cmd *cobra.Command, args []string) {
 	}
 
 	// Determine installation path if not specified
-	var useSudo bool
 	if installPath == "" {
 		// Try to use /usr/local/bin first
 		if _, err := os.Stat("/usr/local/bin"); err == nil {
 			installPath = "/usr/local/bin/tt"
-			useSudo = true
 		} else {
 			// Fall back to $HOME/.local/bin
 			home, err := os.UserHomeDir()
@@ -85,52 +96,101 @@ func installRun(cmd *cobra.Command, args []string) {
 			}
 
 			installPath = filepath.Join(localBin, "tt")
-			useSudo = false
 		}
-	} else {
-		// Check if the specified path requires sudo
-		useSudo = strings.HasPrefix(installPath, "/usr/")
+	} else if info, err := os.Stat(installPath); err == nil && info.IsDir() {
+		// A directory was given, install the binary into it
+		installPath = filepath.Join(installPath, "tt")
+	}
+
+	if err := installBinary(execPath, installPath); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
 	}
 
+	if installService {
+		installDaemonService(installPath)
+	}
+}
+
+// installBinary copies the running binary to installPath, unless it is
+// already installed there and --force is not set
+func installBinary(execPath, installPath string) error {
 	// Check if already installed
 	if _, err := os.Stat(installPath); err == nil {
+		if sameFile(execPath, installPath) {
+			fmt.Printf("Ttracker is installed at %s\n", installPath)
+			return nil
+		}
 		if !force {
 			fmt.Printf("Ttracker is already installed at %s\n", installPath)
-			fmt.Println("Use --force to reinstall")
-			return
+			if !installService {
+				fmt.Println("Use --force to reinstall")
+			}
+			return nil
 		}
 		fmt.Printf("Reinstalling Ttracker at %s\n", installPath)
 	}
 
 	// Copy the binary
-	if useSudo {
-		// Use sudo to copy to /usr/local/bin
-		cmd := exec.Command("sudo", "cp", execPath, installPath)
+	if needsSudo(installPath) {
+		// install sets the mode as root, the file is not ours to chmod
+		cmd := exec.Command("sudo", "install", "-m", "0755", execPath, installPath)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
 		if err := cmd.Run(); err != nil {
-			fmt.Printf("Error installing with sudo: %v\n", err)
-			return
+			return fmt.Errorf("could not install with sudo: %v", err)
 		}
-	} else {
-		// Copy to user's local bin
-		if err := copyFile(execPath, installPath); err != nil {
-			fmt.Printf("Error copying binary: %v\n", err)
+	} else if err := copyFile(execPath, installPath); err != nil {
+		return fmt.Errorf("could not copy binary: %v", err)
+	}
+
+	fmt.Printf("Successfully installed Ttracker to %s\n", installPath)
+	fmt.Println("You can now use 'tt' from anywhere!")
+	return nil
+}
+
+// installDaemonService installs the systemd user service running the daemon
+// from the installed binary in the current directory
+func installDaemonService(installPath string) {
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error getting working directory: %v\n", err)
+		return
+	}
+
+	// Only one daemon can run, systemd takes over from one started by hand
+	if pid, running := daemon.Running(); running {
+		fmt.Printf("Stopping the running daemon (pid %d) so systemd can manage it\n", pid)
+		if _, err := daemon.Stop(daemonTimeout); err != nil {
+			fmt.Printf("Error: %v\n", err)
 			return
 		}
 	}
 
-	// Make the binary executable
-	if err := os.Chmod(installPath, 0755); err != nil {
-		fmt.Printf("Error setting executable permissions: %v\n", err)
+	if err := daemon.InstallService(installPath, dir); err != nil {
+		fmt.Printf("Error installing service: %v\n", err)
 		return
 	}
-
-	fmt.Printf("Successfully installed Ttracker to %s\n", installPath)
-	fmt.Println("You can now use 'tt' from anywhere!")
+	fmt.Printf("Installed and started %s (%s)\n", daemon.ServiceName, daemon.ServiceFile())
+	fmt.Println("The daemon runs in", dir)
+	fmt.Println("Follow its log with: journalctl --user -u ttracker -f")
 }
 
 func uninstallRun(cmd *cobra.Command, args []string) {
+	removed, err := daemon.UninstallService()
+	if err != nil {
+		fmt.Printf("Error removing service: %v\n", err)
+		return
+	}
+	if removed {
+		fmt.Printf("Removed %s\n", daemon.ServiceName)
+	} else if installService {
+		fmt.Println("The daemon service is not installed")
+	}
+	if installService {
+		return
+	}
+
 	// Determine installation path if not specified
-	var useSudo bool
 	if installPath == "" {
 		// Try to find the installation
 		paths := []string{
@@ -147,7 +207,6 @@ func uninstallRun(cmd *cobra.Command, args []string) {
 		for _, path := range paths {
 			if _, err := os.Stat(path); err == nil {
 				installPath = path
-				useSudo = strings.HasPrefix(path, "/usr/")
 				break
 			}
 		}
@@ -157,9 +216,8 @@ func uninstallRun(cmd *cobra.Command, args []string) {
 			fmt.Println("Use --path to specify the installation location")
 			return
 		}
-	} else {
-		// Check if the specified path requires sudo
-		useSudo = strings.HasPrefix(installPath, "/usr/")
+	} else if info, err := os.Stat(installPath); err == nil && info.IsDir() {
+		installPath = filepath.Join(installPath, "tt")
 	}
 
 	// Check if the file exists
@@ -169,8 +227,9 @@ func uninstallRun(cmd *cobra.Command, args []string) {
 	}
 
 	// Remove the binary
-	if useSudo {
+	if needsSudo(installPath) {
 		cmd := exec.Command("sudo", "rm", installPath)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
 		if err := cmd.Run(); err != nil {
 			fmt.Printf("Error removing with sudo: %v\n", err)
 			return
@@ -185,16 +244,32 @@ func uninstallRun(cmd *cobra.Command, args []string) {
 	fmt.Printf("Successfully uninstalled Ttracker from %s\n", installPath)
 }
 
+// sameFile reports whether two paths name the same file
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// copyFile copies the binary to dst through a temporary file that replaces
+// dst in one step, so a running tt at dst is not overwritten in place
 func copyFile(src, dst string) error {
 	input, err := os.ReadFile(src)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(dst, input, 0644)
-	if err != nil {
+	tmp := dst + ".tmp"
+	if err := os.WriteFile(tmp, input, 0755); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
 		return err
 	}
-
 	return nil
 }
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"Ttracker/internal/config"
+	"Ttracker/internal/keywords"
+
+	"github.com/spf13/cobra"
+)
+
+// keywordsCmd represents the keywords command
+var keywordsCmd = &cobra.Command{
+	Use:   "keywords [project-name]",
+	Short: "Manage the keywords that mark TODO comments",
+	Long: `Manage the keywords (TODO, FIXME, HACK, ...) that mark a comment as a TODO.
+
+Keywords can be set globally or per project. A global keyword list replaces
+the defaults (TODO and FIXME); keywords set for a project are added on top of
+the global list. Every keyword has a severity, and matching can be made case
+sensitive or relaxed to match inside words.
+
+The configuration is also passed to external parser plugins through the
+TT_KEYWORD_PATTERN and TT_KEYWORDS environment variables.
+
+If no project name is given the global keywords are used, "." selects the
+active project.
+
+Example:
+  tt keywords                                   # Show the global keywords
+  tt keywords --add HACK --severity low         # Track HACK everywhere
+  tt keywords my-project --add PERF             # Track PERF in one project
+  tt keywords . --remove PERF                   # Remove a keyword from the active project
+  tt keywords --case-sensitive=true             # Only match keywords exactly
+  tt keywords --word-boundary=false             # Also match keywords inside words`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  keywordsRun,
+}
+
+func init() {
+	rootCmd.AddCommand(keywordsCmd)
+
+	keywordsCmd.Flags().StringP("add", "a", "", "Add a keyword")
+	keywordsCmd.Flags().StringP("remove", "r", "", "Remove a keyword")
+	keywordsCmd.Flags().StringP("severity", "s", keywords.SeverityMedium, "Severity of the added keyword (low, medium, high, critical)")
+	keywordsCmd.Flags().Bool("case-sensitive", false, "Match keywords case sensitively")
+	keywordsCmd.Flags().Bool("word-boundary", true, "Only match keywords that stand alone as a word")
+}
+
+func keywordsRun(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	// Determine which layer to edit
+	projectName := ""
+	if len(args) > 0 {
+		projectName = args[0]
+		if projectName == "." {
+			projectName = ""
+			for name, path := range cfg.Projects {
+				if path == cfg.Active || name == cfg.Active {
+					projectName = name
+					break
+				}
+			}
+			if projectName == "" {
+				fmt.Println("Error: No active project set. Please specify a project name.")
+				return
+			}
+		} else if _, exists := cfg.Projects[projectName]; !exists {
+			fmt.Printf("Error: Project '%s' not found\n", projectName)
+			return
+		}
+	}
+
+	var layer *keywords.Config
+	if projectName == "" {
+		if cfg.Keywords == nil {
+			cfg.Keywords = &keywords.Config{}
+		}
+		layer = cfg.Keywords
+	} else {
+		if cfg.Settings == nil {
+			cfg.Settings = make(map[string]config.ProjectSettings)
+		}
+		settings := cfg.Settings[projectName]
+		if settings.Keywords == nil {
+			settings.Keywords = &keywords.Config{}
+		}
+		cfg.Settings[projectName] = settings
+		layer = settings.Keywords
+	}
+
+	changed := false
+
+	if cmd.Flags().Lookup("add").Changed {
+		name, _ := cmd.Flags().GetString("add")
+		severity, _ := cmd.Flags().GetString("severity")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			fmt.Println("Error: Keyword is required for --add")
+			return
+		}
+
+		// Adding to an empty global list starts from the defaults
+		if projectName == "" && len(layer.Keywords) == 0 {
+			layer.Keywords = keywords.DefaultKeywords()
+		}
+		layer.Keywords = removeKeyword(layer.Keywords, name)
+		layer.Keywords = append(layer.Keywords, keywords.Keyword{Name: name, Severity: severity})
+		fmt.Printf("Added keyword: %s (%s)\n", name, severity)
+		changed = true
+	}
+
+	if cmd.Flags().Lookup("remove").Changed {
+		name, _ := cmd.Flags().GetString("remove")
+		if projectName == "" && len(layer.Keywords) == 0 {
+			layer.Keywords = keywords.DefaultKeywords()
+		}
+		layer.Keywords = removeKeyword(layer.Keywords, name)
+		fmt.Printf("Removed keyword: %s\n", name)
+		changed = true
+	}
+
+	if cmd.Flags().Lookup("case-sensitive").Changed {
+		value, _ := cmd.Flags().GetBool("case-sensitive")
+		layer.CaseSensitive = &value
+		changed = true
+	}
+
+	if cmd.Flags().Lookup("word-boundary").Changed {
+		value, _ := cmd.Flags().GetBool("word-boundary")
+		layer.WordBoundary = &value
+		changed = true
+	}
+
+	if changed {
+		if err := config.SaveConfig(cfg); err != nil {
+			fmt.Printf("Error saving config: %v\n", err)
+			return
+		}
+	}
+
+	// Show the effective configuration
+	effective := cfg.KeywordsFor(projectName)
+	if projectName == "" {
+		fmt.Println("Global keywords:")
+	} else {
+		fmt.Printf("Keywords for project '%s':\n", projectName)
+	}
+	for _, kw := range effective.Keywords {
+		severity := kw.Severity
+		if severity == "" {
+			severity = "-"
+		}
+		fmt.Printf("  %-12s %s\n", kw.Name, severity)
+	}
+	fmt.Printf("Case sensitive: %v, word boundary: %v\n", effective.IsCaseSensitive(), effective.IsWordBoundary())
+}
+
+func removeKeyword(list []keywords.Keyword, name string) []keywords.Keyword {
+	filtered := make([]keywords.Keyword, 0, len(list))
+	for _, kw := range list {
+		if !strings.EqualFold(kw.Name, name) {
+			filtered = append(filtered, kw)
+		}
+	}
+	return filtered
+}
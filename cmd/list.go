@@ -18,7 +18,10 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"Ttracker/internal/api"
+	"Ttracker/internal/check"
 	"Ttracker/internal/config"
+	"Ttracker/internal/sarif"
 	"Ttracker/internal/scan"
 	"Ttracker/internal/store"
 
@@ -27,9 +30,12 @@ import (
 )
 
 var (
-	allProjects bool
-	treeView    bool
-	forceScan   bool
+	allProjects  bool
+	treeView     bool
+	forceScan    bool
+	expandTodos  bool
+	contextLines int
+	listFormat   string
 )
 
 var (
@@ -53,6 +59,9 @@ Example:
   tt list "My Project"      # List TODOs for a specific project
   tt list --all             # List TODOs for all projects
   tt list --rescan          # Force a scan before listing
+  tt list --expand          # Show multi-line TODOs in full
+  tt list --context 3       # Show 3 lines of source around each TODO
+  tt list --format sarif    # Write the TODOs as SARIF 2.1.0 for IDE viewers
 `,
 	Run: listRun,
 }
@@ -64,6 +73,9 @@ func init() {
 	listCmd.Flags().BoolVarP(&allProjects, "all", "a", false, "List TODOs for all projects")
 	listCmd.Flags().BoolVarP(&treeView, "tree", "t", true, "Display TODOs in a tree view (default)")
 	listCmd.Flags().BoolVarP(&forceScan, "rescan", "r", false, "Force a scan before listing TODOs")
+	listCmd.Flags().BoolVarP(&expandTodos, "expand", "e", false, "Show the full text of multi-line TODOs")
+	listCmd.Flags().IntVarP(&contextLines, "context", "C", 0, "Show this many lines of source before and after each TODO")
+	listCmd.Flags().StringVar(&listFormat, "format", "text", "Output format: text or sarif")
 
 	// Here you will define your flags and configuration settings.
 
@@ -76,7 +88,37 @@ func init() {
 	// listCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// rescan scans a project through the daemon when client is set, or directly otherwise
+func rescan(client *api.Client, path, name, pluginConfigPath, storeFilePath string) error {
+	if client != nil {
+		_, err := client.Scan(name)
+		return err
+	}
+	return scan.RunScan(path, name, pluginConfigPath, storeFilePath)
+}
+
+// loadTodos loads the TODO store from the daemon when client is set, or from
+// the store file otherwise
+func loadTodos(client *api.Client, storeFilePath string) (*store.Store, error) {
+	if client != nil {
+		return client.Todos(api.Query{})
+	}
+	if _, err := os.Stat(storeFilePath); err != nil {
+		return nil, err
+	}
+	return store.LoadStore(storeFilePath)
+}
+
 func listRun(cmd *cobra.Command, args []string) {
+	if listFormat != "text" && listFormat != "sarif" {
+		fmt.Printf("Error: unknown format '%s', expected text or sarif\n", listFormat)
+		return
+	}
+	if listFormat == "sarif" && forceScan {
+		fmt.Println("Error: --rescan prints its progress, rescan before listing with --format sarif")
+		return
+	}
+
 	// Define paths
 	storeFilePath := filepath.Join("data", "todos.json")
 	pluginConfigPath := filepath.Join("parsers", "plugin.json")
@@ -100,6 +142,9 @@ func listRun(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Go through the daemon when it runs so it stays the only writer of the store
+	client, _ := api.Connect()
+
 	// Force scan if requested
 	if forceScan {
 		if len(args) > 0 {
@@ -118,7 +163,7 @@ func listRun(cmd *cobra.Command, args []string) {
 			}
 
 			fmt.Printf("Scanning project '%s'...\n", projectName)
-			if err := scan.RunScan(path, projectName, pluginConfigPath, storeFilePath); err != nil {
+			if err := rescan(client, path, projectName, pluginConfigPath, storeFilePath); err != nil {
 				fmt.Printf("Error scanning project: %v\n", err)
 				return
 			}
@@ -133,7 +178,7 @@ func listRun(cmd *cobra.Command, args []string) {
 
 			for name, path := range cfg.Projects {
 				fmt.Printf("Scanning project '%s'...\n", name)
-				if err := scan.RunScan(path, name, pluginConfigPath, storeFilePath); err != nil {
+				if err := rescan(client, path, name, pluginConfigPath, storeFilePath); err != nil {
 					fmt.Printf("Error scanning project '%s': %v\n", name, err)
 					// Continue with other projects
 				}
@@ -166,7 +211,7 @@ func listRun(cmd *cobra.Command, args []string) {
 			}
 
 			fmt.Printf("Scanning active project '%s'...\n", projectName)
-			if err := scan.RunScan(cfg.Active, projectName, pluginConfigPath, storeFilePath); err != nil {
+			if err := rescan(client, cfg.Active, projectName, pluginConfigPath, storeFilePath); err != nil {
 				fmt.Printf("Error scanning project: %v\n", err)
 				return
 			}
@@ -174,12 +219,11 @@ func listRun(cmd *cobra.Command, args []string) {
 	}
 
 	// Load the store
-	if _, err := os.Stat(storeFilePath); os.IsNotExist(err) {
+	st, err := loadTodos(client, storeFilePath)
+	if os.IsNotExist(err) {
 		fmt.Println("No TODOs found. Use 'tt track' to track a project first.")
 		return
 	}
-
-	st, err := store.LoadStore(storeFilePath)
 	if err != nil {
 		fmt.Printf("Error loading TODO store: %v\n", err)
 		return
@@ -265,13 +309,37 @@ func listRun(cmd *cobra.Command, args []string) {
 	}
 
 	// Display TODOs
-	if treeView {
+	if listFormat == "sarif" {
+		writeListSARIF(st, projectsToShow)
+	} else if treeView {
 		displayTreeView(st, projectsToShow)
 	} else {
 		displayListView(st, projectsToShow)
 	}
 }
 
+// writeListSARIF writes the TODOs of projects as a SARIF log with a run per
+// project
+func writeListSARIF(st *store.Store, projects []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	log := sarif.NewLog()
+	for _, name := range projects {
+		root := cfg.Projects[name]
+		run := log.AddRun(root)
+		for _, item := range check.Items(root, st.Projects[name]) {
+			run.AddTodo(item)
+		}
+	}
+	if err := log.Write(os.Stdout); err != nil {
+		fmt.Printf("Error writing SARIF: %v\n", err)
+	}
+}
+
 // Get the appropriate function keyword based on file extension
 func getFunctionKeyword(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -305,6 +373,53 @@ func getFunctionKeyword(filename string) string {
 	return "function" // Default keyword
 }
 
+// todoCommentLines returns the lines of a TODO's comment. Continuation lines
+// of multi-line TODOs have their comment markers removed.
+func todoCommentLines(todo store.Todo) []string {
+	raw := strings.Split(strings.TrimSpace(todo.Comment), "\n")
+	lines := make([]string, 0, len(raw))
+	for i, line := range raw {
+		line = strings.Join(strings.Fields(line), " ")
+		if i > 0 {
+			line = scan.StripCommentMarkers(line)
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// collapseComment joins comment lines into a single line shortened for display
+func collapseComment(lines []string) string {
+	comment := strings.Join(lines, " ")
+	if len(comment) > 81 {
+		comment = comment[:80] + "..."
+	}
+	return comment
+}
+
+// lineRange formats a TODO's line, or line range for multi-line TODOs
+func lineRange(todo store.Todo) string {
+	if todo.EndLine > todo.LineNumber {
+		return fmt.Sprintf("%d-%d", todo.LineNumber, todo.EndLine)
+	}
+	return fmt.Sprintf("%d", todo.LineNumber)
+}
+
+// scopeInfo describes where a TODO lives, preferring the enclosing function
+// and falling back to the innermost scope (class, impl block...)
+func scopeInfo(todo store.Todo) string {
+	if todo.Function != "" {
+		keyword := getFunctionKeyword(filepath.Base(todo.FilePath))
+		return fmt.Sprintf("%s %s", keyword, green(todo.Function))
+	}
+	if todo.Scope != nil {
+		return fmt.Sprintf("%s %s", todo.Scope.Kind, green(todo.Scope.Path))
+	}
+	return ""
+}
+
 // displayTreeView shows TODOs in a file system tree-like structure
 func displayTreeView(st *store.Store, projectNames []string) {
 
@@ -430,33 +545,41 @@ func displayTreeView(st *store.Store, projectNames []string) {
 					}
 
 					// Clean the comment
-					comment := strings.TrimSpace(todo.Comment)
-					// Replace newlines with spaces in multi-line comments
-					comment = strings.ReplaceAll(comment, "\n", " ")
-					// Normalize whitespace
-					comment = strings.Join(strings.Fields(comment), " ")
-
-					if len(comment) > 81 {
-						comment = comment[:80] + "..."
-					}
+					commentLines := todoCommentLines(todo)
+					comment := collapseComment(commentLines)
 
 					// Get appropriate function keyword
 					functionInfo := ""
-					if todo.Function != "" {
-						keyword := getFunctionKeyword(file)
-						functionInfo = fmt.Sprintf(" @ %s %s", keyword, green(todo.Function))
+					if info := scopeInfo(todo); info != "" {
+						functionInfo = " @ " + info
 					}
 
+					snippet, snippetErr := loadSnippet(todo)
+
 					// Print the first line with metadata
-					fmt.Printf("%sLine %d%s:\n",
+					fmt.Printf("%sLine %s%s:%s\n",
 						firstLinePrefix,
-						todo.LineNumber,
-						functionInfo)
+						lineRange(todo),
+						functionInfo,
+						staleNote(snippet))
 
 					// Print the second line with the comment
-					fmt.Printf("%s%s\n",
-						secondLinePrefix,
-						cyan(comment))
+					if expandTodos {
+						for l, text := range commentLines {
+							prefix := secondLinePrefix
+							if l > 0 {
+								prefix = strings.Replace(prefix, "└── ", "    ", 1)
+							}
+							fmt.Printf("%s%s\n", prefix, cyan(text))
+						}
+					} else {
+						fmt.Printf("%s%s\n",
+							secondLinePrefix,
+							cyan(comment))
+					}
+
+					// Print the source around the TODO
+					printSnippet(strings.Replace(secondLinePrefix, "└── ", "    ", 1), todo, snippet, snippetErr)
 				}
 			}
 		}
@@ -506,20 +629,16 @@ func displayListView(st *store.Store, projectNames []string) {
 
 		for _, todo := range todos {
 			// Clean the comment
-			comment := strings.TrimSpace(todo.Comment)
-			comment = strings.ReplaceAll(comment, "\n", " ")
-			comment = strings.Join(strings.Fields(comment), " ")
-			if len(comment) > 81 {
-				comment = comment[:80] + "..."
+			commentLines := todoCommentLines(todo)
+			comment := collapseComment(commentLines)
+			if expandTodos {
+				comment = strings.Join(commentLines, "\n    ")
 			}
 
 			// Format the function info
 			functionInfo := ""
-			if todo.Function != "" {
-				// Get the file name from the path
-				file := filepath.Base(todo.FilePath)
-				keyword := getFunctionKeyword(file)
-				functionInfo = fmt.Sprintf("@ %s %s", keyword, green(todo.Function))
+			if info := scopeInfo(todo); info != "" {
+				functionInfo = "@ " + info
 			}
 
 			// Get relative path if project root is known
@@ -530,11 +649,19 @@ func displayListView(st *store.Store, projectNames []string) {
 				}
 			}
 
-			fmt.Fprintf(w, "%s:%d\t%s\t\n    %s\n",
+			snippet, snippetErr := loadSnippet(todo)
+			fmt.Fprintf(w, "%s:%s\t%s\t%s\n    %s\n",
 				yellow(displayPath),
-				todo.LineNumber,
+				lineRange(todo),
 				functionInfo,
+				staleNote(snippet),
 				cyan(comment))
+
+			// The snippet goes around the tabwriter, its lines are not columns
+			if contextLines > 0 {
+				w.Flush()
+				printSnippet("    ", todo, snippet, snippetErr)
+			}
 		}
 
 		w.Flush()
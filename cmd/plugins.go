@@ -4,10 +4,17 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"Ttracker/internal/config"
+	"Ttracker/internal/keywords"
 	plugin "Ttracker/internal/plugins"
+	"Ttracker/internal/plugintest"
+	"Ttracker/pkg/ttapi"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
@@ -56,10 +63,83 @@ EXAMPLES:
   tt plugins --default --id "js-standard"
 
 Each plugin must have a unique ID. To overwrite an existing plugin,
-use the --force flag with --add.`,
+use the --force flag with --add.
+
+Packaged plugins ship a ttplugin.json manifest and are installed with
+'tt plugins install', see 'tt plugins install --help'.`,
 	Run: pluginsRun,
 }
 
+// pluginsInstallCmd represents the plugins install command
+var pluginsInstallCmd = &cobra.Command{
+	Use:   "install <dir|archive.tar.gz>",
+	Short: "Install a packaged parser plugin",
+	Long: `Install a parser plugin from a directory or .tar.gz archive containing a
+ttplugin.json manifest:
+
+  {
+    "id": "js-standard",
+    "version": "1.2.0",
+    "language": "javascript",
+    "command": "bin/js_parser.js",
+    "extensions": [".js", ".jsx"]
+  }
+
+The plugin is copied to <Ttracker home>/plugins/<id>/<version> (the home is
+~/.ttracker unless TTRACKER_HOME is set) and registered with the absolute path
+of its command. Installing a newer version upgrades the plugin; older versions
+stay in the plugins directory so you can pin back to them.
+
+EXAMPLES:
+  tt plugins install ./js-parser
+  tt plugins install js-parser-1.2.0.tar.gz --pin
+  tt plugins install ./js-parser --force     # reinstall or downgrade`,
+	Args: cobra.ExactArgs(1),
+	Run:  pluginsInstallRun,
+}
+
+// pluginsTestCmd represents the plugins test command
+var pluginsTestCmd = &cobra.Command{
+	Use:   "test <id>",
+	Short: "Run a plugin against its fixtures and check it follows the protocol",
+	Long: `Run the conformance checks against a parser plugin:
+
+  - every fixture file is parsed and compared with its golden file
+    (<fixture>.golden.json): line numbers, line ranges, keywords, comments
+    and function attribution must match
+  - every output line must follow the "<line>[-<end>]: <comment>" protocol
+  - every reported line must exist and contain a TODO keyword
+  - function names are compared with the built-in scope detection
+  - the plugin must handle empty, binary and huge inputs within the timeout
+
+Fixtures are read from the testdata directory next to the plugin's manifest
+or command unless --fixtures is given. Use --update to write golden files
+from the plugin's current output, then review them before committing.
+
+EXAMPLES:
+  tt plugins test python-standard
+  tt plugins test python-standard --fixtures ./parsers/testdata --update
+  tt plugins test js-standard --timeout 2s`,
+	Args: cobra.ExactArgs(1),
+	Run:  pluginsTestRun,
+}
+
+// pluginsPinCmd represents the plugins pin command
+var pluginsPinCmd = &cobra.Command{
+	Use:   "pin <id> [version]",
+	Short: "Pin a plugin to its current or an installed version",
+	Args:  cobra.RangeArgs(1, 2),
+	Run:   pluginsPinRun,
+}
+
+// pluginsUnpinCmd represents the plugins unpin command
+var pluginsUnpinCmd = &cobra.Command{
+	Use:   "unpin <id>",
+	Short: "Allow a pinned plugin to be upgraded again",
+	Args:  cobra.ExactArgs(1),
+	Run:   pluginsUnpinRun,
+}
+
 func init() {
 	rootCmd.AddCommand(pluginsCmd)
 
@@ -81,6 +161,20 @@ func init() {
 	pluginsCmd.Flags().BoolP("verbose", "v", false, "Show detailed information when listing")
 	// pluginsCmd.Flags().String("format", "text", "Output format (text, json)")
 	pluginsCmd.Flags().Bool("no-validate", false, "Skip command validation when adding")
+
+	pluginsCmd.AddCommand(pluginsInstallCmd)
+	pluginsCmd.AddCommand(pluginsTestCmd)
+	pluginsCmd.AddCommand(pluginsPinCmd)
+	pluginsCmd.AddCommand(pluginsUnpinCmd)
+
+	pluginsInstallCmd.Flags().BoolP("force", "f", false, "Reinstall, downgrade or replace a pinned version")
+	pluginsInstallCmd.Flags().Bool("pin", false, "Pin the plugin to the installed version")
+	pluginsInstallCmd.Flags().BoolP("default", "d", false, "Set the plugin as the default for its language")
+
+	pluginsTestCmd.Flags().String("fixtures", "", "Directory holding fixtures and golden files (default: testdata next to the plugin)")
+	pluginsTestCmd.Flags().Bool("update", false, "Write golden files from the plugin's output")
+	pluginsTestCmd.Flags().Duration("timeout", 5*time.Second, "Maximum time the plugin may take for one file")
+	pluginsTestCmd.Flags().Int("huge-lines", 200000, "Number of lines in the generated huge input")
 }
 
 func pluginsRun(cmd *cobra.Command, args []string) {
@@ -116,8 +210,22 @@ func pluginsRun(cmd *cobra.Command, args []string) {
 	}
 
 	if isList {
+		verbose, _ := cmd.Flags().GetBool("verbose")
 		for _, plugin := range pluginMngr.Plugins {
 			fmt.Printf("%s:\n\tCommand: %s\n\tLanguage: %s\n\tExtensions: %s\n", plugin.ID, plugin.Command, plugin.Language, plugin.Extensions)
+			if plugin.Version != "" {
+				pinned := ""
+				if plugin.Pinned {
+					pinned = " (pinned)"
+				}
+				fmt.Printf("\tVersion: %s%s\n", plugin.Version, pinned)
+			}
+			if verbose && plugin.Source != "" {
+				fmt.Printf("\tInstalled from: %s\n\tInstalled in: %s\n", plugin.Source, plugin.Dir)
+			}
+		}
+		for _, reg := range ttapi.Registered() {
+			fmt.Printf("%s (compiled in):\n\tExtensions: %s\n", reg.Name, reg.New().SupportedExtensions())
 		}
 		return
 	}
@@ -140,7 +248,7 @@ func addPlugin(cmd *cobra.Command, pluginMngr *plugin.PluginManager, isDefault b
 	command, _ := cmd.Flags().GetString("cmd")
 	ext, _ := cmd.Flags().GetString("ext")
 	testFile, _ := cmd.Flags().GetString("testFile")
-	isValidate, _ := cmd.Flags().GetBool("no-validate")
+	skipValidate, _ := cmd.Flags().GetBool("no-validate")
 
 	if id == "" || lang == "" || command == "" || ext == "" {
 		fmt.Println("Error: --id, --lang, --cmd, and --ext are required for add operation")
@@ -152,6 +260,9 @@ func addPlugin(cmd *cobra.Command, pluginMngr *plugin.PluginManager, isDefault b
 		exts[i] = strings.TrimSpace(e)
 	}
 
+	// Store paths as absolute so the plugin works from any directory
+	command = plugin.ResolveCommand(command)
+
 	newPlugin := plugin.PluginConfig{
 		ID:         id,
 		Language:   lang,
@@ -164,9 +275,21 @@ func addPlugin(cmd *cobra.Command, pluginMngr *plugin.PluginManager, isDefault b
 		return
 	}
 
-	if isValidate {
+	if !skipValidate {
+		// Without a test file, check that the parser copes with an empty one
+		if testFile == "" {
+			tmp, err := os.CreateTemp("", "ttplugin-*"+exts[0])
+			if err != nil {
+				fmt.Println("Error creating test file:", err)
+				return
+			}
+			tmp.Close()
+			defer os.Remove(tmp.Name())
+			testFile = tmp.Name()
+		}
 		if err := pluginMngr.ValidateParserCommand(command, testFile); err != nil {
 			fmt.Println(err)
+			fmt.Println("Use --no-validate to add the plugin anyway")
 			return
 		}
 	}
@@ -175,6 +298,7 @@ func addPlugin(cmd *cobra.Command, pluginMngr *plugin.PluginManager, isDefault b
 		fmt.Println("Error saving plugin:", id, "Error:", err)
 		return
 	}
+	fmt.Printf("Added plugin %s. Run 'tt plugins test %s' to check it against its fixtures.\n", id, id)
 }
 
 func removePlugin(cmd *cobra.Command, pluginMngr *plugin.PluginManager) {
@@ -197,3 +321,182 @@ func removePlugin(cmd *cobra.Command, pluginMngr *plugin.PluginManager) {
 		return
 	}
 }
+
+func pluginsInstallRun(cmd *cobra.Command, args []string) {
+	force, _ := cmd.Flags().GetBool("force")
+	pin, _ := cmd.Flags().GetBool("pin")
+	setDefault, _ := cmd.Flags().GetBool("default")
+
+	pluginMngr, err := plugin.NewPluginManager()
+	if err != nil {
+		fmt.Println("Error instantiating Plugin Manager:", err)
+		return
+	}
+	pluginMngr.LoadPlugins()
+
+	// Remember installed versions to tell upgrades apart from new installs
+	previous := make(map[string]string)
+	for _, p := range pluginMngr.Plugins {
+		previous[p.ID] = p.Version
+	}
+
+	installed, err := pluginMngr.Install(args[0], plugin.InstallOptions{
+		Force:      force,
+		Pin:        pin,
+		SetDefault: setDefault,
+	})
+	if err != nil {
+		fmt.Println("Error installing plugin:", err)
+		return
+	}
+
+	if err := pluginMngr.SavePlugins(); err != nil {
+		fmt.Println("Error saving plugin:", installed.ID, "Error:", err)
+		return
+	}
+
+	if oldVersion, ok := previous[installed.ID]; ok {
+		fmt.Printf("Updated plugin %s from %s to %s\n", installed.ID, oldVersion, installed.Version)
+	} else {
+		fmt.Printf("Installed plugin %s %s for %s files %v\n", installed.ID, installed.Version, installed.Language, installed.Extensions)
+	}
+	fmt.Printf("Command: %s\n", installed.Command)
+}
+
+func pluginsPinRun(cmd *cobra.Command, args []string) {
+	version := ""
+	if len(args) > 1 {
+		version = args[1]
+	}
+
+	pluginMngr, err := plugin.NewPluginManager()
+	if err != nil {
+		fmt.Println("Error instantiating Plugin Manager:", err)
+		return
+	}
+	pluginMngr.LoadPlugins()
+
+	if err := pluginMngr.Pin(args[0], version); err != nil {
+		fmt.Println("Error pinning plugin:", err)
+		if versions, err := plugin.InstalledVersions(args[0]); err == nil {
+			fmt.Println("Installed versions:", strings.Join(versions, ", "))
+		}
+		return
+	}
+
+	if err := pluginMngr.SavePlugins(); err != nil {
+		fmt.Println("error saving plugin data, error:", err)
+		return
+	}
+	for _, p := range pluginMngr.Plugins {
+		if p.ID == args[0] {
+			fmt.Printf("Plugin %s pinned to version %s\n", p.ID, p.Version)
+		}
+	}
+}
+
+func pluginsUnpinRun(cmd *cobra.Command, args []string) {
+	pluginMngr, err := plugin.NewPluginManager()
+	if err != nil {
+		fmt.Println("Error instantiating Plugin Manager:", err)
+		return
+	}
+	pluginMngr.LoadPlugins()
+
+	if err := pluginMngr.Unpin(args[0]); err != nil {
+		fmt.Println("Error unpinning plugin:", err)
+		return
+	}
+
+	if err := pluginMngr.SavePlugins(); err != nil {
+		fmt.Println("error saving plugin data, error:", err)
+		return
+	}
+	fmt.Printf("Plugin %s can be upgraded again\n", args[0])
+}
+
+func pluginsTestRun(cmd *cobra.Command, args []string) {
+	fixtures, _ := cmd.Flags().GetString("fixtures")
+	update, _ := cmd.Flags().GetBool("update")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	hugeLines, _ := cmd.Flags().GetInt("huge-lines")
+
+	pluginMngr, err := plugin.NewPluginManager()
+	if err != nil {
+		fmt.Println("Error instantiating Plugin Manager:", err)
+		return
+	}
+	pluginMngr.LoadPlugins()
+
+	var target *plugin.PluginConfig
+	for i := range pluginMngr.Plugins {
+		if pluginMngr.Plugins[i].ID == args[0] {
+			target = &pluginMngr.Plugins[i]
+			break
+		}
+	}
+	if target == nil {
+		fmt.Printf("Error: cannot find plugin with id: %s\n", args[0])
+		return
+	}
+
+	if fixtures == "" {
+		fixtures = plugintest.DefaultFixturesDir(*target)
+	}
+
+	// Use the active project's keywords, as a scan would
+	matcher := keywords.DefaultMatcher()
+	if cfg, err := config.LoadConfig(); err == nil {
+		if m, err := keywords.NewMatcher(cfg.KeywordsFor(activeProjectName(cfg))); err == nil {
+			matcher = m
+		}
+	}
+
+	fmt.Printf("Testing plugin %s (%s)\nFixtures: %s\n\n", target.ID, target.Command, fixtures)
+	report, err := plugintest.Run(*target, plugintest.Options{
+		FixturesDir: fixtures,
+		Timeout:     timeout,
+		HugeLines:   hugeLines,
+		Update:      update,
+		Matcher:     matcher,
+	})
+	if err != nil {
+		fmt.Println("Error running plugin tests:", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, check := range report.Checks {
+		status := green("PASS")
+		if !check.Passed {
+			status = color.RedString("FAIL")
+			failed++
+		} else if check.Warning {
+			status = yellow("WARN")
+		}
+
+		duration := ""
+		if check.Duration > 0 {
+			duration = fmt.Sprintf(" (%v)", check.Duration.Round(time.Millisecond))
+		}
+		fmt.Printf("%s  %s%s\n", status, check.Name, duration)
+		for _, detail := range check.Details {
+			fmt.Printf("      %s\n", detail)
+		}
+	}
+
+	fmt.Printf("\n%d checks, %d failed\n", len(report.Checks), failed)
+	if !report.Passed() {
+		os.Exit(1)
+	}
+}
+
+// activeProjectName returns the name of the active project, or "" if none is set
+func activeProjectName(cfg config.Config) string {
+	for name, path := range cfg.Projects {
+		if name == cfg.Active || path == cfg.Active {
+			return name
+		}
+	}
+	return ""
+}
@@ -16,14 +16,35 @@ import (
 
 // projectsCmd represents the projects command
 var projectsCmd = &cobra.Command{
-	Use:   "projects",
+	Use:   "projects [project-name] [--poll=true|false] [--context N] [--signature]",
 	Short: "prints all project currently registered on Ttracker",
-	Long: `prints all projects currently registered on Ttracker`,
-	Run: projectsRun,
+	Long: `prints all projects currently registered on Ttracker
+
+With a project name and --poll, sets whether the daemon polls the project for
+changes instead of watching it with inotify. Polling suits network filesystems
+and trees too large for the inotify watch limit. A running daemon picks the
+setting up right away.
+
+--context and --signature set the source scans store with each TODO: lines
+before and after it, and the signature of its enclosing function. Without a
+project name they apply to every project without settings of its own. tt list
+--context shows the snippets.
+
+Example:
+  tt projects                       # List tracked projects
+  tt projects my-project --poll     # Poll my-project
+  tt projects my-project --poll=false
+  tt projects --context 3 --signature         # Store snippets for every project
+  tt projects my-project --context 0          # But not for my-project`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  projectsRun,
 }
 
 func init() {
 	rootCmd.AddCommand(projectsCmd)
+	projectsCmd.Flags().Bool("poll", false, "Poll the project for changes instead of using inotify (set with --poll=false)")
+	projectsCmd.Flags().Int("context", 0, "Lines of source scans store before and after each TODO")
+	projectsCmd.Flags().Bool("signature", false, "Store the signature of the function enclosing each TODO")
 
 	// Here you will define your flags and configuration settings.
 
@@ -43,6 +64,35 @@ func projectsRun(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if cmd.Flags().Lookup("poll").Changed {
+		if len(args) == 0 {
+			fmt.Println("Error: a project name is required with --poll")
+			return
+		}
+		name := args[0]
+		if _, exists := cfg.Projects[name]; !exists {
+			fmt.Printf("Error: Project '%s' not found\n", name)
+			return
+		}
+		poll, _ := cmd.Flags().GetBool("poll")
+		cfg.SetPoll(name, poll)
+		if err := config.SaveConfig(cfg); err != nil {
+			fmt.Printf("Error saving config: %v\n", err)
+			return
+		}
+		if poll {
+			fmt.Printf("Project '%s' is now polled for changes\n", name)
+		} else {
+			fmt.Printf("Project '%s' is now watched with inotify\n", name)
+		}
+		return
+	}
+
+	if cmd.Flags().Lookup("context").Changed || cmd.Flags().Lookup("signature").Changed {
+		setContext(cmd, cfg, args)
+		return
+	}
+
 	fmt.Println("tracked projects:")
 	projects := cfg.Projects
 	activeProject := cfg.Active
@@ -57,3 +107,45 @@ func projectsRun(cmd *cobra.Command, args []string) {
 	}
 	w.Flush()
 }
+
+// setContext saves the snippet settings of a project, or the global ones
+func setContext(cmd *cobra.Command, cfg config.Config, args []string) {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+		if _, exists := cfg.Projects[name]; !exists {
+			fmt.Printf("Error: Project '%s' not found\n", name)
+			return
+		}
+	}
+
+	// Unset flags keep their current value
+	settings := cfg.ContextFor(name)
+	if cmd.Flags().Lookup("context").Changed {
+		settings.Lines, _ = cmd.Flags().GetInt("context")
+		if settings.Lines < 0 {
+			fmt.Println("Error: --context must not be negative")
+			return
+		}
+	}
+	if cmd.Flags().Lookup("signature").Changed {
+		settings.Signature, _ = cmd.Flags().GetBool("signature")
+	}
+
+	cfg.SetContext(name, settings)
+	if err := config.SaveConfig(cfg); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+		return
+	}
+
+	target := "every project"
+	if name != "" {
+		target = fmt.Sprintf("project '%s'", name)
+	}
+	fmt.Printf("Scans of %s store %d lines of context", target, settings.Lines)
+	if settings.Signature {
+		fmt.Print(" and the enclosing signature")
+	}
+	fmt.Println(" with each TODO")
+	fmt.Println("The setting applies from the next scan")
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"Ttracker/internal/api"
+	"Ttracker/internal/config"
+	"Ttracker/internal/daemon"
+	"Ttracker/internal/events"
+	"Ttracker/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportHTML    string
+	reportContext int
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report --html <dir> [project-name...]",
+	Short: "Build a static HTML report of the TODOs",
+	Long: `Report builds a self-contained static site from the TODO store: an overview of
+every project and one page per project with statistics, a directory tree, a
+searchable and filterable table of TODOs and the source around each of them.
+
+When the daemon's event log is available (see tt watch --history), the pages
+chart how the number of TODOs changed over time. The site needs no server or
+tt to browse, e.g. as a CI artifact.
+
+Every project with TODOs is included unless project names are given.
+
+Example:
+  tt report --html out/                  # Report on every project
+  tt report --html out/ my-project       # Report on one project
+  tt report --html out/ --context 5      # Show 5 lines around each TODO`,
+	Run: reportRun,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringVar(&reportHTML, "html", "", "Directory to write the HTML report to")
+	reportCmd.Flags().IntVarP(&reportContext, "context", "C", 3, "Lines of source shown before and after each TODO")
+}
+
+func reportRun(cmd *cobra.Command, args []string) {
+	if reportHTML == "" {
+		fmt.Println("Error: --html <dir> is required")
+		fmt.Println("USAGE: tt report --html out/ [project-name...]")
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	client, _ := api.Connect()
+	st, err := loadTodos(client, filepath.Join("data", "todos.json"))
+	if os.IsNotExist(err) {
+		fmt.Println("No TODOs found. Use 'tt track' to track a project first.")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error loading TODO store: %v\n", err)
+		return
+	}
+
+	names := args
+	if len(names) == 0 {
+		for name := range st.Projects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var projects []report.Project
+	for _, name := range names {
+		todos, ok := st.Projects[name]
+		if !ok {
+			fmt.Printf("Project '%s' not found or has no TODOs.\n", name)
+			return
+		}
+		projects = append(projects, report.Project{Name: name, Root: cfg.Projects[name], Todos: todos})
+	}
+
+	// History is optional, the log only exists once the daemon ran
+	history, err := events.ReadLog(daemon.EventLogFile(), 0)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Warning: could not read the event log, the report has no history: %v\n", err)
+	}
+
+	index, err := report.Build(reportHTML, projects, report.Options{
+		ContextLines: reportContext,
+		History:      history,
+	})
+	if err != nil {
+		fmt.Printf("Error building report: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote the report on %d projects to %s\n", len(projects), index)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
+
+	"github.com/fatih/color"
+)
+
+var (
+	keywordColor = color.New(color.FgBlue, color.Bold).SprintFunc()
+	stringColor  = color.New(color.FgGreen).SprintFunc()
+	numberColor  = color.New(color.FgMagenta).SprintFunc()
+	markerColor  = color.New(color.FgYellow, color.Bold).SprintFunc()
+)
+
+// loadSnippet returns the source around a TODO for --context, nil when it
+// is not asked for
+func loadSnippet(todo store.Todo) (*store.Snippet, error) {
+	if contextLines <= 0 {
+		return nil, nil
+	}
+	return scan.LoadSnippet(todo, contextLines)
+}
+
+// staleNote marks a TODO whose file changed since the scan
+func staleNote(snippet *store.Snippet) string {
+	if snippet == nil || !snippet.Stale {
+		return ""
+	}
+	return " " + red("(stale: file changed since the scan)")
+}
+
+// printSnippet prints the source around a TODO with the lines of the TODO
+// marked, each line starting with prefix
+func printSnippet(prefix string, todo store.Todo, snippet *store.Snippet, err error) {
+	if err != nil {
+		fmt.Printf("%s%s\n", prefix, red(fmt.Sprintf("source unavailable: %v", err)))
+		return
+	}
+	if snippet == nil {
+		return
+	}
+
+	last := snippet.StartLine + len(snippet.Lines) - 1
+	width := len(fmt.Sprint(last))
+	height := 1
+	if todo.EndLine > todo.LineNumber {
+		height = todo.EndLine - todo.LineNumber + 1
+	}
+
+	// The signature of the enclosing function when the snippet starts below it
+	if snippet.Signature != "" && snippet.SignatureLine < snippet.StartLine {
+		tokens := scan.Highlight(todo.FilePath, []string{snippet.Signature})[0]
+		fmt.Printf("%s  %s %s %s\n", prefix, faint(fmt.Sprintf("%*d", width, snippet.SignatureLine)), faint("│"), highlightTokens(tokens))
+		if snippet.SignatureLine < snippet.StartLine-1 {
+			fmt.Printf("%s  %s %s\n", prefix, strings.Repeat(" ", width), faint("┆"))
+		}
+	}
+
+	for i, tokens := range scan.Highlight(todo.FilePath, snippet.Lines) {
+		number := snippet.StartLine + i
+		num := faint(fmt.Sprintf("%*d", width, number))
+		marker := " "
+		if number >= snippet.Line && number < snippet.Line+height {
+			num = markerColor(fmt.Sprintf("%*d", width, number))
+			marker = markerColor(">")
+		}
+		fmt.Printf("%s%s %s %s %s\n", prefix, marker, num, faint("│"), highlightTokens(tokens))
+	}
+}
+
+// highlightTokens colors the tokens of a source line
+func highlightTokens(tokens []scan.Token) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		text := strings.ReplaceAll(token.Text, "\t", "    ")
+		switch token.Kind {
+		case scan.TokenKeyword:
+			text = keywordColor(text)
+		case scan.TokenString:
+			text = stringColor(text)
+		case scan.TokenNumber:
+			text = numberColor(text)
+		case scan.TokenComment:
+			text = faint(text)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
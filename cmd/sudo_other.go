@@ -0,0 +1,9 @@
+//go:build !unix
+
+package cmd
+
+// needsSudo reports whether creating or removing path needs root. There is no
+// sudo to run without unix, so it never does.
+func needsSudo(path string) bool {
+	return false
+}
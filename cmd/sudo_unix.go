@@ -0,0 +1,19 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// needsSudo reports whether creating or removing path needs root, which is
+// the case when its directory is not writable by the current user
+func needsSudo(path string) bool {
+	if os.Geteuid() == 0 {
+		return false
+	}
+	return unix.Access(filepath.Dir(path), unix.W_OK) != nil
+}
@@ -6,9 +6,9 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
+	"Ttracker/internal/api"
 	"Ttracker/internal/config"
 	plugin "Ttracker/internal/plugins"
 	"Ttracker/internal/scan"
@@ -16,7 +16,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var projectName string
+var (
+	projectName string
+	trackPoll   bool
+)
 
 // trackCmd represents the track command
 var trackCmd = &cobra.Command{
@@ -33,6 +36,7 @@ var trackCmd = &cobra.Command{
 
 func init() {
 	trackCmd.Flags().StringVarP(&projectName, "name", "n", "", "Optional name for the project")
+	trackCmd.Flags().BoolVar(&trackPoll, "poll", false, "Have the daemon poll the project instead of using inotify, e.g. on network filesystems")
 	rootCmd.AddCommand(trackCmd)
 
 	// Here you will define your flags and configuration settings.
@@ -68,6 +72,23 @@ func trackRun(dir, name string) {
 		fmt.Println("Error resolving absolute path:", err)
 	}
 
+	// if no optional name was given use directory path
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+
+	// A running daemon tracks and scans the project itself
+	if client, ok := api.Connect(); ok {
+		if err := client.Track(name, absPath, trackPoll); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Println("Tracking new project:", absPath)
+		fmt.Println("Daemon is running: The project will be scanned automatically.")
+		fmt.Println("You can view TODOs with: tt list")
+		return
+	}
+
 	// load existing tracked projects
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -79,11 +100,6 @@ func trackRun(dir, name string) {
 		cfg.Projects = make(map[string]string)
 	}
 
-	// if no optional name was given use directory path
-	if name == "" {
-		name = filepath.Base(dir)
-	}
-
 	// check if optional name is already taken
 	if _, exists := cfg.Projects[name]; exists {
 		fmt.Println("Error: Project name already exists. Choose a different name.")
@@ -100,6 +116,9 @@ func trackRun(dir, name string) {
 
 	// add new project
 	cfg.Projects[name] = absPath
+	if trackPoll {
+		cfg.SetPoll(name, true)
+	}
 
 	// if no project is active make the new one the active project
 	if cfg.Active == "" {
@@ -136,13 +155,6 @@ func trackRun(dir, name string) {
 
 	storeFilePath := filepath.Join("data", "todos.json")
 
-	// Check if daemon is running and let the user know they don't need to scan manually
-	if isDaemonRunning() {
-		fmt.Println("Daemon is running: The project will be scanned automatically.")
-		fmt.Println("You can view TODOs with: tt list")
-		return
-	}
-
 	// If daemon is not running, scan the project immediately
 	fmt.Println("Scanning project for TODOs...")
 	err = scan.RunScan(absPath, name, plugin.PluginConfigsPath, storeFilePath)
@@ -150,18 +162,5 @@ func trackRun(dir, name string) {
 		fmt.Printf("Error scanning project %s: %v\n", absPath, err)
 	}
 
-	fmt.Println("To continuously monitor this project, start the daemon with: tt daemon")
-}
-
-// Check if the daemon process is running
-func isDaemonRunning() bool {
-	// This is a simple implementation - in a production app you might
-	// use a PID file or another IPC mechanism for more robust detection
-
-	// For now, we'll just check if there's a running process with "tt daemon" in the command line
-	cmd := exec.Command("pgrep", "-f", "tt daemon")
-	if err := cmd.Run(); err != nil {
-		return false
-	}
-	return true
+	fmt.Println("To continuously monitor this project, start the daemon with: tt daemon start")
 }
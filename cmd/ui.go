@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"Ttracker/internal/api"
+	"Ttracker/internal/config"
+	"Ttracker/internal/events"
+	"Ttracker/internal/store"
+	"Ttracker/internal/tui"
+
+	"github.com/spf13/cobra"
+)
+
+// uiCmd represents the ui command
+var uiCmd = &cobra.Command{
+	Use:   "ui [project-name]",
+	Short: "Browse TODOs in a full-screen terminal interface",
+	Long: `UI opens a full-screen terminal interface to browse TODOs by project, directory
+and file, with a preview of the source around the selected TODO.
+
+It shows the active project, or the project given, and refreshes by itself when
+the running daemon finds TODOs changing. Without a daemon it refreshes when the
+store file changes.
+
+Keys:
+  ↑/↓ j/k        Move            ←/→ h/l     Collapse / expand
+  enter, space   Toggle a node   enter, e    Open a TODO in $EDITOR
+  /              Fuzzy search    esc         Clear the search
+  p              Switch project  tab         Show or hide the preview
+  + / -          Expand / collapse everything
+  r              Refresh         q           Quit
+
+Example:
+  tt ui                     # Browse the active project
+  tt ui my-project          # Browse a specific project`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  uiRun,
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+}
+
+func uiRun(cmd *cobra.Command, args []string) {
+	storeFilePath := filepath.Join("data", "todos.json")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	// Start with the project given, or the active one
+	project := ""
+	if len(args) > 0 {
+		project = args[0]
+		if _, exists := cfg.Projects[project]; !exists {
+			fmt.Printf("Project '%s' not found\n", project)
+			return
+		}
+	} else {
+		for name, path := range cfg.Projects {
+			if name == cfg.Active || path == cfg.Active {
+				project = name
+				break
+			}
+		}
+	}
+
+	client, _ := api.Connect()
+	load := func() (*store.Store, error) {
+		if client != nil {
+			if st, err := client.Todos(api.Query{}); err == nil {
+				return st, nil
+			}
+			// The daemon went away, read what it left in the store
+		}
+		st, err := loadTodos(nil, storeFilePath)
+		if os.IsNotExist(err) {
+			return store.NewStore(), nil
+		}
+		return st, err
+	}
+
+	changes := make(chan struct{}, 1)
+	changed := func() {
+		select {
+		case changes <- struct{}{}:
+		default: // a refresh is already pending
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := "store file"
+	if client != nil {
+		source = "live"
+		go func() {
+			client.Events(ctx, "", func(event events.Event) {
+				switch event.Type {
+				case events.TodoAdded, events.TodoRemoved, events.TodoChanged, events.ProjectAdded, events.ProjectRemoved:
+					changed()
+				}
+			})
+			followStoreFile(ctx, storeFilePath, changed)
+		}()
+	} else {
+		go followStoreFile(ctx, storeFilePath, changed)
+	}
+
+	err = tui.Run(tui.Options{
+		Projects: cfg.Projects,
+		Project:  project,
+		Load:     load,
+		Changes:  changes,
+		Source:   source,
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+}
+
+// followStoreFile calls changed whenever the store file is modified, until
+// ctx is done
+func followStoreFile(ctx context.Context, storeFilePath string, changed func()) {
+	var last time.Time
+	if info, err := os.Stat(storeFilePath); err == nil {
+		last = info.ModTime()
+	}
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(storeFilePath)
+		if err != nil || info.ModTime().Equal(last) {
+			continue
+		}
+		last = info.ModTime()
+		changed()
+	}
+}
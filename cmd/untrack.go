@@ -4,8 +4,12 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"Ttracker/internal/config"
 	"fmt"
+	"path/filepath"
+
+	"Ttracker/internal/api"
+	"Ttracker/internal/config"
+	"Ttracker/internal/store"
 
 	"github.com/spf13/cobra"
 )
@@ -79,11 +83,32 @@ func untrackRun(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	if cfg.Active == project {
+	// A running daemon stops watching the project and drops its TODOs itself
+	if client, ok := api.Connect(); ok {
+		if err := client.Untrack(project); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Println("Project:", project, "has been untrack, all tracking data has been removed.")
+		return
+	}
+
+	if cfg.Active == project || cfg.Active == isExists {
 		cfg.Active = ""
 	}
 	delete(cfg.Projects, project)
-	config.SaveConfig(cfg)
+	if err := config.SaveConfig(cfg); err != nil {
+		fmt.Println("Error saving config file", err)
+		return
+	}
+
+	storeFilePath := filepath.Join("data", "todos.json")
+	if st, err := store.LoadStore(storeFilePath); err == nil {
+		st.RemoveProject(project)
+		if err := st.Save(storeFilePath); err != nil {
+			fmt.Println("Error removing TODOs of project", project, err)
+		}
+	}
 	fmt.Println("Project:", project, "has been untrack, all tracking data has been removed.")
 
 }
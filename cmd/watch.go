@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"Ttracker/internal/api"
+	"Ttracker/internal/daemon"
+	"Ttracker/internal/events"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchJSON    bool
+	watchHistory int
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:     "watch [project-name]",
+	Aliases: []string{"tail"},
+	Short:   "Follow TODOs being added, removed and changed as the daemon scans",
+	Long: `Watch prints the TODOs the running daemon finds added, removed or changed after
+each scan, and the scans themselves, as they happen. Pass a project name to only
+follow that project.
+
+The daemon also appends every event to events.ndjson in the state directory,
+--history prints the last events from that log before following.
+
+Example:
+  tt watch                  # Follow every project
+  tt tail my-project        # Follow one project
+  tt watch --history 20     # Print the last 20 events first
+  tt watch --json           # Print events as newline delimited JSON
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  watchRun,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().BoolVar(&watchJSON, "json", false, "Print events as newline delimited JSON")
+	watchCmd.Flags().IntVarP(&watchHistory, "history", "n", 0, "Print the last N events from the event log first")
+}
+
+func watchRun(cmd *cobra.Command, args []string) {
+	var project string
+	if len(args) > 0 {
+		project = args[0]
+	}
+
+	if watchHistory > 0 {
+		history, err := events.ReadLog(daemon.EventLogFile(), 0)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Println("Error reading event log:", err)
+			return
+		}
+		var selected []events.Event
+		for _, event := range history {
+			if project == "" || event.Project == project {
+				selected = append(selected, event)
+			}
+		}
+		if len(selected) > watchHistory {
+			selected = selected[len(selected)-watchHistory:]
+		}
+		for _, event := range selected {
+			printEvent(event)
+		}
+	}
+
+	client, ok := api.Connect()
+	if !ok {
+		fmt.Println("The daemon is not running. Start it with: tt daemon start")
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if !watchJSON {
+		fmt.Println("Watching for TODO changes. Press Ctrl+C to stop.")
+	}
+	if err := client.Events(ctx, project, printEvent); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if ctx.Err() == nil {
+		fmt.Println("The daemon stopped.")
+	}
+}
+
+var (
+	red   = color.New(color.FgRed).SprintFunc()
+	faint = color.New(color.Faint).SprintFunc()
+)
+
+// printEvent prints an event as one line, or as JSON with --json
+func printEvent(event events.Event) {
+	if watchJSON {
+		data, err := json.Marshal(event)
+		if err == nil {
+			fmt.Println(string(data))
+		}
+		return
+	}
+
+	at := faint(event.Time.Local().Format("15:04:05"))
+	switch event.Type {
+	case events.TodoAdded, events.TodoRemoved, events.TodoChanged:
+		todo := event.Todo
+		if todo == nil {
+			return
+		}
+		mark, location := green("+"), fmt.Sprintf("%s:%d", todo.FilePath, todo.LineNumber)
+		switch event.Type {
+		case events.TodoRemoved:
+			mark = red("-")
+		case events.TodoChanged:
+			mark = yellow("~")
+			if prev := event.Previous; prev != nil && prev.LineNumber != todo.LineNumber {
+				location = fmt.Sprintf("%s:%d->%d", todo.FilePath, prev.LineNumber, todo.LineNumber)
+			}
+		}
+		fmt.Printf("%s %s %s %s %s\n", at, mark, bold(event.Project), cyan(location), firstLine(todo.Comment))
+	case events.ScanCompleted:
+		if event.Error != "" {
+			fmt.Printf("%s %s scan of %s failed: %s\n", at, red("!"), bold(event.Project), event.Error)
+		} else {
+			fmt.Printf("%s %s scanned %s\n", at, faint("·"), bold(event.Project))
+		}
+	case events.ProjectAdded:
+		fmt.Printf("%s %s tracking %s (%s)\n", at, faint("·"), bold(event.Project), event.Path)
+	case events.ProjectRemoved:
+		fmt.Printf("%s %s stopped tracking %s\n", at, faint("·"), bold(event.Project))
+	}
+}
+
+// firstLine returns the first line of a TODO continued over several lines
+func firstLine(comment string) string {
+	line, _, _ := strings.Cut(comment, "\n")
+	return strings.TrimSpace(line)
+}
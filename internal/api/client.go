@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"Ttracker/internal/daemon"
+	"Ttracker/internal/events"
+	"Ttracker/internal/store"
+)
+
+// Client talks to the daemon's API over its Unix socket
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the API served on socketPath
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Client{http: &http.Client{Transport: transport}}
+}
+
+// Connect returns a client for the running daemon. It reports false when no
+// daemon is running or its API cannot be reached, in which case the caller
+// works on the data files directly.
+func Connect() (*Client, bool) {
+	if _, running := daemon.Running(); !running {
+		return nil, false
+	}
+	client := NewClient(daemon.SocketFile())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := client.status(ctx); err != nil {
+		return nil, false
+	}
+	return client, true
+}
+
+// Status returns the daemon's status report
+func (c *Client) Status() (daemon.Status, error) {
+	return c.status(context.Background())
+}
+
+func (c *Client) status(ctx context.Context) (daemon.Status, error) {
+	var status daemon.Status
+	err := c.do(ctx, http.MethodGet, "/v1/status", nil, nil, &status)
+	return status, err
+}
+
+// Todos returns the TODOs selected by q
+func (c *Client) Todos(q Query) (*store.Store, error) {
+	st := store.NewStore()
+	err := c.do(context.Background(), http.MethodGet, "/v1/todos", q.Values(), nil, st)
+	return st, err
+}
+
+// Scan rescans a project, or every project when project is empty, and waits
+// for the scans to finish
+func (c *Client) Scan(project string) (ScanResult, error) {
+	values := url.Values{}
+	if project != "" {
+		values.Set("project", project)
+	}
+	var result ScanResult
+	err := c.do(context.Background(), http.MethodPost, "/v1/scan", values, nil, &result)
+	if len(result.Errors) > 0 {
+		return result, result.Err()
+	}
+	return result, err
+}
+
+// Track asks the daemon to track and scan a project, polling it with poll
+func (c *Client) Track(name, path string, poll bool) error {
+	return c.do(context.Background(), http.MethodPost, "/v1/projects", nil, TrackRequest{Name: name, Path: path, Poll: poll}, nil)
+}
+
+// Untrack asks the daemon to stop tracking a project and drop its TODOs
+func (c *Client) Untrack(name string) error {
+	return c.do(context.Background(), http.MethodDelete, "/v1/projects/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// Events calls fn for every event the daemon publishes, for one project or
+// all of them, until ctx is done or the daemon stops
+func (c *Client) Events(ctx context.Context, project string, fn func(events.Event)) error {
+	values := url.Values{}
+	if project != "" {
+		values.Set("project", project)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://tt/v1/events?"+values.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not reach the daemon: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var event events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		fn(event)
+	}
+	// The stream is cut when the daemon shuts down
+	if ctx.Err() != nil || errors.Is(scanner.Err(), io.ErrUnexpectedEOF) {
+		return nil
+	}
+	return scanner.Err()
+}
+
+// do sends a request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, values url.Values, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not marshal request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := "http://tt" + path
+	if len(values) > 0 {
+		target += "?" + values.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not reach the daemon: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// Scan results carry the per-project errors
+		if out != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+			json.NewDecoder(resp.Body).Decode(out)
+			return fmt.Errorf("request failed")
+		}
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode response: %v", err)
+	}
+	return nil
+}
+
+// responseError turns an error response into an error
+func responseError(resp *http.Response) error {
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		return fmt.Errorf("daemon returned %s", resp.Status)
+	}
+	return fmt.Errorf("%s", body.Error)
+}
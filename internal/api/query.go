@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"Ttracker/internal/store"
+)
+
+// Query selects TODOs from the store. Empty fields match everything.
+type Query struct {
+	Project  string // project name
+	Keyword  string // keyword, e.g. FIXME
+	Severity string // severity of the keyword
+	File     string // part of the file path
+	Text     string // part of the comment, case-insensitive
+}
+
+// Values encodes the query as URL parameters
+func (q Query) Values() url.Values {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"project":  q.Project,
+		"keyword":  q.Keyword,
+		"severity": q.Severity,
+		"file":     q.File,
+		"q":        q.Text,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	return values
+}
+
+// QueryFromValues decodes a query from URL parameters
+func QueryFromValues(values url.Values) Query {
+	return Query{
+		Project:  values.Get("project"),
+		Keyword:  values.Get("keyword"),
+		Severity: values.Get("severity"),
+		File:     values.Get("file"),
+		Text:     values.Get("q"),
+	}
+}
+
+// Matches reports whether a TODO of a project is selected by the query
+func (q Query) Matches(project string, todo store.Todo) bool {
+	switch {
+	case q.Project != "" && project != q.Project:
+		return false
+	case q.Keyword != "" && !strings.EqualFold(todo.Keyword, q.Keyword):
+		return false
+	case q.Severity != "" && !strings.EqualFold(todo.Severity, q.Severity):
+		return false
+	case q.File != "" && !strings.Contains(todo.FilePath, q.File):
+		return false
+	case q.Text != "" && !strings.Contains(strings.ToLower(todo.Comment), strings.ToLower(q.Text)):
+		return false
+	}
+	return true
+}
+
+// Filter returns a store holding the selected TODOs. Projects without
+// matching TODOs are left out unless the query names the project.
+func (q Query) Filter(st *store.Store) *store.Store {
+	filtered := store.NewStore()
+	for project, todos := range st.Projects {
+		if q.Project != "" && project != q.Project {
+			continue
+		}
+		selected := make([]store.Todo, 0, len(todos))
+		for _, todo := range todos {
+			if q.Matches(project, todo) {
+				selected = append(selected, todo)
+			}
+		}
+		if len(selected) > 0 || q.Project != "" {
+			filtered.Projects[project] = selected
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,242 @@
+// Package api is the local HTTP API the daemon serves on a Unix socket, and
+// the client the CLI uses to reach it
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"Ttracker/internal/daemon"
+	"Ttracker/internal/store"
+	"Ttracker/internal/watcher"
+)
+
+// Server serves the API of a running daemon
+type Server struct {
+	watcher   *watcher.ProjectWatcher
+	startedAt time.Time
+	http      *http.Server
+}
+
+// NewServer creates the API server for the daemon running w
+func NewServer(w *watcher.ProjectWatcher, startedAt time.Time) *Server {
+	return &Server{watcher: w, startedAt: startedAt}
+}
+
+// Handler routes the API requests
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET /v1/todos", s.handleTodos)
+	mux.HandleFunc("POST /v1/scan", s.handleScan)
+	mux.HandleFunc("POST /v1/projects", s.handleTrack)
+	mux.HandleFunc("DELETE /v1/projects/{name}", s.handleUntrack)
+	mux.HandleFunc("GET /v1/events", s.handleEvents)
+	return mux
+}
+
+// Listen starts serving the API on a Unix socket only the current user can
+// connect to. A socket left behind by a daemon that crashed is replaced, the
+// caller must hold the daemon lock.
+func (s *Server) Listen(socketPath string) error {
+	os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %v", socketPath, err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("could not restrict access to %s: %v", socketPath, err)
+	}
+
+	s.http = &http.Server{Handler: s.Handler()}
+	go s.http.Serve(listener)
+	return nil
+}
+
+// Close stops the server and ends open event streams
+func (s *Server) Close() error {
+	if s.http == nil {
+		return nil
+	}
+	return s.http.Close()
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, daemon.NewStatus(s.watcher, s.startedAt))
+}
+
+func (s *Server) handleTodos(w http.ResponseWriter, r *http.Request) {
+	st, err := store.LoadStore(s.watcher.StoreFile())
+	if err != nil {
+		if _, statErr := os.Stat(s.watcher.StoreFile()); os.IsNotExist(statErr) {
+			st = store.NewStore()
+		} else {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, QueryFromValues(r.URL.Query()).Filter(st))
+}
+
+// ScanResult reports the projects scanned by a scan request
+type ScanResult struct {
+	Scanned []string          `json:"scanned"`
+	Errors  map[string]string `json:"errors,omitempty"` // project name -> scan error
+}
+
+// Err joins the errors of the projects that failed to scan, sorted by project
+// name, or returns nil if every scan succeeded
+func (r ScanResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(r.Errors))
+	for name := range r.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = fmt.Sprintf("%s: %s", name, r.Errors[name])
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	if project := r.URL.Query().Get("project"); project != "" {
+		names = []string{project}
+	} else {
+		for _, project := range s.watcher.Projects() {
+			names = append(names, project.Name)
+		}
+	}
+
+	result := ScanResult{Scanned: []string{}}
+	for _, name := range names {
+		if err := s.watcher.ScanProject(name); err != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			result.Errors[name] = err.Error()
+			continue
+		}
+		result.Scanned = append(result.Scanned, name)
+	}
+
+	status := http.StatusOK
+	if len(names) == 1 && len(result.Scanned) == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, result)
+}
+
+// TrackRequest is the body of a request to track a project
+type TrackRequest struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Poll bool   `json:"poll,omitempty"` // poll the project instead of watching it
+}
+
+func (s *Server) handleTrack(w http.ResponseWriter, r *http.Request) {
+	var req TrackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+	if req.Name == "" || req.Path == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("a project name and path must be provided"))
+		return
+	}
+	if err := s.watcher.Track(req.Name, req.Path, req.Poll); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, req)
+}
+
+func (s *Server) handleUntrack(w http.ResponseWriter, r *http.Request) {
+	if err := s.watcher.Untrack(r.PathValue("name")); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents streams events until the client disconnects, as server-sent
+// events when the client accepts text/event-stream and as newline delimited
+// JSON otherwise. The project parameter limits the stream to one project.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	project := r.URL.Query().Get("project")
+	sse := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+
+	stream, cancel := s.watcher.Events.Subscribe(256)
+	defer cancel()
+
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	id := 0
+	for {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			if project != "" && event.Project != project {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if sse {
+				id++
+				_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event.Type, data)
+			} else {
+				_, err = fmt.Fprintf(w, "%s\n", data)
+			}
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// errorResponse is the body of a failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: strings.TrimSpace(err.Error())})
+}
@@ -0,0 +1,142 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// BaselineFile is the baseline written at the root of a directory by default
+const BaselineFile = ".ttbaseline.json"
+
+// baselineVersion is the format of the baseline files written
+const baselineVersion = 1
+
+// Baseline is a snapshot of the TODOs of a directory, committed so checks
+// only flag TODOs added since. Entries are matched by fingerprint, so they
+// survive code moving around them; file and line only help readers.
+type Baseline struct {
+	Version int             `json:"version"`
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// BaselineEntry is a TODO in a baseline
+type BaselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Keyword     string `json:"keyword"`
+	Comment     string `json:"comment"`
+}
+
+// NewBaseline snapshots items
+func NewBaseline(items []Item) *Baseline {
+	b := &Baseline{Version: baselineVersion, Entries: make([]BaselineEntry, 0, len(items))}
+	for _, item := range items {
+		b.Entries = append(b.Entries, entryOf(item))
+	}
+	b.sort()
+	return b
+}
+
+// LoadBaseline reads a baseline file
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid baseline file %s: %v", path, err)
+	}
+	if b.Version > baselineVersion {
+		return nil, fmt.Errorf("baseline file %s has version %d, this tt reads up to version %d", path, b.Version, baselineVersion)
+	}
+	return &b, nil
+}
+
+// Save writes the baseline to path
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Compare splits items into those missing from the baseline and returns
+// them with the baseline entries no item matches any more. A fingerprint
+// listed n times covers n TODOs, so copying a baselined TODO is new.
+func (b *Baseline) Compare(items []Item) (added []Item, resolved []BaselineEntry) {
+	matches, added := b.match(items)
+	for i, entry := range b.Entries {
+		if matches[i] < 0 {
+			resolved = append(resolved, entry)
+		}
+	}
+	return added, resolved
+}
+
+// Update drops the entries of TODOs that were resolved and moves the rest to
+// where the TODOs are now. TODOs added since are not added to the baseline.
+// It returns the entries dropped.
+func (b *Baseline) Update(items []Item) []BaselineEntry {
+	matches, _ := b.match(items)
+	var resolved []BaselineEntry
+	entries := make([]BaselineEntry, 0, len(b.Entries))
+	for i, entry := range b.Entries {
+		if matches[i] < 0 {
+			resolved = append(resolved, entry)
+		} else {
+			entries = append(entries, entryOf(items[matches[i]]))
+		}
+	}
+
+	b.Version = baselineVersion
+	b.Entries = entries
+	b.sort()
+	return resolved
+}
+
+// match pairs items with baseline entries of the same fingerprint in order.
+// It returns the index of the item each entry matched, -1 for none, and the
+// items left over.
+func (b *Baseline) match(items []Item) ([]int, []Item) {
+	pending := make(map[string][]int)
+	matches := make([]int, len(b.Entries))
+	for i, entry := range b.Entries {
+		pending[entry.Fingerprint] = append(pending[entry.Fingerprint], i)
+		matches[i] = -1
+	}
+
+	var added []Item
+	for j, item := range items {
+		if indexes := pending[item.Fingerprint]; len(indexes) > 0 {
+			matches[indexes[0]] = j
+			pending[item.Fingerprint] = indexes[1:]
+			continue
+		}
+		added = append(added, item)
+	}
+	return matches, added
+}
+
+func (b *Baseline) sort() {
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		if b.Entries[i].File != b.Entries[j].File {
+			return b.Entries[i].File < b.Entries[j].File
+		}
+		return b.Entries[i].Line < b.Entries[j].Line
+	})
+}
+
+func entryOf(item Item) BaselineEntry {
+	return BaselineEntry{
+		Fingerprint: item.Fingerprint,
+		File:        item.File,
+		Line:        item.Line,
+		Keyword:     item.Keyword,
+		Comment:     item.Comment,
+	}
+}
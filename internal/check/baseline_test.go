@@ -0,0 +1,131 @@
+package check
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// item is a TODO of a baseline test, the fingerprint stands for the comment
+func item(file string, line int, fingerprint string) Item {
+	return Item{File: file, Line: line, Keyword: "TODO", Comment: "TODO: " + fingerprint, Fingerprint: fingerprint}
+}
+
+func describeItems(items []Item) string {
+	var parts []string
+	for _, it := range items {
+		parts = append(parts, fmt.Sprintf("%s@%s:%d", it.Fingerprint, it.File, it.Line))
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeEntries(entries []BaselineEntry) string {
+	var parts []string
+	for _, e := range entries {
+		parts = append(parts, fmt.Sprintf("%s@%s:%d", e.Fingerprint, e.File, e.Line))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBaseline(t *testing.T) {
+	baseline := []Item{item("a.go", 1, "x"), item("a.go", 5, "x"), item("a.go", 9, "y"), item("b.go", 2, "z")}
+
+	tests := []struct {
+		name     string
+		items    []Item
+		added    string // Compare
+		resolved string // Compare and Update
+		updated  string // the entries after Update
+	}{
+		{
+			name:    "unchanged",
+			items:   baseline,
+			updated: "x@a.go:1 x@a.go:5 y@a.go:9 z@b.go:2",
+		},
+		{
+			name:    "moved",
+			items:   []Item{item("a.go", 3, "x"), item("a.go", 7, "x"), item("a.go", 11, "y"), item("c.go", 2, "z")},
+			updated: "x@a.go:3 x@a.go:7 y@a.go:11 z@c.go:2",
+		},
+		{
+			name:     "one of two duplicates resolved",
+			items:    []Item{item("a.go", 1, "x"), item("a.go", 9, "y"), item("b.go", 2, "z")},
+			resolved: "x@a.go:5",
+			updated:  "x@a.go:1 y@a.go:9 z@b.go:2",
+		},
+		{
+			name:    "a baselined TODO copied is new",
+			items:   append(append([]Item{}, baseline...), item("a.go", 20, "x"), item("b.go", 8, "z")),
+			added:   "x@a.go:20 z@b.go:8",
+			updated: "x@a.go:1 x@a.go:5 y@a.go:9 z@b.go:2",
+		},
+		{
+			name:     "added and resolved",
+			items:    []Item{item("a.go", 1, "x"), item("a.go", 5, "x"), item("a.go", 9, "w")},
+			added:    "w@a.go:9",
+			resolved: "y@a.go:9 z@b.go:2",
+			updated:  "x@a.go:1 x@a.go:5",
+		},
+		{
+			name:     "everything resolved",
+			resolved: "x@a.go:1 x@a.go:5 y@a.go:9 z@b.go:2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBaseline(baseline)
+			added, resolved := b.Compare(tt.items)
+			if got := describeItems(added); got != tt.added {
+				t.Errorf("Compare added %q, want %q", got, tt.added)
+			}
+			if got := describeEntries(resolved); got != tt.resolved {
+				t.Errorf("Compare resolved %q, want %q", got, tt.resolved)
+			}
+
+			dropped := b.Update(tt.items)
+			if got := describeEntries(dropped); got != tt.resolved {
+				t.Errorf("Update dropped %q, want %q", got, tt.resolved)
+			}
+			if got := describeEntries(b.Entries); got != tt.updated {
+				t.Errorf("Update left %q, want %q", got, tt.updated)
+			}
+		})
+	}
+}
+
+func TestBaselineRun(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":     "package a\n\n// TODO: old\nfunc f() {}\n",
+		"lib/b.py": "# TODO: old python\n",
+	})
+	report, err := Run(root, Policy{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, BaselineFile)
+	if err := NewBaseline(report.Todos).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(b.Entries); got != 2 {
+		t.Fatalf("baseline has %d entries, want 2", got)
+	}
+
+	// A TODO in a file without a built-in parser is still checked against it
+	root2 := writeTree(t, map[string]string{
+		"a.go":     "package a\n\n// TODO: old\nfunc f() {}\n",
+		"lib/b.py": "# TODO: old python\n\n# TODO: new python\n",
+	})
+	report, err = Run(root2, Policy{}, Options{Baseline: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed || report.Baselined != 2 || len(report.Todos) != 1 || report.Todos[0].Line != 3 {
+		t.Errorf("baselined %d, new %v, want the new python TODO", report.Baselined, report.Todos)
+	}
+}
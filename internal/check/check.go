@@ -0,0 +1,263 @@
+// Package check evaluates TODO policies over a directory for CI. It scans
+// without a registered project, store, installed plugins or global
+// configuration, so a check gives the same result wherever it runs.
+package check
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"Ttracker/internal/ignore"
+	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
+)
+
+// Rules a violation can break
+const (
+	RuleForbidden     = "forbidden-keyword"
+	RuleMissingOwner  = "missing-owner"
+	RuleOverdue       = "overdue"
+	RuleMaxPerPackage = "max-per-package"
+	RuleNew           = "new-todo"
+)
+
+// Item is a TODO found by a check. Paths are relative to the checked
+// directory and slash separated, so reports and fingerprints do not depend
+// on where the repository is checked out.
+type Item struct {
+	File        string     `json:"file"`
+	Line        int        `json:"line"`
+	EndLine     int        `json:"end_line,omitempty"`
+	Keyword     string     `json:"keyword"`
+	Severity    string     `json:"severity,omitempty"`
+	Comment     string     `json:"comment"`
+	Owner       string     `json:"owner,omitempty"`
+	Due         string     `json:"due,omitempty"`
+	Function    string     `json:"function,omitempty"`
+	Package     string     `json:"package"` // the directory of the file
+	Fingerprint string     `json:"fingerprint"`
+	Todo        store.Todo `json:"-"` // the TODO as scanned, with its absolute path
+}
+
+// Violation is a policy a TODO, or a package of TODOs, breaks
+type Violation struct {
+	Rule        string `json:"rule"`
+	Message     string `json:"message"`
+	File        string `json:"file,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Package     string `json:"package,omitempty"`
+	Keyword     string `json:"keyword,omitempty"`
+	Severity    string `json:"severity,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Item        *Item  `json:"-"` // the TODO at fault, nil for package limits
+}
+
+// Report is the outcome of a check. With a baseline, Todos only holds the
+// TODOs missing from it.
+type Report struct {
+	Root       string          `json:"root"`
+	Branch     string          `json:"branch,omitempty"`
+	Files      int             `json:"files"`
+	Todos      []Item          `json:"todos"`
+	Baselined  int             `json:"baselined,omitempty"` // TODOs found in the baseline
+	Resolved   []BaselineEntry `json:"resolved,omitempty"`  // baseline entries no longer found
+	Violations []Violation     `json:"violations"`
+	Passed     bool            `json:"passed"`
+}
+
+// Options tunes a check
+type Options struct {
+	Branch string    // the branch forbid rules are matched against
+	Today  time.Time // the day TODOs become overdue after, today when zero
+	// Baseline lists the TODOs that are accepted. Only TODOs missing from it
+	// are checked, and each of them is a violation.
+	Baseline *Baseline
+}
+
+// Run scans root and evaluates policy over the TODOs found
+func Run(root string, policy Policy, opts Options) (*Report, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(root); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	// Plugins are set up per machine, only the parsers built into tt are used
+	mgr := scan.NewBuiltinManager()
+	mgr.SetQuiet(true)
+	if err := mgr.SetKeywords(policy.KeywordConfig()); err != nil {
+		return nil, fmt.Errorf("invalid keywords: %v", err)
+	}
+	ignoreMgr, err := ignore.NewRepoIgnoreManager(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ignore files: %v", err)
+	}
+
+	// Without plugins, files in other languages are read with their comment
+	// syntax like tt diff does
+	todos, files, err := scan.ScanDir(root, mgr, ignoreMgr, scan.ScanOptions{Fallback: true})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Root: root, Branch: opts.Branch, Files: files, Todos: Items(root, todos)}
+	if opts.Baseline != nil {
+		found := len(report.Todos)
+		report.Todos, report.Resolved = opts.Baseline.Compare(report.Todos)
+		if report.Todos == nil {
+			report.Todos = make([]Item, 0)
+		}
+		report.Baselined = found - len(report.Todos)
+	}
+	report.Violations = Evaluate(report.Todos, policy, opts)
+	report.Passed = len(report.Violations) == 0
+	return report, nil
+}
+
+// Items describes scanned TODOs relative to root, in file and line order
+func Items(root string, todos []store.Todo) []Item {
+	items := make([]Item, 0, len(todos))
+	for _, todo := range todos {
+		rel := todo.FilePath
+		if r, err := filepath.Rel(root, todo.FilePath); err == nil {
+			rel = r
+		}
+		rel = filepath.ToSlash(rel)
+
+		relTodo := todo
+		relTodo.FilePath = rel
+		meta := ParseMeta(todo)
+		item := Item{
+			File:        rel,
+			Line:        todo.LineNumber,
+			EndLine:     todo.EndLine,
+			Keyword:     todo.Keyword,
+			Severity:    todo.Severity,
+			Comment:     todo.Comment,
+			Owner:       meta.Owner,
+			Function:    todo.Function,
+			Package:     packageOf(rel),
+			Fingerprint: relTodo.Fingerprint(),
+			Todo:        todo,
+		}
+		if !meta.Due.IsZero() {
+			item.Due = meta.Due.Format("2006-01-02")
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].File != items[j].File {
+			return items[i].File < items[j].File
+		}
+		return items[i].Line < items[j].Line
+	})
+	return items
+}
+
+// Evaluate returns the violations of policy among items, in file and line
+// order followed by the package limits
+func Evaluate(items []Item, policy Policy, opts Options) []Violation {
+	today := opts.Today
+	if today.IsZero() {
+		now := time.Now()
+		today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	violations := make([]Violation, 0)
+	perPackage := make(map[string]int)
+	for i := range items {
+		item := &items[i]
+		perPackage[item.Package]++
+
+		if opts.Baseline != nil {
+			violations = append(violations, itemViolation(RuleNew,
+				fmt.Sprintf("%s is not in the baseline", item.Keyword), item))
+		}
+		if policy.forbidden(item.Keyword, opts.Branch) {
+			message := fmt.Sprintf("%s is not allowed", item.Keyword)
+			if opts.Branch != "" {
+				message = fmt.Sprintf("%s is not allowed on branch %s", item.Keyword, opts.Branch)
+			}
+			violations = append(violations, itemViolation(RuleForbidden, message, item))
+		}
+		if policy.RequireOwner && item.Owner == "" {
+			violations = append(violations, itemViolation(RuleMissingOwner,
+				fmt.Sprintf("%s has no owner, e.g. %s(name): ...", item.Keyword, item.Keyword), item))
+		}
+		if policy.NoOverdue && item.Due != "" && item.Due < today.Format("2006-01-02") {
+			violations = append(violations, itemViolation(RuleOverdue,
+				fmt.Sprintf("%s was due on %s", item.Keyword, item.Due), item))
+		}
+	}
+
+	if policy.MaxPerPackage > 0 {
+		packages := make([]string, 0, len(perPackage))
+		for pkg := range perPackage {
+			packages = append(packages, pkg)
+		}
+		sort.Strings(packages)
+		for _, pkg := range packages {
+			if count := perPackage[pkg]; count > policy.MaxPerPackage {
+				violations = append(violations, Violation{
+					Rule:    RuleMaxPerPackage,
+					Message: fmt.Sprintf("%s has %d TODOs, at most %d are allowed", pkg, count, policy.MaxPerPackage),
+					Package: pkg,
+				})
+			}
+		}
+	}
+	return violations
+}
+
+func itemViolation(rule, message string, item *Item) Violation {
+	return Violation{
+		Rule:        rule,
+		Message:     message,
+		File:        item.File,
+		Line:        item.Line,
+		Package:     item.Package,
+		Keyword:     item.Keyword,
+		Severity:    item.Severity,
+		Fingerprint: item.Fingerprint,
+		Item:        item,
+	}
+}
+
+// packageOf returns the directory of a slash separated path, "." for the root
+func packageOf(rel string) string {
+	if i := strings.LastIndex(rel, "/"); i >= 0 {
+		return rel[:i]
+	}
+	return "."
+}
+
+// DetectBranch returns the branch the checked code lands on: the target of a
+// pull request or the pushed branch as CI systems announce them, else the
+// branch checked out in dir. It returns "" on a detached HEAD outside CI.
+func DetectBranch(dir string) string {
+	for _, name := range []string{
+		"GITHUB_BASE_REF", "GITHUB_REF_NAME", // GitHub Actions
+		"CI_MERGE_REQUEST_TARGET_BRANCH_NAME", "CI_COMMIT_REF_NAME", // GitLab
+		"BUILDKITE_PULL_REQUEST_BASE_BRANCH", "BUILDKITE_BRANCH", // Buildkite
+		"CHANGE_TARGET", "BRANCH_NAME", // Jenkins
+	} {
+		if branch := os.Getenv(name); branch != "" {
+			return branch
+		}
+	}
+	// symbolic-ref fails on a detached HEAD, and works before the first commit
+	out, err := exec.Command("git", "-C", dir, "symbolic-ref", "--short", "-q", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,174 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Ttracker/internal/store"
+)
+
+// writeTree writes files, keyed by their slash separated path, below a new
+// directory and returns it
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRun(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":      "package a\n\n// TODO(alice): go thing\nfunc f() {}\n",
+		"broken.go": "package a\n\n// FIXME: does not parse\nfunc g( {\n",
+		"lib/b.py":  "def f():\n    # TODO: py thing\n    return 1\n",
+		"notes.txt": "TODO: not a comment\n",
+		"data.xyz":  "# TODO: unknown language\n",
+		"Makefile":  "# TODO: no extension\n",
+		".ttignore": "gen/\n",
+		"gen/c.go":  "package gen\n\n// TODO: ignored\n",
+	})
+
+	report, err := Run(root, Policy{Forbid: []ForbidRule{{Keywords: []string{"TODO"}}}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, item := range report.Todos {
+		found = append(found, item.File+":"+item.Keyword+":"+item.Function)
+	}
+	want := []string{"a.go:TODO:f", "broken.go:FIXME:", "lib/b.py:TODO:f"}
+	if strings.Join(found, " ") != strings.Join(want, " ") {
+		t.Errorf("found %v, want %v", found, want)
+	}
+	// Only the files read count
+	if report.Files != 3 {
+		t.Errorf("Files = %d, want 3", report.Files)
+	}
+	if report.Passed || len(report.Violations) != 2 {
+		t.Errorf("violations %v, want the two TODOs", report.Violations)
+	}
+}
+
+func TestParseMeta(t *testing.T) {
+	tests := []struct {
+		comment string
+		keyword string
+		owner   string
+		due     string
+	}{
+		{"// TODO: plain", "TODO", "", ""},
+		{"// TODO(alice): owner", "TODO", "alice", ""},
+		{"// TODO(alice, 2025-03-01): owner and date", "TODO", "alice", "2025-03-01"},
+		{"// TODO(2025-03-01; alice)", "TODO", "alice", "2025-03-01"},
+		{"// TODO(@alice due:2025-03-01)", "TODO", "alice", "2025-03-01"},
+		{"// TODO(owner=bob due=2025-03-01)", "TODO", "bob", "2025-03-01"},
+		{"// TODO: later @alice due=2025-03-01", "TODO", "alice", "2025-03-01"},
+		{"// TODO: later owner:bob @alice", "TODO", "bob", ""},
+		{"# fixme(carol.d): lower case keyword", "FIXME", "carol.d", ""},
+		{"// TODO: mail me@example.com", "TODO", "", ""},
+		{"// TODO(alice): due:2025-13-45 is no date", "TODO", "alice", ""},
+		{"// TODO: line one\n// due: 2026-01-02", "TODO", "", "2026-01-02"},
+	}
+	for _, tt := range tests {
+		meta := ParseMeta(store.Todo{Comment: tt.comment, Keyword: tt.keyword})
+		due := ""
+		if !meta.Due.IsZero() {
+			due = meta.Due.Format("2006-01-02")
+		}
+		if meta.Owner != tt.owner || due != tt.due {
+			t.Errorf("ParseMeta(%q) = %q, %q, want %q, %q", tt.comment, meta.Owner, due, tt.owner, tt.due)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	items := Items("/src", []store.Todo{
+		{FilePath: "/src/a.go", LineNumber: 1, Keyword: "TODO", Comment: "// TODO(alice, 2024-05-01): overdue"},
+		{FilePath: "/src/a.go", LineNumber: 5, Keyword: "FIXME", Comment: "// FIXME: no owner"},
+		{FilePath: "/src/lib/b.go", LineNumber: 2, Keyword: "TODO", Comment: "// TODO(bob, 2024-06-01): due today"},
+		{FilePath: "/src/lib/b.go", LineNumber: 9, Keyword: "HACK", Comment: "// HACK(carol): fine"},
+	})
+	today := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		opts   Options
+		want   []string // rule and place of each violation
+	}{
+		{"no rules", Policy{}, Options{}, nil},
+		{
+			name:   "forbidden everywhere",
+			policy: Policy{Forbid: []ForbidRule{{Keywords: []string{"fixme", "HACK"}}}},
+			want:   []string{"forbidden-keyword a.go:5", "forbidden-keyword lib/b.go:9"},
+		},
+		{
+			name:   "forbidden on a matching branch",
+			policy: Policy{Forbid: []ForbidRule{{Keywords: []string{"FIXME"}, Branches: []string{"main", "release/*"}}}},
+			opts:   Options{Branch: "release/1.2"},
+			want:   []string{"forbidden-keyword a.go:5"},
+		},
+		{
+			name:   "allowed on other branches",
+			policy: Policy{Forbid: []ForbidRule{{Keywords: []string{"FIXME"}, Branches: []string{"main", "release/*"}}}},
+			opts:   Options{Branch: "release/1.2/hotfix"},
+		},
+		{
+			name:   "allowed without a branch",
+			policy: Policy{Forbid: []ForbidRule{{Keywords: []string{"FIXME"}, Branches: []string{"main"}}}},
+		},
+		{
+			name:   "missing owner",
+			policy: Policy{RequireOwner: true},
+			want:   []string{"missing-owner a.go:5"},
+		},
+		{
+			name:   "overdue",
+			policy: Policy{NoOverdue: true},
+			opts:   Options{Today: today},
+			want:   []string{"overdue a.go:1"},
+		},
+		{
+			name:   "max per package",
+			policy: Policy{MaxPerPackage: 1},
+			want:   []string{"max-per-package .", "max-per-package lib"},
+		},
+		{
+			name:   "under the package limit",
+			policy: Policy{MaxPerPackage: 2},
+		},
+		{
+			name: "baseline flags every item",
+			opts: Options{Baseline: &Baseline{}},
+			want: []string{"new-todo a.go:1", "new-todo a.go:5", "new-todo lib/b.go:2", "new-todo lib/b.go:9"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range Evaluate(items, tt.policy, tt.opts) {
+				place := v.Package
+				if v.File != "" {
+					place = fmt.Sprintf("%s:%d", v.File, v.Line)
+				}
+				got = append(got, v.Rule+" "+place)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("violations:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package check
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"Ttracker/internal/store"
+)
+
+// Meta is what a TODO says about itself: who owns it and when it is due
+type Meta struct {
+	Owner string
+	Due   time.Time // zero when the TODO has no due date
+}
+
+var (
+	dateRe  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	dueRe   = regexp.MustCompile(`(?i)\bdue[:=]\s*(\d{4}-\d{2}-\d{2})`)
+	ownerRe = regexp.MustCompile(`(?i)\bowner[:=]\s*@?([\w.@-]+)`)
+	atRe    = regexp.MustCompile(`(?:^|[\s(\[,])@([\w.-]+)`)
+)
+
+// ParseMeta reads the owner and due date of a TODO from its comment. The
+// usual conventions are understood:
+//
+//	TODO(alice): ...               owner in parentheses after the keyword
+//	TODO(alice, 2025-03-01): ...   with a due date
+//	TODO(@alice due:2025-03-01)    @ and due: prefixes
+//	TODO: ... @alice due=2025-03-01 owner:alice
+func ParseMeta(todo store.Todo) Meta {
+	var meta Meta
+	text := strings.Join(strings.Fields(todo.Comment), " ")
+
+	// Look right after the keyword for (owner, date)
+	rest := text
+	if todo.Keyword != "" {
+		if i := strings.Index(strings.ToUpper(text), strings.ToUpper(todo.Keyword)); i >= 0 {
+			rest = text[i+len(todo.Keyword):]
+		}
+	}
+	if strings.HasPrefix(rest, "(") {
+		if end := strings.Index(rest, ")"); end > 0 {
+			for _, item := range strings.FieldsFunc(rest[1:end], func(r rune) bool {
+				return r == ',' || r == ';' || r == ' '
+			}) {
+				meta.parseItem(item)
+			}
+		}
+	}
+
+	if meta.Due.IsZero() {
+		if m := dueRe.FindStringSubmatch(rest); m != nil {
+			meta.Due = parseDate(m[1])
+		}
+	}
+	if meta.Owner == "" {
+		if m := ownerRe.FindStringSubmatch(rest); m != nil {
+			meta.Owner = m[1]
+		} else if m := atRe.FindStringSubmatch(rest); m != nil {
+			meta.Owner = m[1]
+		}
+	}
+	return meta
+}
+
+// parseItem reads one item of the parentheses after a keyword
+func (m *Meta) parseItem(item string) {
+	lower := strings.ToLower(item)
+	switch {
+	case dateRe.MatchString(item):
+		m.Due = parseDate(item)
+	case strings.HasPrefix(lower, "due:") || strings.HasPrefix(lower, "due="):
+		m.Due = parseDate(item[4:])
+	case strings.HasPrefix(lower, "owner:") || strings.HasPrefix(lower, "owner="):
+		m.Owner = strings.TrimPrefix(item[6:], "@")
+	case m.Owner == "":
+		m.Owner = strings.TrimPrefix(item, "@")
+	}
+}
+
+func parseDate(text string) time.Time {
+	t, err := time.Parse("2006-01-02", text)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,83 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"Ttracker/internal/keywords"
+)
+
+// PolicyFile is the policy read from the root of a checked directory
+const PolicyFile = ".ttcheck.json"
+
+// Policy is the set of rules tt check enforces
+type Policy struct {
+	Keywords      *keywords.Config `json:"keywords,omitempty"`        // the keywords that mark TODOs, the defaults when unset
+	Forbid        []ForbidRule     `json:"forbid,omitempty"`          // keywords not allowed at all, or on some branches
+	RequireOwner  bool             `json:"require_owner,omitempty"`   // every TODO names an owner
+	NoOverdue     bool             `json:"no_overdue,omitempty"`      // no TODO is past its due date
+	MaxPerPackage int              `json:"max_per_package,omitempty"` // the most TODOs allowed in one package, no limit when 0
+}
+
+// ForbidRule forbids keywords on the branches it lists, every branch when
+// it lists none. Branches may be patterns such as release/*.
+type ForbidRule struct {
+	Keywords []string `json:"keywords"`
+	Branches []string `json:"branches,omitempty"`
+}
+
+// LoadPolicy reads a policy file
+func LoadPolicy(path string) (Policy, error) {
+	var policy Policy
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("invalid policy file %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+// KeywordConfig returns the keywords matched by the check. A keyword list in
+// the policy replaces the defaults, like the global keywords of a project.
+func (p Policy) KeywordConfig() keywords.Config {
+	cfg := keywords.Default()
+	if p.Keywords != nil {
+		if len(p.Keywords.Keywords) > 0 {
+			cfg.Keywords = nil
+		}
+		cfg = cfg.Merge(p.Keywords)
+	}
+	return cfg
+}
+
+// forbidden reports whether a keyword is forbidden on branch
+func (p Policy) forbidden(keyword, branch string) bool {
+	for _, rule := range p.Forbid {
+		if !rule.appliesTo(branch) {
+			continue
+		}
+		for _, forbidden := range rule.Keywords {
+			if strings.EqualFold(forbidden, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r ForbidRule) appliesTo(branch string) bool {
+	if len(r.Branches) == 0 {
+		return true
+	}
+	for _, pattern := range r.Branches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
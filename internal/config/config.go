@@ -1,21 +1,197 @@
 package config
 
-
 import (
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"Ttracker/internal/keywords"
 )
 
 const configFile = "data/config.json"
 
+// HomeEnv overrides the location of the Ttracker home directory
+const HomeEnv = "TTRACKER_HOME"
+
+// HomeDir returns the Ttracker home directory, where installed plugins and
+// other per-user state live. It defaults to ~/.ttracker.
+func HomeDir() string {
+	if home := os.Getenv(HomeEnv); home != "" {
+		return home
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return ".ttracker"
+	}
+	return filepath.Join(userHome, ".ttracker")
+}
+
+// StateDir returns the directory holding the daemon's PID, log and status files
+func StateDir() string {
+	return filepath.Join(HomeDir(), "state")
+}
+
+// Path returns the location of the config file
+func Path() string {
+	return configFile
+}
+
 // config struct for Ttracker data
 type Config struct {
-	Projects map[string]string `json:"project"`
-	Active string `json:"active"`
+	Projects map[string]string          `json:"project"`
+	Active   string                     `json:"active"`
+	Keywords *keywords.Config           `json:"keywords,omitempty"` // global TODO keywords
+	Settings map[string]ProjectSettings `json:"settings,omitempty"` // project name -> settings
+	Watch    *WatchSettings             `json:"watch,omitempty"`    // how the daemon schedules scans
+	Context  *ContextSettings           `json:"context,omitempty"`  // source stored with each TODO
 }
 
+// ContextSettings sets the source a scan stores with each TODO
+type ContextSettings struct {
+	Lines     int  `json:"lines"`     // lines kept before and after each TODO
+	Signature bool `json:"signature"` // keep the signature of the enclosing function
+}
+
+// Default scan scheduling of the daemon
+const (
+	DefaultQuietPeriod     = 500 * time.Millisecond
+	DefaultMinScanInterval = 2 * time.Second
+	DefaultPollInterval    = 5 * time.Second
+)
+
+// WatchSettings tunes when the daemon rescans a project after files change.
+// Durations are written like "500ms" or "2s".
+type WatchSettings struct {
+	QuietPeriod     string `json:"quiet_period,omitempty"`      // wait for changes to stop for this long
+	MinScanInterval string `json:"min_scan_interval,omitempty"` // least time between two scans of a project
+	PollInterval    string `json:"poll_interval,omitempty"`     // how often polled projects are checked
+}
+
+// QuietPeriod returns how long a project's files must stay unchanged before
+// the daemon rescans it
+func (c Config) QuietPeriod() time.Duration {
+	if c.Watch == nil {
+		return DefaultQuietPeriod
+	}
+	return parseDuration(c.Watch.QuietPeriod, DefaultQuietPeriod)
+}
+
+// MinScanInterval returns the least time between the start of two scans of
+// the same project
+func (c Config) MinScanInterval() time.Duration {
+	if c.Watch == nil {
+		return DefaultMinScanInterval
+	}
+	return parseDuration(c.Watch.MinScanInterval, DefaultMinScanInterval)
+}
+
+// PollInterval returns how often the daemon checks projects it polls instead
+// of watching
+func (c Config) PollInterval() time.Duration {
+	if c.Watch == nil {
+		return DefaultPollInterval
+	}
+	interval := parseDuration(c.Watch.PollInterval, DefaultPollInterval)
+	if interval <= 0 {
+		return DefaultPollInterval
+	}
+	return interval
+}
+
+// parseDuration parses a duration setting, falling back to def when it is
+// unset or invalid
+func parseDuration(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return def
+	}
+	return d
+}
+
+// ProjectSettings holds options that can be set for a single project
+type ProjectSettings struct {
+	Keywords  *keywords.Config `json:"keywords,omitempty"`   // layered on top of the global keywords
+	GitIgnore *bool            `json:"git_ignore,omitempty"` // use the git ignore rules, on by default
+	Poll      bool             `json:"poll,omitempty"`       // poll for changes instead of using inotify
+	Context   *ContextSettings `json:"context,omitempty"`    // replaces the global context settings
+}
+
+// ForcePoll reports whether the daemon polls a project for changes instead of
+// watching it, as is needed on network filesystems
+func (c Config) ForcePoll(projectName string) bool {
+	return c.Settings[projectName].Poll
+}
+
+// SetPoll sets whether the daemon polls a project instead of watching it
+func (c *Config) SetPoll(projectName string, poll bool) {
+	if c.Settings == nil {
+		c.Settings = make(map[string]ProjectSettings)
+	}
+	settings := c.Settings[projectName]
+	settings.Poll = poll
+	c.Settings[projectName] = settings
+}
+
+// ContextFor returns the source a scan stores with the TODOs of a project,
+// none unless configured
+func (c Config) ContextFor(projectName string) ContextSettings {
+	var settings ContextSettings
+	if c.Context != nil {
+		settings = *c.Context
+	}
+	if project, ok := c.Settings[projectName]; ok && project.Context != nil {
+		settings = *project.Context
+	}
+	if settings.Lines < 0 {
+		settings.Lines = 0
+	}
+	return settings
+}
+
+// SetContext sets the source stored with the TODOs of a project, or of every
+// project without settings of its own when projectName is empty
+func (c *Config) SetContext(projectName string, settings ContextSettings) {
+	if projectName == "" {
+		c.Context = &settings
+		return
+	}
+	if c.Settings == nil {
+		c.Settings = make(map[string]ProjectSettings)
+	}
+	project := c.Settings[projectName]
+	project.Context = &settings
+	c.Settings[projectName] = project
+}
+
+// UseGitIgnore reports whether a project's git ignore rules are layered
+// under its .ttignore files
+func (c Config) UseGitIgnore(projectName string) bool {
+	if settings, ok := c.Settings[projectName]; ok && settings.GitIgnore != nil {
+		return *settings.GitIgnore
+	}
+	return true
+}
+
+// KeywordsFor returns the keyword configuration that applies to a project.
+// A global keyword list replaces the defaults, project keywords are added on top.
+func (c Config) KeywordsFor(projectName string) keywords.Config {
+	cfg := keywords.Default()
+	if c.Keywords != nil {
+		if len(c.Keywords.Keywords) > 0 {
+			cfg.Keywords = nil
+		}
+		cfg = cfg.Merge(c.Keywords)
+	}
+	if settings, ok := c.Settings[projectName]; ok {
+		cfg = cfg.Merge(settings.Keywords)
+	}
+	return cfg
+}
 
 // loadConfig reads and parses config.json
 func LoadConfig() (Config, error) {
@@ -35,7 +211,6 @@ func LoadConfig() (Config, error) {
 	return config, nil
 }
 
-
 // writes the updated config to file
 func SaveConfig(config Config) error {
 	data, err := json.MarshalIndent(config, "", "  ")
@@ -48,5 +223,3 @@ func SaveConfig(config Config) error {
 	os.WriteFile(configFile, data, 0644)
 	return nil
 }
-
-
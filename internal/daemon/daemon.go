@@ -0,0 +1,117 @@
+// Package daemon manages the lifecycle of the background tt daemon: its PID
+// lock file, log file and status report, all kept in the state directory.
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"Ttracker/internal/config"
+	"Ttracker/internal/watcher"
+)
+
+// PIDFile returns the path of the daemon's PID and lock file
+func PIDFile() string {
+	return filepath.Join(config.StateDir(), "daemon.pid")
+}
+
+// LogFile returns the path the detached daemon writes its output to
+func LogFile() string {
+	return filepath.Join(config.StateDir(), "daemon.log")
+}
+
+// StatusFile returns the path of the daemon's status report
+func StatusFile() string {
+	return filepath.Join(config.StateDir(), "status.json")
+}
+
+// SocketFile returns the path of the Unix socket the daemon serves its API on
+func SocketFile() string {
+	return filepath.Join(config.StateDir(), "daemon.sock")
+}
+
+// EventLogFile returns the path of the append-only log of the daemon's events
+func EventLogFile() string {
+	return filepath.Join(config.StateDir(), "events.ndjson")
+}
+
+// ErrRunning is returned by Lock when another daemon holds the lock
+var ErrRunning = errors.New("the daemon is already running")
+
+// ErrUnsupported is returned on platforms the daemon does not run on
+var ErrUnsupported = errors.New("the daemon is not supported on this platform")
+
+// Lock is the PID file held by a running daemon. The file is locked with
+// flock for as long as the daemon runs, so a PID file left behind by a daemon
+// that crashed is detected as stale.
+type Lock struct {
+	file *os.File
+}
+
+// Release removes the PID and status files and releases the lock
+func (l *Lock) Release() error {
+	os.Remove(StatusFile())
+	os.Remove(SocketFile())
+	os.Remove(PIDFile())
+	return l.file.Close()
+}
+
+// Status is the report written by the running daemon
+type Status struct {
+	PID        int                     `json:"pid"`
+	StartedAt  time.Time               `json:"started_at"`
+	UpdatedAt  time.Time               `json:"updated_at"`
+	Dir        string                  `json:"dir"` // working directory holding the data files
+	QueueDepth int                     `json:"queue_depth"`
+	Projects   []watcher.ProjectStatus `json:"projects"`
+}
+
+// NewStatus reports the state of the daemon running w
+func NewStatus(w *watcher.ProjectWatcher, startedAt time.Time) Status {
+	dir, _ := os.Getwd()
+	return Status{
+		PID:        os.Getpid(),
+		StartedAt:  startedAt,
+		UpdatedAt:  time.Now(),
+		Dir:        dir,
+		QueueDepth: w.QueueDepth(),
+		Projects:   w.Projects(),
+	}
+}
+
+// Uptime returns how long the daemon has been running
+func (s Status) Uptime() time.Duration {
+	return time.Since(s.StartedAt).Round(time.Second)
+}
+
+// WriteStatus saves the status report of the running daemon
+func WriteStatus(status Status) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal status: %v", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial report
+	tmp := StatusFile() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write status file: %v", err)
+	}
+	return os.Rename(tmp, StatusFile())
+}
+
+// ReadStatus loads the status report of the running daemon
+func ReadStatus() (Status, error) {
+	var status Status
+	data, err := os.ReadFile(StatusFile())
+	if err != nil {
+		return status, fmt.Errorf("could not read status file: %v", err)
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return status, fmt.Errorf("could not unmarshal status file: %v", err)
+	}
+	return status, nil
+}
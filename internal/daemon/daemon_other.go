@@ -0,0 +1,25 @@
+//go:build !unix
+
+package daemon
+
+import "time"
+
+// Acquire takes the daemon lock, the daemon only runs on Unix systems
+func Acquire() (*Lock, error) {
+	return nil, ErrUnsupported
+}
+
+// Running returns the PID of the running daemon, there is none on this platform
+func Running() (int, bool) {
+	return 0, false
+}
+
+// Start runs the daemon in the background, which is not supported on this platform
+func Start(timeout time.Duration) (int, error) {
+	return 0, ErrUnsupported
+}
+
+// Stop asks the running daemon to shut down, which is not supported on this platform
+func Stop(timeout time.Duration) (int, error) {
+	return 0, ErrUnsupported
+}
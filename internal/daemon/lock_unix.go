@@ -0,0 +1,93 @@
+//go:build unix
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"Ttracker/internal/config"
+)
+
+// Acquire takes the daemon lock and writes the current PID to the PID file
+func Acquire() (*Lock, error) {
+	if err := os.MkdirAll(config.StateDir(), 0755); err != nil {
+		return nil, fmt.Errorf("could not create state directory: %v", err)
+	}
+
+	f, err := os.OpenFile(PIDFile(), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open PID file: %v", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrRunning
+		}
+		return nil, fmt.Errorf("could not lock PID file: %v", err)
+	}
+
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not write PID file: %v", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not write PID file: %v", err)
+	}
+	return &Lock{file: f}, nil
+}
+
+// Running returns the PID of the running daemon. A PID file that is not
+// locked, or names a process that no longer exists, is stale and removed.
+func Running() (int, bool) {
+	data, err := os.ReadFile(PIDFile())
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+
+	if err != nil || !locked() || !processExists(pid) {
+		removeStale()
+		return 0, false
+	}
+	return pid, true
+}
+
+// locked reports whether a daemon holds the PID file lock
+func locked() bool {
+	f, err := os.Open(PIDFile())
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return errors.Is(err, syscall.EWOULDBLOCK)
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return false
+}
+
+// removeStale removes the files left behind by a daemon that did not shut
+// down cleanly, unless a new daemon took the lock meanwhile
+func removeStale() {
+	if locked() {
+		return
+	}
+	os.Remove(PIDFile())
+	os.Remove(StatusFile())
+	os.Remove(SocketFile())
+}
+
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,85 @@
+//go:build unix
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Start runs "tt daemon run" detached from the terminal, in its own session,
+// with its output appended to the log file. The daemon runs in the current
+// directory, where its data files live. Start returns once the daemon holds
+// its lock.
+func Start(timeout time.Duration) (int, error) {
+	if pid, ok := Running(); ok {
+		return pid, fmt.Errorf("the daemon is already running (pid %d)", pid)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("could not find the tt executable: %v", err)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return 0, fmt.Errorf("could not get working directory: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(LogFile()), 0755); err != nil {
+		return 0, fmt.Errorf("could not create state directory: %v", err)
+	}
+	logFile, err := os.OpenFile(LogFile(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("could not open log file: %v", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "daemon", "run")
+	cmd.Dir = dir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("could not start the daemon: %v", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(timeout)
+	for {
+		if pid, ok := Running(); ok && pid == cmd.Process.Pid {
+			return pid, nil
+		}
+		select {
+		case err := <-exited:
+			return 0, fmt.Errorf("the daemon exited during startup (%v), see %s", err, LogFile())
+		case <-deadline:
+			return cmd.Process.Pid, fmt.Errorf("the daemon did not start within %s, see %s", timeout, LogFile())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// Stop asks the running daemon to shut down and waits for it to exit
+func Stop(timeout time.Duration) (int, error) {
+	pid, ok := Running()
+	if !ok {
+		return 0, fmt.Errorf("the daemon is not running")
+	}
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return pid, fmt.Errorf("could not stop the daemon (pid %d): %v", pid, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !processExists(pid) || !locked() {
+			return pid, nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return pid, fmt.Errorf("the daemon (pid %d) did not stop within %s", pid, timeout)
+}
@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"Ttracker/internal/config"
+)
+
+// ServiceName is the name of the systemd user unit running the daemon
+const ServiceName = "ttracker.service"
+
+// ServiceFile returns the path of the systemd user unit
+func ServiceFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "systemd", "user", ServiceName)
+}
+
+// ServiceUnit returns a systemd user unit running exe as the daemon in dir,
+// where its data files live. The daemon reports readiness and pings the
+// watchdog with sd_notify, and its output goes to the journal.
+func ServiceUnit(exe, dir string) string {
+	return fmt.Sprintf(`[Unit]
+Description=Ttracker TODO tracking daemon
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart=%s daemon run
+ExecReload=/bin/kill -HUP $MAINPID
+WorkingDirectory=%s
+Environment=%s
+Restart=on-failure
+RestartSec=5s
+WatchdogSec=30s
+StandardOutput=journal
+StandardError=journal
+SyslogIdentifier=tt
+
+[Install]
+WantedBy=default.target
+`, unitQuote(exe), strings.ReplaceAll(dir, "%", "%%"), unitQuote(config.HomeEnv+"="+config.HomeDir()))
+}
+
+// unitQuote quotes a command line or environment value for a unit file when
+// it contains spaces
+func unitQuote(value string) string {
+	value = strings.ReplaceAll(value, "%", "%%")
+	if !strings.ContainsAny(value, " \t\"\\") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// InstallService writes the systemd user unit for exe running in dir, then
+// enables and starts it
+func InstallService(exe, dir string) error {
+	path := ServiceFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create unit directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(ServiceUnit(exe, dir)), 0644); err != nil {
+		return fmt.Errorf("could not write unit file: %v", err)
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl("enable", "--now", ServiceName)
+}
+
+// UninstallService stops and disables the systemd user unit and removes it.
+// It reports false when no unit is installed.
+func UninstallService() (bool, error) {
+	path := ServiceFile()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err := systemctl("disable", "--now", ServiceName); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return true, fmt.Errorf("could not remove unit file: %v", err)
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	return true, nil
+}
+
+// systemctl runs systemctl for the user's service manager
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl --user %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
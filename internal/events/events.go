@@ -0,0 +1,120 @@
+// Package events publishes what the daemon does to its subscribers
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"Ttracker/internal/store"
+)
+
+// Event types
+const (
+	ScanStarted    = "scan_started"
+	ScanCompleted  = "scan_completed"
+	ProjectAdded   = "project_added"
+	ProjectRemoved = "project_removed"
+	TodoAdded      = "todo_added"
+	TodoRemoved    = "todo_removed"
+	TodoChanged    = "todo_changed"
+)
+
+// Event is something that happened in the daemon
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Project string    `json:"project,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Error   string    `json:"error,omitempty"`
+
+	// Set for TODO events. Previous is the TODO before it changed.
+	Fingerprint string      `json:"fingerprint,omitempty"`
+	Todo        *store.Todo `json:"todo,omitempty"`
+	Previous    *store.Todo `json:"previous,omitempty"`
+}
+
+// FromDiff returns the TODO events for the differences found by a scan of a
+// project: removed TODOs first, then changed and added ones
+func FromDiff(project string, diff store.TodoDiff) []Event {
+	now := time.Now()
+	list := make([]Event, 0, len(diff.Added)+len(diff.Removed)+len(diff.Changed))
+	for _, todo := range diff.Removed {
+		list = append(list, Event{Type: TodoRemoved, Time: now, Project: project, Path: todo.FilePath,
+			Fingerprint: todo.Fingerprint(), Todo: &todo})
+	}
+	for _, change := range diff.Changed {
+		list = append(list, Event{Type: TodoChanged, Time: now, Project: project, Path: change.After.FilePath,
+			Fingerprint: change.After.Fingerprint(), Todo: &change.After, Previous: &change.Before})
+	}
+	for _, todo := range diff.Added {
+		list = append(list, Event{Type: TodoAdded, Time: now, Project: project, Path: todo.FilePath,
+			Fingerprint: todo.Fingerprint(), Todo: &todo})
+	}
+	return list
+}
+
+// Broker fans events out to subscribers
+type Broker struct {
+	mutex sync.Mutex
+	subs  map[chan Event]struct{}
+	log   *Log // appended every event, see SetLog
+}
+
+// NewBroker creates a broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving every event published from now on
+// and a function that ends the subscription. Events are dropped for a
+// subscriber whose buffer is full rather than blocking the daemon.
+func (b *Broker) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mutex.Lock()
+	b.subs[ch] = struct{}{}
+	b.mutex.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			delete(b.subs, ch)
+			b.mutex.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// SetLog makes Publish append every event to log, or stops logging with nil.
+// Unlike a subscriber, which misses events when it falls behind, the log
+// keeps all of them in the order they were published.
+func (b *Broker) SetLog(log *Log) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.log = log
+}
+
+// Publish appends an event to the log and sends it to every subscriber,
+// setting its time if unset
+func (b *Broker) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.log != nil {
+		if err := b.log.Append(event); err != nil {
+			fmt.Printf("Error writing event log: %v\n", err)
+		}
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLogKeepsEveryEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	log, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	b := NewBroker()
+	b.SetLog(log)
+	stream, cancel := b.Subscribe(1) // never read, so it misses events
+	defer cancel()
+
+	const n = 2000
+	for i := 0; i < n; i++ {
+		b.Publish(Event{Type: ScanStarted, Project: "p"})
+	}
+	if len(stream) != 1 {
+		t.Errorf("subscriber holds %d events, want 1", len(stream))
+	}
+
+	logged, err := ReadLog(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != n {
+		t.Fatalf("log has %d events, want %d", len(logged), n)
+	}
+	for i := 1; i < n; i++ {
+		if logged[i].Time.Before(logged[i-1].Time) {
+			t.Fatalf("event %d logged out of order", i)
+		}
+	}
+
+	// Events published once logging stopped are not logged
+	b.SetLog(nil)
+	b.Publish(Event{Type: ScanCompleted, Project: "p"})
+	if logged, _ := ReadLog(path, 0); len(logged) != n {
+		t.Errorf("log has %d events after SetLog(nil), want %d", len(logged), n)
+	}
+}
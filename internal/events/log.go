@@ -0,0 +1,71 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Log is an append-only file of events, one JSON object per line
+type Log struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// OpenLog opens the event log at path for appending, creating it if needed
+func OpenLog(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create event log directory: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open event log: %v", err)
+	}
+	return &Log{file: f}, nil
+}
+
+// Append writes an event to the end of the log
+func (l *Log) Append(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not marshal event: %v", err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// ReadLog returns the last n events of the log at path, or all of them when
+// n is not positive. Lines that are not valid events are skipped.
+func ReadLog(path string, n int) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		list = append(list, event)
+		if n > 0 && len(list) > n {
+			list = list[1:]
+		}
+	}
+	return list, scanner.Err()
+}
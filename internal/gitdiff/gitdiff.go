@@ -0,0 +1,363 @@
+// Package gitdiff finds the TODOs a change adds, removes and edits, either
+// between git revisions or from a unified diff.
+package gitdiff
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"Ttracker/internal/ignore"
+	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
+)
+
+// Edit is a TODO whose text a change edited
+type Edit struct {
+	Before store.Todo `json:"before"`
+	After  store.Todo `json:"after"`
+}
+
+// Result lists the TODOs a change adds, removes and edits. Paths are
+// relative to the repository and slash separated.
+type Result struct {
+	Added   []store.Todo `json:"added"`
+	Removed []store.Todo `json:"removed"`
+	Edited  []Edit       `json:"edited"`
+}
+
+// Summary describes the result in a sentence, e.g. "adds 3 TODOs and
+// resolves 5"
+func (r Result) Summary() string {
+	parts := []string{fmt.Sprintf("adds %d %s", len(r.Added), plural(len(r.Added), "TODO"))}
+	if len(r.Edited) > 0 {
+		parts = append(parts, fmt.Sprintf("edits %d", len(r.Edited)))
+	}
+	parts = append(parts, fmt.Sprintf("resolves %d", len(r.Removed)))
+	if len(parts) == 2 {
+		return parts[0] + " and " + parts[1]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// Repo reads the TODOs of a git repository at its revisions
+type Repo struct {
+	Root    string
+	Manager *scan.Manager         // finds the TODOs in files
+	Ignore  *ignore.IgnoreManager // leaves files out, nil to read every file
+}
+
+// OpenRepo finds the repository dir belongs to
+func OpenRepo(dir string, mgr *scan.Manager) (*Repo, error) {
+	out, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{Root: strings.TrimSpace(string(out)), Manager: mgr}, nil
+}
+
+// ResolveRange turns a revision range into the two revisions to compare:
+// "A..B", "A.." and "..B" as in git, "A...B" from the merge base of A and B,
+// and "A" alone against the working tree. An empty range compares HEAD with
+// the working tree, an empty "to" revision means the working tree.
+func (r *Repo) ResolveRange(spec string) (from, to string, err error) {
+	switch {
+	case spec == "":
+		return "HEAD", "", nil
+	case strings.Contains(spec, "..."):
+		parts := strings.SplitN(spec, "...", 2)
+		from, to = orHead(parts[0]), orHead(parts[1])
+		out, err := git(r.Root, "merge-base", from, to)
+		if err != nil {
+			return "", "", err
+		}
+		return strings.TrimSpace(string(out)), to, nil
+	case strings.Contains(spec, ".."):
+		parts := strings.SplitN(spec, "..", 2)
+		return orHead(parts[0]), orHead(parts[1]), nil
+	default:
+		return spec, "", nil
+	}
+}
+
+func orHead(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+// Diff compares the TODOs of revision from with those of revision to, or of
+// the working tree when to is empty. Only files the change touches are read.
+func (r *Repo) Diff(from, to string) (Result, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--no-textconv", "-U0", "-M", from}
+	if to != "" {
+		args = append(args, to)
+	}
+	out, err := git(r.Root, append(args, "--")...)
+	if err != nil {
+		return Result{}, err
+	}
+	files, err := ParsePatch(bytes.NewReader(out))
+	if err != nil {
+		return Result{}, fmt.Errorf("reading git diff: %v", err)
+	}
+
+	// New files git does not track yet are part of the working tree
+	if to == "" {
+		out, err := git(r.Root, "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return Result{}, err
+		}
+		for _, path := range strings.Split(string(out), "\x00") {
+			if path != "" {
+				files = append(files, FileDiff{NewPath: path})
+			}
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "tt-diff-")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	result := newResult()
+	for _, file := range files {
+		if r.Ignore != nil && r.Ignore.IsIgnored(filepath.Join(r.Root, filepath.FromSlash(file.Path())), false) {
+			continue
+		}
+		if _, err := r.Manager.GetParser(file.Path()); err != nil && !scan.KnowsComments(file.Path()) {
+			continue // no way to find its TODOs
+		}
+
+		var before, after []store.Todo
+		if file.OldPath != "" {
+			if before, err = r.todosAt(tmp, from, file.OldPath); err != nil {
+				return Result{}, err
+			}
+		}
+		if file.NewPath != "" {
+			if after, err = r.todosAt(tmp, to, file.NewPath); err != nil {
+				return Result{}, err
+			}
+		}
+		result.add(compare(before, after, file.Hunks))
+	}
+	return result, nil
+}
+
+// todosAt finds the TODOs of a file at a revision, or in the working tree
+// when rev is empty. Files without a parser, or that their parser fails on,
+// are read with the comment syntax of their language.
+func (r *Repo) todosAt(tmp, rev, path string) ([]store.Todo, error) {
+	file := filepath.Join(r.Root, filepath.FromSlash(path))
+	var content []byte
+	var err error
+	if rev != "" {
+		if content, err = git(r.Root, "cat-file", "blob", rev+":"+path); err != nil {
+			return nil, err
+		}
+		// Parsers read files, keep the name so they know the language
+		file = filepath.Join(tmp, filepath.Base(path))
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			return nil, err
+		}
+		defer os.Remove(file)
+	} else if content, err = os.ReadFile(file); err != nil {
+		return nil, err
+	}
+
+	var todos []store.Todo
+	parsed := false
+	if parser, err := r.Manager.GetParser(path); err == nil {
+		todos, err = parser.ParseFile(file)
+		parsed = err == nil
+	}
+	if !parsed {
+		lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+		todos = r.Manager.ParseFragments(path, []scan.Fragment{{StartLine: 1, Lines: lines}})
+	}
+	for i := range todos {
+		todos[i].FilePath = path
+		todos[i].Context = nil
+	}
+	return todos, nil
+}
+
+// FromPatch finds the TODOs in the lines of a unified diff. Only the hunks
+// are at hand, so TODOs are found with the comment syntax of each file's
+// language rather than its parser.
+func FromPatch(mgr *scan.Manager, files []FileDiff) Result {
+	result := newResult()
+	for _, file := range files {
+		var oldFragments, newFragments []scan.Fragment
+		for _, hunk := range file.Hunks {
+			oldFragments = append(oldFragments, scan.Fragment{StartLine: hunk.OldStart, Lines: hunk.Side(true)})
+			newFragments = append(newFragments, scan.Fragment{StartLine: hunk.NewStart, Lines: hunk.Side(false)})
+		}
+
+		var before, after []store.Todo
+		if file.OldPath != "" {
+			before = mgr.ParseFragments(file.OldPath, oldFragments)
+		}
+		if file.NewPath != "" {
+			after = mgr.ParseFragments(file.NewPath, newFragments)
+		}
+		result.add(compare(before, after, file.Hunks))
+	}
+	return result
+}
+
+// newResult returns an empty result whose lists are empty rather than nil,
+// so they are written as [] in JSON
+func newResult() Result {
+	return Result{Added: []store.Todo{}, Removed: []store.Todo{}, Edited: []Edit{}}
+}
+
+func (r *Result) add(other Result) {
+	r.Added = append(r.Added, other.Added...)
+	r.Removed = append(r.Removed, other.Removed...)
+	r.Edited = append(r.Edited, other.Edited...)
+}
+
+// compare pairs the TODOs of one file before and after a change. TODOs with
+// the same keyword and text are the same TODO, wherever they moved. Of the
+// rest, a removed and an added TODO in the same hunk with similar text were
+// edited.
+func compare(before, after []store.Todo, hunks []Hunk) Result {
+	pending := make(map[string][]int)
+	for i, todo := range before {
+		key := textKey(todo)
+		pending[key] = append(pending[key], i)
+	}
+	matched := make([]bool, len(before))
+	var added []store.Todo
+	for _, todo := range after {
+		key := textKey(todo)
+		if len(pending[key]) > 0 {
+			matched[pending[key][0]] = true
+			pending[key] = pending[key][1:]
+			continue
+		}
+		added = append(added, todo)
+	}
+
+	// Removed TODOs by the hunk they are in
+	byHunk := make(map[int][]int)
+	for i, todo := range before {
+		if !matched[i] {
+			h := hunkOf(hunks, todo, true)
+			byHunk[h] = append(byHunk[h], i)
+		}
+	}
+
+	var result Result
+	edited := make([]bool, len(before))
+	for _, todo := range added {
+		if h := hunkOf(hunks, todo, false); h >= 0 {
+			if i, ok := similarIn(before, byHunk[h], edited, todo); ok {
+				edited[i] = true
+				result.Edited = append(result.Edited, Edit{Before: before[i], After: todo})
+				continue
+			}
+		}
+		result.Added = append(result.Added, todo)
+	}
+	for i, todo := range before {
+		if !matched[i] && !edited[i] {
+			result.Removed = append(result.Removed, todo)
+		}
+	}
+	return result
+}
+
+// similarIn returns the first TODO among candidates that is not edited yet
+// and has the keyword of todo and at least half the words of the shorter text
+func similarIn(todos []store.Todo, candidates []int, edited []bool, todo store.Todo) (int, bool) {
+	words := wordSet(todo.Comment, todo.Keyword)
+	for _, i := range candidates {
+		if edited[i] || !strings.EqualFold(todos[i].Keyword, todo.Keyword) {
+			continue
+		}
+		other := wordSet(todos[i].Comment, todos[i].Keyword)
+		shared := 0
+		for word := range other {
+			if words[word] {
+				shared++
+			}
+		}
+		shorter := len(words)
+		if len(other) < shorter {
+			shorter = len(other)
+		}
+		if shared*2 >= shorter {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// wordSet returns the words of a comment but its keyword, lower-cased
+func wordSet(comment, keyword string) map[string]bool {
+	keyword = strings.ToLower(keyword)
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(comment), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if word != keyword {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// textKey identifies a TODO by its keyword and text, whitespace collapsed
+func textKey(todo store.Todo) string {
+	return strings.ToUpper(todo.Keyword) + "\x00" + strings.Join(strings.Fields(todo.Comment), " ")
+}
+
+// hunkOf returns the index of the first hunk whose old or new lines overlap
+// the TODO's, -1 when none does
+func hunkOf(hunks []Hunk, todo store.Todo, old bool) int {
+	first, last := todo.LineNumber, todo.EndLine
+	if last < first {
+		last = first
+	}
+	for i, h := range hunks {
+		start, count := h.NewStart, h.NewLines
+		if old {
+			start, count = h.OldStart, h.OldLines
+		}
+		if count > 0 && first < start+count && last >= start {
+			return i
+		}
+	}
+	return -1
+}
+
+// git runs a git command in dir and returns its output
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
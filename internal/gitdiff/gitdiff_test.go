@@ -0,0 +1,155 @@
+package gitdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
+)
+
+// todo is a TODO of a.go at line
+func todo(line int, keyword, text string) store.Todo {
+	return store.Todo{FilePath: "a.go", LineNumber: line, Keyword: keyword, Comment: "// " + keyword + ": " + text}
+}
+
+// describeResult writes each TODO of a result as "+line text", "-line text"
+// or "~old>new text"
+func describeResult(r Result) []string {
+	var out []string
+	for _, t := range r.Removed {
+		out = append(out, fmt.Sprintf("-%d %s", t.LineNumber, t.Comment))
+	}
+	for _, e := range r.Edited {
+		out = append(out, fmt.Sprintf("~%d>%d %s", e.Before.LineNumber, e.After.LineNumber, e.After.Comment))
+	}
+	for _, t := range r.Added {
+		out = append(out, fmt.Sprintf("+%d %s", t.LineNumber, t.Comment))
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []store.Todo
+		after  []store.Todo
+		hunks  []Hunk
+		want   []string
+	}{
+		{
+			name:   "moved by code above",
+			before: []store.Todo{todo(3, "TODO", "keep")},
+			after:  []store.Todo{todo(8, "TODO", "keep  ")},
+			hunks:  []Hunk{{OldStart: 1, OldLines: 0, NewStart: 1, NewLines: 5}},
+		},
+		{
+			name:   "edited in the same hunk",
+			before: []store.Todo{todo(3, "TODO", "handle the error")},
+			after:  []store.Todo{todo(3, "TODO", "handle the timeout error")},
+			hunks:  []Hunk{{OldStart: 3, OldLines: 1, NewStart: 3, NewLines: 1}},
+			want:   []string{"~3>3 // TODO: handle the timeout error"},
+		},
+		{
+			name:   "rewritten text is a new TODO",
+			before: []store.Todo{todo(3, "TODO", "handle the error")},
+			after:  []store.Todo{todo(3, "TODO", "cache lookups")},
+			hunks:  []Hunk{{OldStart: 3, OldLines: 1, NewStart: 3, NewLines: 1}},
+			want:   []string{"-3 // TODO: handle the error", "+3 // TODO: cache lookups"},
+		},
+		{
+			name:   "another keyword is a new TODO",
+			before: []store.Todo{todo(3, "TODO", "handle the error")},
+			after:  []store.Todo{todo(3, "FIXME", "handle the error")},
+			hunks:  []Hunk{{OldStart: 3, OldLines: 1, NewStart: 3, NewLines: 1}},
+			want:   []string{"-3 // TODO: handle the error", "+3 // FIXME: handle the error"},
+		},
+		{
+			name:   "similar text in another hunk",
+			before: []store.Todo{todo(3, "TODO", "handle the error")},
+			after:  []store.Todo{todo(40, "TODO", "handle the timeout error")},
+			hunks: []Hunk{
+				{OldStart: 3, OldLines: 1, NewStart: 3, NewLines: 0},
+				{OldStart: 41, OldLines: 0, NewStart: 40, NewLines: 1},
+			},
+			want: []string{"-3 // TODO: handle the error", "+40 // TODO: handle the timeout error"},
+		},
+		{
+			name:   "duplicates are paired one to one",
+			before: []store.Todo{todo(3, "TODO", "same"), todo(9, "TODO", "same")},
+			after:  []store.Todo{todo(3, "TODO", "same")},
+			hunks:  []Hunk{{OldStart: 9, OldLines: 1, NewStart: 8, NewLines: 0}},
+			want:   []string{"-9 // TODO: same"},
+		},
+		{
+			name:   "each removed TODO is edited once",
+			before: []store.Todo{todo(3, "TODO", "parse the header"), todo(4, "TODO", "parse the body")},
+			after: []store.Todo{
+				todo(3, "TODO", "parse the header fields"),
+				todo(4, "TODO", "parse the header again"),
+				todo(5, "TODO", "parse the body fast"),
+			},
+			hunks: []Hunk{{OldStart: 3, OldLines: 2, NewStart: 3, NewLines: 3}},
+			want: []string{
+				"~3>3 // TODO: parse the header fields",
+				"~4>4 // TODO: parse the header again",
+				"+5 // TODO: parse the body fast",
+			},
+		},
+		{
+			name:   "added and removed files",
+			before: []store.Todo{todo(1, "TODO", "old")},
+			hunks:  []Hunk{{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0}},
+			want:   []string{"-1 // TODO: old"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeResult(compare(tt.before, tt.after, tt.hunks))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("compare:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+			}
+		})
+	}
+}
+
+func TestFromPatch(t *testing.T) {
+	patch := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -3 +3 @@ func f() {
+-	// TODO: handle the error
++	// TODO: handle the timeout error
+@@ -10,0 +11,2 @@ func g() {
++	// FIXME: leaks
++	x := 1 // not a TODO: trailing prose
+diff --git a/b.py b/b.py
+deleted file mode 100644
+--- a/b.py
++++ /dev/null
+@@ -1,2 +0,0 @@
+-# TODO: py thing
+-x = 1
+diff --git a/notes.txt b/notes.txt
+--- a/notes.txt
++++ b/notes.txt
+@@ -1 +1 @@
+-TODO: no comment syntax
++TODO: still none
+`
+	files, err := ParsePatch(strings.NewReader(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := describeResult(FromPatch(scan.NewBuiltinManager(), files))
+	want := []string{
+		"-1 # TODO: py thing",
+		"~3>3 // TODO: handle the timeout error",
+		"+11 // FIXME: leaks",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("FromPatch:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
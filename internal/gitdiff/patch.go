@@ -0,0 +1,200 @@
+package gitdiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileDiff is the change to one file in a unified diff. OldPath is empty for
+// added files and NewPath for deleted ones.
+type FileDiff struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// Path is the file's name after the change, or before it when deleted
+func (f FileDiff) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// Hunk is a changed region of a file. Lines keep their diff prefix: ' ' for
+// context, '-' for removed and '+' for added lines.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []string
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch reads a unified diff, as written by git diff or diff -u. Paths
+// lose the a/ and b/ prefixes git adds.
+func ParsePatch(r io.Reader) ([]FileDiff, error) {
+	var files []FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+	oldLeft, newLeft := 0, 0 // lines the current hunk still misses on each side
+	gitStyle := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		lineNo++
+
+		// Lines of the current hunk come first, they may look like headers
+		if oldLeft > 0 || newLeft > 0 {
+			if strings.HasPrefix(line, "\\") {
+				continue // "\ No newline at end of file"
+			}
+			if line == "" {
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				oldLeft--
+				newLeft--
+			case '-':
+				oldLeft--
+			case '+':
+				newLeft--
+			default:
+				return nil, fmt.Errorf("line %d: hunk ends early", lineNo)
+			}
+			hunk.Lines = append(hunk.Lines, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			gitStyle = true
+			files = append(files, FileDiff{})
+			file, hunk = &files[len(files)-1], nil
+			// Renames and mode changes without content have no ---/+++ lines
+			if old, new, ok := gitPaths(line); ok {
+				file.OldPath, file.NewPath = old, new
+			}
+		case file != nil && strings.HasPrefix(line, "rename from "):
+			file.OldPath = unquote(strings.TrimPrefix(line, "rename from "))
+		case file != nil && strings.HasPrefix(line, "rename to "):
+			file.NewPath = unquote(strings.TrimPrefix(line, "rename to "))
+		case file != nil && strings.HasPrefix(line, "new file mode"):
+			file.OldPath = ""
+		case file != nil && strings.HasPrefix(line, "deleted file mode"):
+			file.NewPath = ""
+		case strings.HasPrefix(line, "--- "):
+			if !gitStyle || file == nil {
+				files = append(files, FileDiff{})
+				file = &files[len(files)-1]
+			}
+			file.OldPath = patchPath(line[4:], "a/", gitStyle)
+			hunk = nil
+		case strings.HasPrefix(line, "+++ "):
+			if file == nil {
+				return nil, fmt.Errorf("line %d: +++ without ---", lineNo)
+			}
+			file.NewPath = patchPath(line[4:], "b/", gitStyle)
+		case strings.HasPrefix(line, "@@ "):
+			if file == nil {
+				return nil, fmt.Errorf("line %d: hunk outside of a file", lineNo)
+			}
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: invalid hunk header %q", lineNo, line)
+			}
+			file.Hunks = append(file.Hunks, Hunk{
+				OldStart: atoi(m[1], 0),
+				OldLines: atoi(m[2], 1),
+				NewStart: atoi(m[3], 0),
+				NewLines: atoi(m[4], 1),
+			})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			oldLeft, newLeft = hunk.OldLines, hunk.NewLines
+		default:
+			// Commit messages, index lines and other chatter
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, fmt.Errorf("the diff ends in the middle of a hunk")
+	}
+	return files, nil
+}
+
+// Side returns the lines of one side of the hunk, without their prefix
+func (h Hunk) Side(old bool) []string {
+	var lines []string
+	for _, line := range h.Lines {
+		if line[0] == ' ' || old && line[0] == '-' || !old && line[0] == '+' {
+			lines = append(lines, line[1:])
+		}
+	}
+	return lines
+}
+
+// gitPaths reads the paths of a "diff --git a/x b/x" line, which is only
+// unambiguous when both are the same
+func gitPaths(line string) (string, string, bool) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if len(rest)%2 == 0 {
+		return "", "", false
+	}
+	half := (len(rest) - 1) / 2
+	old, new := rest[:half], rest[half+1:]
+	if !strings.HasPrefix(old, "a/") || !strings.HasPrefix(new, "b/") || old[2:] != new[2:] {
+		return "", "", false
+	}
+	return old[2:], new[2:], true
+}
+
+// patchPath cleans the path of a ---/+++ line: /dev/null means no file, a
+// tab starts a timestamp and git prefixes paths with a/ or b/
+func patchPath(path, prefix string, gitStyle bool) string {
+	if i := strings.Index(path, "\t"); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return ""
+	}
+	path = unquote(path)
+	if gitStyle {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+// unquote decodes a path git quoted because of its special characters, e.g.
+// "caf\303\251.go"
+func unquote(path string) string {
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
+
+func atoi(text string, fallback int) int {
+	if text == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,214 @@
+package gitdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describePatch writes each file as "old -> new" followed by its hunks
+func describePatch(files []FileDiff) []string {
+	var out []string
+	for _, f := range files {
+		out = append(out, fmt.Sprintf("%q -> %q", f.OldPath, f.NewPath))
+		for _, h := range f.Hunks {
+			out = append(out, fmt.Sprintf("  -%d,%d +%d,%d %s", h.OldStart, h.OldLines, h.NewStart, h.NewLines, strings.Join(h.Lines, "|")))
+		}
+	}
+	return out
+}
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  []string
+		err   string
+	}{
+		{
+			name: "modified file",
+			patch: `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -1,3 +1,3 @@ package a
+ package a
+-// TODO: old
++// TODO: new
+ func f() {}
+`,
+			want: []string{`"a.go" -> "a.go"`, "  -1,3 +1,3  package a|-// TODO: old|+// TODO: new| func f() {}"},
+		},
+		{
+			name: "new and deleted files",
+			patch: `diff --git a/new.py b/new.py
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/new.py
+@@ -0,0 +1,2 @@
++# TODO: py
++x = 1
+diff --git a/old.go b/old.go
+deleted file mode 100644
+index 4444444..0000000
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-// FIXME: gone
+`,
+			want: []string{
+				`"" -> "new.py"`, "  -0,0 +1,2 +# TODO: py|+x = 1",
+				`"old.go" -> ""`, "  -1,1 +0,0 -// FIXME: gone",
+			},
+		},
+		{
+			name: "renames",
+			patch: `diff --git a/x.go b/y.go
+similarity index 100%
+rename from x.go
+rename to y.go
+diff --git a/dir/old name.go b/dir/new name.go
+similarity index 80%
+rename from dir/old name.go
+rename to dir/new name.go
+index 5555555..6666666 100644
+--- a/dir/old name.go
++++ b/dir/new name.go
+@@ -2 +2 @@
+-// TODO: a
++// TODO: b
+`,
+			want: []string{
+				`"x.go" -> "y.go"`,
+				`"dir/old name.go" -> "dir/new name.go"`, "  -2,1 +2,1 -// TODO: a|+// TODO: b",
+			},
+		},
+		{
+			name: "quoted paths",
+			patch: `diff --git "a/caf\303\251.go" "b/caf\303\251.go"
+index 7777777..8888888 100644
+--- "a/caf\303\251.go"
++++ "b/caf\303\251.go"
+@@ -5,0 +6,2 @@ func f() {
++// TODO: one
++// TODO: two
+diff --git "a/\303\251.go" "b/\303\251t\303\251.go"
+similarity index 100%
+rename from "\303\251.go"
+rename to "\303\251t\303\251.go"
+`,
+			want: []string{
+				`"café.go" -> "café.go"`, "  -5,0 +6,2 +// TODO: one|+// TODO: two",
+				`"é.go" -> "été.go"`,
+			},
+		},
+		{
+			name: "no newline at end of file",
+			patch: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+ package a
+-// TODO: old
+\ No newline at end of file
++// TODO: new
+\ No newline at end of file
+`,
+			want: []string{`"a.go" -> "a.go"`, "  -1,2 +1,2  package a|-// TODO: old|+// TODO: new"},
+		},
+		{
+			name: "zero context hunks",
+			patch: `diff --git a/a.c b/a.c
+--- a/a.c
++++ b/a.c
+@@ -3 +3 @@ int main()
+-  /* TODO: a */
++  /* TODO: b */
+@@ -10,2 +9,0 @@
+-  // FIXME: x
+-  // FIXME: y
+@@ -20,0 +19 @@
++  // HACK: z
+`,
+			want: []string{
+				`"a.c" -> "a.c"`,
+				"  -3,1 +3,1 -  /* TODO: a */|+  /* TODO: b */",
+				"  -10,2 +9,0 -  // FIXME: x|-  // FIXME: y",
+				"  -20,0 +19,1 +  // HACK: z",
+			},
+		},
+		{
+			name: "hunk lines that look like headers",
+			patch: `diff --git a/q.sql b/q.sql
+--- a/q.sql
++++ b/q.sql
+@@ -1,3 +1,3 @@
+--- TODO: old
++++ TODO: new
+-diff --git is text here
+
++@@ also text
+`,
+			want: []string{`"q.sql" -> "q.sql"`, "  -1,3 +1,3 --- TODO: old|+++ TODO: new|-diff --git is text here| |+@@ also text"},
+		},
+		{
+			name: "diff -u",
+			patch: "--- x.c.orig\t2024-01-01 10:00:00.000000000 +0100\n" +
+				"+++ x.c\t2024-01-02 10:00:00.000000000 +0100\n" +
+				"@@ -1 +1 @@\n" +
+				"-// TODO: a\n" +
+				"+// TODO: b\n",
+			want: []string{`"x.c.orig" -> "x.c"`, "  -1,1 +1,1 -// TODO: a|+// TODO: b"},
+		},
+		{
+			name: "hunk ends early",
+			patch: `--- a
++++ b
+@@ -1,2 +1,2 @@
+ one
+diff --git a/c b/c
+`,
+			err: "line 5: hunk ends early",
+		},
+		{
+			name:  "diff ends in a hunk",
+			patch: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n one\n",
+			err:   "the diff ends in the middle of a hunk",
+		},
+		{
+			name:  "hunk outside of a file",
+			patch: "@@ -1 +1 @@\n-a\n+b\n",
+			err:   "line 1: hunk outside of a file",
+		},
+		{
+			name:  "+++ without ---",
+			patch: "+++ b/a.go\n",
+			err:   "line 1: +++ without ---",
+		},
+		{
+			name:  "invalid hunk header",
+			patch: "--- a\n+++ b\n@@ -x +1 @@\n",
+			err:   `line 3: invalid hunk header "@@ -x +1 @@"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ParsePatch(strings.NewReader(tt.patch))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := describePatch(files)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("parsed:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -2,87 +2,559 @@ package ignore
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 )
 
+// IgnoreFile is the name of the ignore files read in every project directory
+const IgnoreFile = ".ttignore"
+
+// GitIgnoreFile is the name of git's ignore files, read when a project uses
+// its git ignore rules
+const GitIgnoreFile = ".gitignore"
+
 // IgnorePattern represents a single ignore pattern
 type IgnorePattern struct {
-	Pattern string
-	IsDir   bool
+	Text     string // the pattern as written in its ignore file
+	Pattern  string // the pattern without its leading "!" and trailing "/"
+	IsDir    bool   // the pattern only matches directories
+	Negate   bool   // the pattern re-includes paths excluded by an earlier one
+	Anchored bool   // the pattern is matched against the path relative to Base instead of at any depth
+	Base     string // directory the pattern is relative to, empty for patterns added in code
+	Source   string // file the pattern was read from, empty for patterns added in code
+	Line     int    // line of the pattern in Source
+
+	re *regexp.Regexp
+}
+
+// String describes the pattern and where it comes from
+func (p IgnorePattern) String() string {
+	pattern := p.Text
+	if pattern == "" {
+		pattern = p.Pattern
+		if p.Anchored && !strings.Contains(pattern, "/") {
+			pattern = "/" + pattern
+		}
+		if p.Negate {
+			pattern = "!" + pattern
+		}
+		if p.IsDir {
+			pattern += "/"
+		}
+	}
+	if p.Source == "" {
+		return pattern
+	}
+	return fmt.Sprintf("%s:%d: %s", p.Source, p.Line, pattern)
 }
 
-// IgnoreManager handles ignore patterns for a project
+// matches reports whether rel, a slash separated path relative to the
+// pattern's base, matches the pattern
+func (p *IgnorePattern) matches(rel string, isDir bool) bool {
+	if p.IsDir && !isDir {
+		return false
+	}
+	return p.re.MatchString(rel)
+}
+
+// IgnoreManager handles ignore patterns for a project. It follows the
+// gitignore rules: the last matching pattern wins, "!" re-includes a path,
+// patterns containing a slash are anchored to the directory of their ignore
+// file, "**" matches any number of directories and a trailing "/" only matches
+// directories. Ignore files in subdirectories are read as they are reached and
+// take precedence over the ones above them. Nothing inside an ignored
+// directory can be re-included.
+//
+// Projects can also use git's ignore rules. They are layered under .ttignore,
+// from lowest to highest precedence: the global excludes file
+// (core.excludesFile), .git/info/exclude and the .gitignore files.
 type IgnoreManager struct {
-	patterns []IgnorePattern
+	patterns []IgnorePattern            // patterns added in code, relative to the project root
+	files    map[string][]IgnorePattern // patterns of the ignore files in each directory, keyed by path
+	projects []*project                 // nested ignore files are only read below a project root
+	mutex    sync.Mutex
+
+	committedOnly bool // only the ignore files of the work tree are read, not git's excludes files
+}
+
+// project is a directory tree whose ignore files are read
+type project struct {
+	root     string
+	gitRoot  string          // top of the git work tree holding root, empty if git rules are not used
+	excludes []IgnorePattern // global and repository excludes, relative to gitRoot
 }
 
 // NewIgnoreManager creates a new ignore manager
 func NewIgnoreManager() *IgnoreManager {
 	return &IgnoreManager{
 		patterns: make([]IgnorePattern, 0),
+		files:    make(map[string][]IgnorePattern),
+	}
+}
+
+// NewProjectIgnoreManager creates an ignore manager holding the default
+// patterns and the ignore files of the project at root
+func NewProjectIgnoreManager(root string, useGit bool) (*IgnoreManager, error) {
+	im := newDefaultIgnoreManager()
+	return im, im.LoadProject(root, useGit)
+}
+
+// NewRepoIgnoreManager creates an ignore manager holding the default patterns
+// and the .ttignore and .gitignore files of the repository at root. Git's
+// global excludes file and the clone's .git/info/exclude are left out, so the
+// result only depends on the files of the repository.
+func NewRepoIgnoreManager(root string) (*IgnoreManager, error) {
+	im := newDefaultIgnoreManager()
+	im.committedOnly = true
+	return im, im.LoadProject(root, true)
+}
+
+// newDefaultIgnoreManager creates an ignore manager holding the default patterns
+func newDefaultIgnoreManager() *IgnoreManager {
+	im := NewIgnoreManager()
+	for _, pattern := range GetDefaultPatterns() {
+		im.AddPattern(pattern.Pattern, pattern.IsDir)
+	}
+	return im
+}
+
+// LoadProject makes root a project root and loads its ignore file. Ignore
+// files in subdirectories are loaded when paths below them are matched. With
+// useGit the git ignore rules of the work tree holding root apply as well. A
+// missing ignore file is not an error.
+func (im *IgnoreManager) LoadProject(root string, useGit bool) error {
+	root = absPath(root)
+
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+
+	p := im.addProject(root)
+	if useGit && p.gitRoot == "" {
+		if gitRoot := findGitRoot(root); gitRoot != "" {
+			p.gitRoot = gitRoot
+			if !im.committedOnly {
+				p.excludes = loadGitExcludes(gitRoot)
+			}
+		}
 	}
+
+	patterns, err := ParseFile(filepath.Join(root, IgnoreFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read ignore file: %v", err)
+	}
+	im.files[filepath.Join(root, IgnoreFile)] = patterns
+	return nil
 }
 
-// LoadFromFile loads ignore patterns from a file
+// findGitRoot returns the top of the git work tree holding dir
+func findGitRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadGitExcludes reads the global excludes file and .git/info/exclude of the
+// work tree at gitRoot. Their patterns are relative to the work tree.
+func loadGitExcludes(gitRoot string) []IgnorePattern {
+	var excludes []IgnorePattern
+	for _, file := range []string{globalExcludesFile(gitRoot), filepath.Join(gitRoot, ".git", "info", "exclude")} {
+		if file == "" {
+			continue
+		}
+		patterns, err := ParseFile(file)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("Warning: Failed to load git excludes %s: %v", file, err)
+			}
+			continue
+		}
+		for i := range patterns {
+			patterns[i].Base = gitRoot
+		}
+		excludes = append(excludes, patterns...)
+	}
+	return excludes
+}
+
+// globalExcludesFile returns git's core.excludesFile, defaulting like git to
+// $XDG_CONFIG_HOME/git/ignore
+func globalExcludesFile(gitRoot string) string {
+	out, err := exec.Command("git", "-C", gitRoot, "config", "--path", "--get", "core.excludesFile").Output()
+	if err == nil && strings.TrimSpace(string(out)) != "" {
+		return strings.TrimSpace(string(out))
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "git", "ignore")
+}
+
+// LoadFromFile loads ignore patterns from a file. The patterns are relative
+// to the file's directory, which becomes a project root unless it is inside
+// one already. The returned error wraps the read error, so
+// errors.Is(err, os.ErrNotExist) reports a missing file.
 func (im *IgnoreManager) LoadFromFile(path string) error {
+	path = absPath(path)
+	patterns, err := ParseFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+
+	// The patterns are used as the ignore file of the directory
+	dir := filepath.Dir(path)
+	if im.projectFor(dir) == nil {
+		im.addProject(dir)
+	}
+	key := filepath.Join(dir, IgnoreFile)
+	if _, loaded := im.files[key]; !loaded && path != key {
+		im.filePatterns(dir, IgnoreFile)
+	}
+	im.files[key] = append(im.files[key], patterns...)
+	return nil
+}
+
+// ParseFile reads the patterns of an ignore file
+func ParseFile(path string) ([]IgnorePattern, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to read ignore file: %v", err)
+		return nil, err
 	}
 
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
+	var patterns []IgnorePattern
+	base := filepath.Dir(path)
+	for i, line := range strings.Split(string(content), "\n") {
+		pattern, ok := ParsePattern(line)
+		if !ok {
 			continue // Skip empty lines and comments
 		}
+		pattern.Base = base
+		pattern.Source = path
+		pattern.Line = i + 1
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// ParsePattern parses a line of an ignore file. It reports false for blank
+// lines and comments.
+func ParsePattern(line string) (IgnorePattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are dropped unless escaped with a backslash
+	trimmed := strings.TrimRight(line, " ")
+	if strings.HasSuffix(trimmed, `\`) && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+	line = trimmed
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return IgnorePattern{}, false
+	}
 
-		// Handle directory patterns (ending with /)
-		isDir := strings.HasSuffix(line, "/")
-		if isDir {
-			line = strings.TrimSuffix(line, "/")
+	p := IgnorePattern{Text: line}
+	if strings.HasPrefix(line, "!") {
+		p.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	// Handle directory patterns (ending with /)
+	if strings.HasSuffix(line, "/") {
+		p.IsDir = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// A slash at the start or in the middle anchors the pattern
+	if strings.Contains(line, "/") {
+		p.Anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return IgnorePattern{}, false
+	}
+
+	p.Pattern = line
+	p.re = compileGlob(line, p.Anchored)
+	return p, true
+}
+
+// compileGlob turns a gitignore glob into a regular expression matching
+// slash separated relative paths
+func compileGlob(glob string, anchored bool) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				atStart := i == 0 || glob[i-1] == '/'
+				rest := glob[i+2:]
+				if atStart && strings.HasPrefix(rest, "/") {
+					// "**/" matches zero or more directories
+					sb.WriteString("(?:.*/)?")
+					i += 2
+					continue
+				}
+				if atStart && rest == "" {
+					// A trailing "/**" matches everything inside
+					sb.WriteString(".*")
+					i++
+					continue
+				}
+				// Other consecutive asterisks are regular asterisks
+				for i+1 < len(glob) && glob[i+1] == '*' {
+					i++
+				}
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := classEnd(glob, i)
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : end]
+			sb.WriteString("[")
+			if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+				sb.WriteString("^/")
+				class = class[1:]
+			}
+			sb.WriteString(strings.ReplaceAll(class, `\`, `\\`))
+			sb.WriteString("]")
+			i = end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				c = glob[i]
+			}
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
 		}
+	}
+	sb.WriteString("$")
 
-		im.patterns = append(im.patterns, IgnorePattern{
-			Pattern: line,
-			IsDir:   isDir,
-		})
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		// Fall back to a literal match for malformed character classes
+		return regexp.MustCompile("^" + regexp.QuoteMeta(glob) + "$")
 	}
+	return re
+}
 
-	return nil
+// classEnd returns the index of the "]" closing the character class opened at
+// start, or -1 if it is not closed
+func classEnd(glob string, start int) int {
+	i := start + 1
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		i++
+	}
+	if i < len(glob) && glob[i] == ']' {
+		i++ // a leading "]" is part of the class
+	}
+	for ; i < len(glob); i++ {
+		if glob[i] == ']' {
+			return i
+		}
+	}
+	return -1
 }
 
-// AddPattern adds a single ignore pattern
+// AddPattern adds a single ignore pattern, using the same syntax as ignore
+// files, relative to the project root
 func (im *IgnoreManager) AddPattern(pattern string, isDir bool) {
-	im.patterns = append(im.patterns, IgnorePattern{
-		Pattern: pattern,
-		IsDir:   isDir,
-	})
+	p, ok := ParsePattern(pattern)
+	if !ok {
+		return
+	}
+	if isDir && !p.IsDir {
+		p.IsDir = true
+		p.Text += "/"
+	}
+
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.patterns = append(im.patterns, p)
 }
 
 // ShouldIgnore checks if a path should be ignored
 func (im *IgnoreManager) ShouldIgnore(path string) bool {
-	// Convert path to use forward slashes for consistency
-	path = filepath.ToSlash(path)
-
-	for _, pattern := range im.patterns {
-		// Handle directory patterns
-		if pattern.IsDir {
-			if strings.HasSuffix(path, pattern.Pattern) {
-				return true
+	info, err := os.Lstat(path)
+	return im.IsIgnored(path, err == nil && info.IsDir())
+}
+
+// IsIgnored checks if a path, known to be a directory or not, should be ignored
+func (im *IgnoreManager) IsIgnored(path string, isDir bool) bool {
+	p := im.Match(path, isDir)
+	return p != nil && !p.Negate
+}
+
+// Match returns the pattern deciding whether path is ignored, or nil when no
+// pattern matches. The path is ignored unless the pattern is a negation.
+func (im *IgnoreManager) Match(path string, isDir bool) *IgnorePattern {
+	path = absPath(path)
+
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+
+	p := im.projectFor(path)
+	if p == nil {
+		// Outside every project only the patterns added in code apply
+		return im.matchPath(nil, path, isDir)
+	}
+
+	rel, err := filepath.Rel(p.root, path)
+	if err != nil || rel == "." {
+		return nil
+	}
+
+	// Paths inside an excluded directory are excluded too
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i < len(parts); i++ {
+		dir := filepath.Join(p.root, filepath.FromSlash(strings.Join(parts[:i], "/")))
+		if match := im.matchPath(p, dir, true); match != nil && !match.Negate {
+			return match
+		}
+	}
+	return im.matchPath(p, path, isDir)
+}
+
+// matchPath finds the last pattern matching path, ignoring its parents
+func (im *IgnoreManager) matchPath(p *project, path string, isDir bool) *IgnorePattern {
+	var match *IgnorePattern
+	check := func(patterns []IgnorePattern, base string) {
+		rel := relativeSlash(base, path)
+		for i := range patterns {
+			if patterns[i].matches(rel, isDir) {
+				match = &patterns[i]
 			}
-			continue
 		}
+	}
+
+	if p == nil {
+		check(im.patterns, "")
+		return match
+	}
+	check(im.patterns, p.root)
 
-		// Handle file patterns
-		if matched, _ := filepath.Match(pattern.Pattern, filepath.Base(path)); matched {
-			return true
+	// Git rules come first so .ttignore takes precedence over them
+	if p.gitRoot != "" {
+		check(p.excludes, p.gitRoot)
+		for _, dir := range dirChain(p.gitRoot, filepath.Dir(path)) {
+			check(im.filePatterns(dir, GitIgnoreFile), dir)
 		}
 	}
 
-	return false
+	// Ignore files from the root down to the path's directory, deeper files
+	// taking precedence
+	for _, dir := range dirChain(p.root, filepath.Dir(path)) {
+		check(im.filePatterns(dir, IgnoreFile), dir)
+	}
+	return match
+}
+
+// dirChain lists the directories from top down to dir, which must be inside top
+func dirChain(top, dir string) []string {
+	chain := []string{top}
+	rel, err := filepath.Rel(top, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return chain
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		top = filepath.Join(top, part)
+		chain = append(chain, top)
+	}
+	return chain
+}
+
+// filePatterns returns the patterns of the named ignore file in dir, reading
+// it the first time the directory is reached
+func (im *IgnoreManager) filePatterns(dir, name string) []IgnorePattern {
+	path := filepath.Join(dir, name)
+	if patterns, ok := im.files[path]; ok {
+		return patterns
+	}
+
+	patterns, err := ParseFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to load ignore file %s: %v", path, err)
+	}
+	im.files[path] = patterns
+	return patterns
+}
+
+// projectFor returns the project with the deepest root containing path
+func (im *IgnoreManager) projectFor(path string) *project {
+	var best *project
+	for _, p := range im.projects {
+		if isWithin(p.root, path) && (best == nil || len(p.root) > len(best.root)) {
+			best = p
+		}
+	}
+	return best
+}
+
+func (im *IgnoreManager) addProject(root string) *project {
+	for _, p := range im.projects {
+		if p.root == root {
+			return p
+		}
+	}
+	p := &project{root: root}
+	im.projects = append(im.projects, p)
+	return p
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// relativeSlash returns path relative to base with forward slashes. Without a
+// base the whole path is used.
+func relativeSlash(base, path string) string {
+	if base != "" {
+		if rel, err := filepath.Rel(base, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(path), "/")
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
 }
 
 // GetDefaultPatterns returns common patterns that should be ignored by default
@@ -0,0 +1,254 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newProject writes the ignore files, keyed by their path relative to a new
+// project root, and returns the root and an ignore manager for it
+func newProject(t *testing.T, files map[string]string) (string, *IgnoreManager) {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	im := NewIgnoreManager()
+	if err := im.LoadProject(root, false); err != nil {
+		t.Fatal(err)
+	}
+	return root, im
+}
+
+// The examples of gitignore(5)
+func TestGitignoreExamples(t *testing.T) {
+	type check struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}
+	tests := []struct {
+		name   string
+		files  map[string]string
+		checks []check
+	}{
+		{
+			name:  "trailing slash only matches directories",
+			files: map[string]string{".ttignore": "build/\n"},
+			checks: []check{
+				{"build", true, true},
+				{"src/build", true, true},
+				{"build", false, false},
+				{"rebuild", true, false},
+				{"build/out.o", false, true},
+			},
+		},
+		{
+			name:  "leading slash anchors to the ignore file",
+			files: map[string]string{".ttignore": "/doc/frotz/\n"},
+			checks: []check{
+				{"doc/frotz", true, true},
+				{"a/doc/frotz", true, false},
+				{"doc/frotz", false, false},
+			},
+		},
+		{
+			name:  "middle slash anchors too",
+			files: map[string]string{".ttignore": "doc/frotz\n"},
+			checks: []check{
+				{"doc/frotz", false, true},
+				{"a/doc/frotz", false, false},
+			},
+		},
+		{
+			name:  "double asterisk matches any number of directories",
+			files: map[string]string{".ttignore": "foo/**/hello.c\n"},
+			checks: []check{
+				{"foo/hello.c", false, true},
+				{"foo/bar/hello.c", false, true},
+				{"foo/bar/baz/hello.c", false, true},
+				{"bar/foo/hello.c", false, false},
+				{"foo/hello.cc", false, false},
+			},
+		},
+		{
+			name:  "a/**/b",
+			files: map[string]string{".ttignore": "a/**/b\n"},
+			checks: []check{
+				{"a/b", false, true},
+				{"a/x/b", false, true},
+				{"a/x/y/b", false, true},
+				{"a/xb", false, false},
+			},
+		},
+		{
+			name:  "leading and trailing double asterisks",
+			files: map[string]string{".ttignore": "**/foo\nabc/**\n"},
+			checks: []check{
+				{"foo", false, true},
+				{"x/y/foo", false, true},
+				{"abc/x", false, true},
+				{"abc/x/y", false, true},
+				{"abc", true, false},
+			},
+		},
+		{
+			name:  "negation re-includes a file",
+			files: map[string]string{".ttignore": "*.html\n!foo.html\n"},
+			checks: []check{
+				{"index.html", false, true},
+				{"foo.html", false, false},
+				{"a/foo.html", false, false},
+			},
+		},
+		{
+			name:  "negation cannot re-include under an excluded parent",
+			files: map[string]string{".ttignore": "dir/\n!dir/file\n"},
+			checks: []check{
+				{"dir", true, true},
+				{"dir/file", false, true},
+			},
+		},
+		{
+			name:  "negation of the parent directory itself",
+			files: map[string]string{".ttignore": "/*\n!/foo\n/foo/*\n!/foo/bar\n"},
+			checks: []check{
+				{"other", false, true},
+				{"foo", true, false},
+				{"foo/baz", false, true},
+				{"foo/bar", true, false},
+				{"foo/bar/x.c", false, false},
+			},
+		},
+		{
+			name: "nested ignore file anchored with a slash",
+			files: map[string]string{
+				"sub/.ttignore": "/gen\n",
+			},
+			checks: []check{
+				{"sub/gen", false, true},
+				{"gen", false, false},
+				{"sub/deeper/gen", false, false},
+				{"other/gen", false, false},
+			},
+		},
+		{
+			name: "nested ignore file takes precedence",
+			files: map[string]string{
+				".ttignore":     "*.gen\n",
+				"sub/.ttignore": "!keep.gen\n",
+			},
+			checks: []check{
+				{"a.gen", false, true},
+				{"keep.gen", false, true},
+				{"sub/keep.gen", false, false},
+				{"sub/x/keep.gen", false, false},
+			},
+		},
+		{
+			name:  "escaped special characters",
+			files: map[string]string{".ttignore": "\\!important\n\\#hash\n"},
+			checks: []check{
+				{"!important", false, true},
+				{"#hash", false, true},
+				{"important", false, false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, im := newProject(t, tt.files)
+			for _, c := range tt.checks {
+				path := filepath.Join(root, filepath.FromSlash(c.path))
+				if got := im.IsIgnored(path, c.isDir); got != c.ignored {
+					t.Errorf("IsIgnored(%q, dir=%v) = %v, want %v", c.path, c.isDir, got, c.ignored)
+				}
+			}
+		})
+	}
+}
+
+func TestPatternString(t *testing.T) {
+	for _, text := range []string{"/doc/frotz/", "doc/frotz", "/build", "!foo.html", "foo/**/hello.c", `\!important`} {
+		p, ok := ParsePattern(text)
+		if !ok {
+			t.Fatalf("ParsePattern(%q) failed", text)
+		}
+		if got := p.String(); got != text {
+			t.Errorf("ParsePattern(%q).String() = %q", text, got)
+		}
+	}
+
+	root, im := newProject(t, map[string]string{".ttignore": "# generated\n/doc/frotz/\n"})
+	match := im.Match(filepath.Join(root, "doc", "frotz"), true)
+	want := filepath.Join(root, IgnoreFile) + ":2: /doc/frotz/"
+	if match == nil || match.String() != want {
+		t.Errorf("Match explained %v, want %s", match, want)
+	}
+
+	im.AddPattern("vendor", true)
+	if match := im.Match(filepath.Join(root, "vendor"), true); match == nil || match.String() != "vendor/" {
+		t.Errorf("Match explained %v, want vendor/", match)
+	}
+}
+
+func TestRepoIgnoreManagerSkipsExcludes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	global := filepath.Join(home, ".config", "git", "ignore")
+	if err := os.MkdirAll(filepath.Dir(global), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(global, []byte("*.secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, GitIgnoreFile), []byte("*.out\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exclude := filepath.Join(root, ".git", "info", "exclude")
+	if err := os.MkdirAll(filepath.Dir(exclude), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(exclude, []byte("*.local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := NewProjectIgnoreManager(root, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepoIgnoreManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		path          string
+		project, repo bool
+	}{
+		{"a.secret", true, false},
+		{"a.local", true, false},
+		{"a.out", true, true},
+		{"a.go", false, false},
+	} {
+		path := filepath.Join(root, tt.path)
+		if got := project.IsIgnored(path, false); got != tt.project {
+			t.Errorf("NewProjectIgnoreManager: IsIgnored(%q) = %v, want %v", tt.path, got, tt.project)
+		}
+		if got := repo.IsIgnored(path, false); got != tt.repo {
+			t.Errorf("NewRepoIgnoreManager: IsIgnored(%q) = %v, want %v", tt.path, got, tt.repo)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package keywords
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity levels used by the default keywords
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Environment variables used to hand the keyword configuration to external parsers
+const (
+	// EnvPattern holds a regular expression whose first group captures the
+	// keyword. It is matched against comment text and only finds keywords at
+	// its start, after comment markers and whitespace.
+	EnvPattern = "TT_KEYWORD_PATTERN"
+	// EnvConfig holds the full keyword configuration as JSON
+	EnvConfig = "TT_KEYWORDS"
+)
+
+// Keyword is a comment tag that marks a TODO, e.g. TODO, FIXME or HACK
+type Keyword struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// Config describes which keywords are tracked and how they are matched
+type Config struct {
+	Keywords      []Keyword `json:"keywords,omitempty"`
+	CaseSensitive *bool     `json:"case_sensitive,omitempty"` // default false
+	WordBoundary  *bool     `json:"word_boundary,omitempty"`  // default true
+}
+
+// DefaultKeywords returns the keywords tracked when nothing is configured
+func DefaultKeywords() []Keyword {
+	return []Keyword{
+		{Name: "TODO", Severity: SeverityMedium},
+		{Name: "FIXME", Severity: SeverityHigh},
+	}
+}
+
+// Default returns the configuration used when nothing is configured
+func Default() Config {
+	return Config{Keywords: DefaultKeywords()}
+}
+
+// IsCaseSensitive reports whether keywords must match exactly
+func (c Config) IsCaseSensitive() bool {
+	return c.CaseSensitive != nil && *c.CaseSensitive
+}
+
+// IsWordBoundary reports whether keywords must stand alone as a word
+func (c Config) IsWordBoundary() bool {
+	return c.WordBoundary == nil || *c.WordBoundary
+}
+
+// Merge layers override on top of c. Keywords are added to the list (replacing
+// the severity of keywords with the same name) and match options that are set
+// in override win.
+func (c Config) Merge(override *Config) Config {
+	if override == nil {
+		return c
+	}
+
+	merged := Config{
+		Keywords:      append([]Keyword{}, c.Keywords...),
+		CaseSensitive: c.CaseSensitive,
+		WordBoundary:  c.WordBoundary,
+	}
+
+	for _, kw := range override.Keywords {
+		replaced := false
+		for i, existing := range merged.Keywords {
+			if strings.EqualFold(existing.Name, kw.Name) {
+				merged.Keywords[i] = kw
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.Keywords = append(merged.Keywords, kw)
+		}
+	}
+
+	if override.CaseSensitive != nil {
+		merged.CaseSensitive = override.CaseSensitive
+	}
+	if override.WordBoundary != nil {
+		merged.WordBoundary = override.WordBoundary
+	}
+	return merged
+}
+
+// commentPrefix matches the comment markers and whitespace a keyword may
+// follow at the start of a comment, e.g. "// ", " * ", "# " or "<!-- "
+const commentPrefix = `^[\s/*#;!<%-]*`
+
+// Matcher finds configured keywords at the start of comment text
+type Matcher struct {
+	config  Config
+	re      *regexp.Regexp
+	anyRe   *regexp.Regexp
+	pattern string
+}
+
+// NewMatcher compiles a matcher for cfg, falling back to the default keywords
+// when cfg has none
+func NewMatcher(cfg Config) (*Matcher, error) {
+	if len(cfg.Keywords) == 0 {
+		cfg.Keywords = DefaultKeywords()
+	}
+
+	names := make([]string, 0, len(cfg.Keywords))
+	for _, kw := range cfg.Keywords {
+		name := strings.TrimSpace(kw.Name)
+		if name == "" {
+			return nil, fmt.Errorf("keyword names cannot be empty")
+		}
+		names = append(names, name)
+	}
+	// Longer keywords first, so TODO! is not matched as TODO
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+
+	alternatives := make([]string, len(names))
+	for i, name := range names {
+		alternatives[i] = keywordPattern(name, cfg.IsWordBoundary())
+	}
+	keyword := "(" + strings.Join(alternatives, "|") + ")"
+	flags := ""
+	if !cfg.IsCaseSensitive() {
+		flags = "(?i)"
+	}
+
+	// Keywords only mark a TODO when the comment starts with them, so prose
+	// that mentions a TODO is not one
+	pattern := flags + commentPrefix + keyword
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyword pattern: %v", err)
+	}
+	anyRe, err := regexp.Compile(flags + keyword)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyword pattern: %v", err)
+	}
+
+	return &Matcher{config: cfg, re: re, anyRe: anyRe, pattern: pattern}, nil
+}
+
+// keywordPattern quotes a keyword for a regular expression. With
+// wordBoundary it must stand alone as a word: \b is added on the sides that
+// are word characters, since it never matches next to @ or ! in @todo or TODO!.
+func keywordPattern(name string, wordBoundary bool) string {
+	pattern := regexp.QuoteMeta(name)
+	if !wordBoundary {
+		return pattern
+	}
+	if isWordChar(name[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordChar(name[len(name)-1]) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// DefaultMatcher returns a matcher for the default keywords
+func DefaultMatcher() *Matcher {
+	m, _ := NewMatcher(Default())
+	return m
+}
+
+// Match returns the keyword comment text starts with, after its comment
+// markers and whitespace
+func (m *Matcher) Match(text string) (Keyword, bool) {
+	loc := m.re.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return Keyword{}, false
+	}
+	return m.lookup(text[loc[2]:loc[3]]), true
+}
+
+// MatchIndex returns the keyword comment text starts with and the byte offset it starts at
+func (m *Matcher) MatchIndex(text string) (Keyword, int, bool) {
+	loc := m.re.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return Keyword{}, -1, false
+	}
+	return m.lookup(text[loc[2]:loc[3]]), loc[2], true
+}
+
+// Contains returns the first keyword found anywhere in text, e.g. in a source
+// line that ends with a comment
+func (m *Matcher) Contains(text string) (Keyword, bool) {
+	loc := m.anyRe.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return Keyword{}, false
+	}
+	return m.lookup(text[loc[2]:loc[3]]), true
+}
+
+// lookup finds the configured keyword for a matched word
+func (m *Matcher) lookup(word string) Keyword {
+	for _, kw := range m.config.Keywords {
+		if kw.Name == word || (!m.config.IsCaseSensitive() && strings.EqualFold(kw.Name, word)) {
+			return kw
+		}
+	}
+	return Keyword{Name: word}
+}
+
+// Pattern returns the matcher's regular expression. It only uses syntax shared
+// by Go and Python so external parsers can compile it as is.
+func (m *Matcher) Pattern() string {
+	return m.pattern
+}
+
+// Config returns the configuration the matcher was built from
+func (m *Matcher) Config() Config {
+	return m.config
+}
+
+// Environ returns the environment variables that pass the keyword
+// configuration to an external parser
+func (m *Matcher) Environ() []string {
+	data, _ := json.Marshal(m.config)
+	return []string{
+		EnvPattern + "=" + m.pattern,
+		EnvConfig + "=" + string(data),
+	}
+}
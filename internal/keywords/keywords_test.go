@@ -0,0 +1,76 @@
+package keywords
+
+import "testing"
+
+func TestMatchAtCommentStart(t *testing.T) {
+	cfg := Default().Merge(&Config{Keywords: []Keyword{{Name: "NOTE"}}})
+	m, err := NewMatcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text    string
+		keyword string
+	}{
+		{"// TODO: fix this", "TODO"},
+		{"//TODO fix this", "TODO"},
+		{"// todo: lower case", "TODO"},
+		{"/* FIXME(alice): block */", "FIXME"},
+		{" * TODO inside a block comment", "TODO"},
+		{"# TODO: hash comment", "TODO"},
+		{"-- TODO: lua or sql", "TODO"},
+		{"<!-- TODO: html -->", "TODO"},
+		{"TODO: markers already stripped", "TODO"},
+		{"NOTE: keep in sync", "NOTE"},
+		{"// loadTodos loads the TODO store", ""},
+		{"// Item is a TODO found by a check", ""},
+		{"// global TODO keywords", ""},
+		{"// a note about the cache", ""},
+		{"// TODOS are not TODO", ""},
+		{"x := 1 // TODO: trailing", ""},
+	}
+	for _, tt := range tests {
+		kw, found := m.Match(tt.text)
+		if found != (tt.keyword != "") || kw.Name != tt.keyword {
+			t.Errorf("Match(%q) = %q, %v; want %q", tt.text, kw.Name, found, tt.keyword)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	m := DefaultMatcher()
+	if kw, ok := m.Contains("x := 1 // FIXME: trailing"); !ok || kw.Name != "FIXME" {
+		t.Errorf("Contains found %q, %v; want FIXME", kw.Name, ok)
+	}
+	if _, ok := m.Contains("x := todos[0]"); ok {
+		t.Errorf("Contains matched a word that only starts with a keyword")
+	}
+}
+
+func TestNonWordKeywords(t *testing.T) {
+	m, err := NewMatcher(Config{Keywords: []Keyword{{Name: "TODO"}, {Name: "@todo"}, {Name: "TODO!"}, {Name: "XXX"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text    string
+		keyword string
+	}{
+		{"// @todo: tag style", "@todo"},
+		{" * @todo write docs", "@todo"},
+		{"// TODO! urgent", "TODO!"},
+		{"// TODO: plain", "TODO"},
+		{"// TODOS", ""},
+		{"// @todos", ""},
+		{"// XXX marks the spot", "XXX"},
+		{"// XXXL size", ""},
+	}
+	for _, tt := range tests {
+		kw, found := m.Match(tt.text)
+		if found != (tt.keyword != "") || kw.Name != tt.keyword {
+			t.Errorf("Match(%q) = %q, %v; want %q", tt.text, kw.Name, found, tt.keyword)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package plugin
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"Ttracker/internal/config"
+)
+
+// InstallOptions controls how a packaged plugin is installed
+type InstallOptions struct {
+	Force      bool // reinstall, downgrade or replace a pinned version
+	Pin        bool // pin the plugin to the installed version
+	SetDefault bool // make the plugin the default for its language
+}
+
+// PluginsDir returns the directory holding installed plugins. Every version
+// of a plugin is kept in <PluginsDir>/<id>/<version>.
+func PluginsDir() string {
+	return filepath.Join(config.HomeDir(), "plugins")
+}
+
+// Install installs the plugin packaged in src, a directory or a .tar.gz
+// archive holding a ttplugin.json manifest, into the plugins directory and
+// registers it. The caller is responsible for saving the plugin list.
+func (pm *PluginManager) Install(src string, opts InstallOptions) (PluginConfig, error) {
+	srcDir := src
+	if isArchive(src) {
+		tmp, err := os.MkdirTemp("", "ttplugin-")
+		if err != nil {
+			return PluginConfig{}, fmt.Errorf("could not create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(tmp)
+
+		if err := extractTarGz(src, tmp); err != nil {
+			return PluginConfig{}, fmt.Errorf("could not extract %s: %v", src, err)
+		}
+		if srcDir, err = findManifestDir(tmp); err != nil {
+			return PluginConfig{}, err
+		}
+	} else if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return PluginConfig{}, fmt.Errorf("%s is not a plugin directory or .tar.gz archive", src)
+	}
+
+	manifest, err := LoadManifest(srcDir)
+	if err != nil {
+		return PluginConfig{}, err
+	}
+
+	// Check the installed version before replacing it
+	existing := -1
+	for i, p := range pm.Plugins {
+		if p.ID == manifest.ID {
+			existing = i
+			break
+		}
+	}
+	if existing >= 0 && !opts.Force {
+		current := pm.Plugins[existing]
+		switch {
+		case current.Pinned && current.Version != manifest.Version:
+			return PluginConfig{}, fmt.Errorf("plugin %s is pinned to version %s, use --force to replace it", current.ID, current.Version)
+		case current.Version == "":
+			return PluginConfig{}, fmt.Errorf("plugin %s was added by hand, use --force to replace it", current.ID)
+		case CompareVersions(manifest.Version, current.Version) == 0:
+			return PluginConfig{}, fmt.Errorf("plugin %s %s is already installed, use --force to reinstall it", current.ID, current.Version)
+		case CompareVersions(manifest.Version, current.Version) < 0:
+			return PluginConfig{}, fmt.Errorf("plugin %s %s is newer than %s, use --force to downgrade", current.ID, current.Version, manifest.Version)
+		}
+	}
+
+	// Copy the package next to its destination and check it before it
+	// replaces an installed version, so a failed install leaves nothing behind
+	versionsDir := filepath.Join(PluginsDir(), manifest.ID)
+	if err := os.MkdirAll(versionsDir, 0755); err != nil {
+		return PluginConfig{}, fmt.Errorf("could not create %s: %v", versionsDir, err)
+	}
+	defer os.Remove(versionsDir) // only removed when no version is left in it
+
+	staging, err := os.MkdirTemp(versionsDir, ".install-")
+	if err != nil {
+		return PluginConfig{}, fmt.Errorf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := copyDir(srcDir, staging); err != nil {
+		return PluginConfig{}, fmt.Errorf("could not copy plugin files: %v", err)
+	}
+	if _, err := configFromManifest(manifest, staging); err != nil {
+		return PluginConfig{}, err
+	}
+
+	destDir := filepath.Join(versionsDir, manifest.Version)
+	if err := os.RemoveAll(destDir); err != nil {
+		return PluginConfig{}, fmt.Errorf("could not replace %s: %v", destDir, err)
+	}
+	if err := os.Rename(staging, destDir); err != nil {
+		return PluginConfig{}, fmt.Errorf("could not install plugin files: %v", err)
+	}
+
+	installed, err := configFromManifest(manifest, destDir)
+	if err != nil {
+		os.RemoveAll(destDir)
+		return PluginConfig{}, err
+	}
+	if absSrc, err := filepath.Abs(src); err == nil {
+		installed.Source = absSrc
+	}
+	installed.Pinned = opts.Pin || (existing >= 0 && pm.Plugins[existing].Pinned && opts.Force)
+
+	if existing >= 0 {
+		pm.Plugins[existing] = installed
+	} else {
+		pm.Plugins = append(pm.Plugins, installed)
+	}
+
+	if pm.Defaults == nil {
+		pm.Defaults = make(map[string]string)
+	}
+	if _, hasDefault := pm.Defaults[installed.Language]; opts.SetDefault || !hasDefault {
+		pm.Defaults[installed.Language] = installed.ID
+	}
+
+	return installed, nil
+}
+
+// InstalledVersions lists the versions of a plugin kept in the plugins directory, oldest first
+func InstalledVersions(id string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(PluginsDir(), id))
+	if err != nil {
+		return nil, fmt.Errorf("no installed versions of plugin %s: %v", id, err)
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		// Skip the temporary directories of installs in progress
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+	return versions, nil
+}
+
+// Pin pins a plugin to one of its installed versions. An empty version pins
+// the version currently in use.
+func (pm *PluginManager) Pin(id, version string) error {
+	for i, p := range pm.Plugins {
+		if p.ID != id {
+			continue
+		}
+
+		if version != "" && version != p.Version {
+			manifest, err := LoadManifest(filepath.Join(PluginsDir(), id, version))
+			if err != nil {
+				return fmt.Errorf("version %s of plugin %s is not installed: %v", version, id, err)
+			}
+			pinned, err := configFromManifest(manifest, filepath.Join(PluginsDir(), id, version))
+			if err != nil {
+				return err
+			}
+			pinned.Source = p.Source
+			pm.Plugins[i] = pinned
+		}
+		pm.Plugins[i].Pinned = true
+		return nil
+	}
+	return fmt.Errorf("cannot find plugin with id: %s", id)
+}
+
+// Unpin allows a plugin to be upgraded again
+func (pm *PluginManager) Unpin(id string) error {
+	for i, p := range pm.Plugins {
+		if p.ID == id {
+			pm.Plugins[i].Pinned = false
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot find plugin with id: %s", id)
+}
+
+// configFromManifest builds the plugin configuration for a manifest installed in dir
+func configFromManifest(m *Manifest, dir string) (PluginConfig, error) {
+	command := filepath.Join(dir, m.Command)
+	info, err := os.Stat(command)
+	if err != nil {
+		if pathErr, ok := err.(*os.PathError); ok {
+			err = pathErr.Err // the path is a temporary one while installing
+		}
+		return PluginConfig{}, fmt.Errorf("plugin command %s not found: %v", m.Command, err)
+	}
+	if info.Mode()&0111 == 0 && !IsModule(command) {
+		if err := os.Chmod(command, info.Mode()|0755); err != nil {
+			return PluginConfig{}, fmt.Errorf("could not make %s executable: %v", m.Command, err)
+		}
+	}
+
+	return PluginConfig{
+		ID:         m.ID,
+		Language:   m.Language,
+		Command:    command,
+		Extensions: m.Extensions,
+		Version:    m.Version,
+		Dir:        dir,
+	}, nil
+}
+
+// ResolveCommand turns a command given as a relative path (./parsers/x.py)
+// into an absolute one so it keeps working when tt runs from another
+// directory. Bare command names looked up in $PATH are left alone.
+func ResolveCommand(command string) string {
+	if filepath.IsAbs(command) || !strings.ContainsRune(command, filepath.Separator) {
+		return command
+	}
+	if abs, err := filepath.Abs(command); err == nil {
+		return abs
+	}
+	return command
+}
+
+func isArchive(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// findManifestDir locates the manifest in an extracted archive, either at its
+// root or inside a single top-level directory
+func findManifestDir(root string) (string, error) {
+	if _, err := os.Stat(filepath.Join(root, ManifestFile)); err == nil {
+		return root, nil
+	}
+
+	entries, err := os.ReadDir(root)
+	if err == nil && len(entries) == 1 && entries[0].IsDir() {
+		dir := filepath.Join(root, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("archive does not contain a %s manifest", ManifestFile)
+}
+
+// extractTarGz unpacks a gzipped tar archive into dest
+func extractTarGz(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Refuse entries that would escape the destination
+		target := filepath.Join(dest, hdr.Name)
+		if rel, err := filepath.Rel(dest, target); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0777)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// copyDir recursively copies src into dest, keeping file modes
+func copyDir(src, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
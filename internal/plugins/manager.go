@@ -1,10 +1,10 @@
 package plugin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"slices"
 )
@@ -14,6 +14,10 @@ type PluginConfig struct {
 	Language   string   `json:"language"`
 	Command    string   `json:"command"`
 	Extensions []string `json:"extensions"`
+	Version    string   `json:"version,omitempty"` // set for plugins installed from a package
+	Pinned     bool     `json:"pinned,omitempty"`  // pinned plugins are not upgraded
+	Source     string   `json:"source,omitempty"`  // the directory or archive the plugin was installed from
+	Dir        string   `json:"dir,omitempty"`     // the directory the plugin is installed in
 }
 
 var PluginConfigsPath = "./internal/plugins/config.json"
@@ -161,10 +165,8 @@ func (pm PluginManager) ValidateParserCommand(parser, testFilePath string) error
 	1.) run the command against the testFile
 	2.) if no error detected -> Sweet!
 	*/
-	cmd := exec.Command(parser, testFilePath)
-
 	// try running command
-	output, err := cmd.CombinedOutput()
+	_, output, err := Run(context.Background(), parser, testFilePath, nil)
 	if err != nil {
 		return fmt.Errorf("parser validation failed: %v (output: %s)", err, string(output))
 	}
@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ManifestFile is the name of the manifest shipped with a packaged parser
+const ManifestFile = "ttplugin.json"
+
+// Manifest describes a packaged parser plugin
+type Manifest struct {
+	ID          string   `json:"id"`
+	Version     string   `json:"version"`
+	Language    string   `json:"language"`
+	Command     string   `json:"command"` // path of the parser, relative to the manifest
+	Extensions  []string `json:"extensions"`
+	Description string   `json:"description,omitempty"`
+}
+
+// LoadManifest reads and validates the manifest in dir
+func LoadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not read plugin manifest: %v", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("could not unmarshal plugin manifest: %v", err)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks that the manifest has every required field
+func (m *Manifest) Validate() error {
+	if m.ID == "" {
+		return fmt.Errorf("manifest: plugin id must be provided")
+	}
+	if strings.ContainsAny(m.ID, `/\`) || m.ID == "." || m.ID == ".." {
+		return fmt.Errorf("manifest: invalid plugin id %q", m.ID)
+	}
+	if m.Version == "" {
+		return fmt.Errorf("manifest: version must be provided")
+	}
+	if strings.ContainsAny(m.Version, `/\`) || m.Version == "." || m.Version == ".." {
+		return fmt.Errorf("manifest: invalid version %q", m.Version)
+	}
+	if m.Language == "" {
+		return fmt.Errorf("manifest: language must be provided")
+	}
+	if m.Command == "" {
+		return fmt.Errorf("manifest: no command provided")
+	}
+	if filepath.IsAbs(m.Command) || strings.HasPrefix(filepath.Clean(m.Command), "..") {
+		return fmt.Errorf("manifest: command must be a path inside the plugin directory")
+	}
+	if len(m.Extensions) < 1 {
+		return fmt.Errorf("manifest: extensions list cannot be empty")
+	}
+	return nil
+}
+
+// CompareVersions compares two dotted version strings such as 1.2.10 and
+// 1.10.0. A leading "v" is ignored and missing parts count as zero. It returns
+// -1, 0 or 1 when a is older than, the same as or newer than b.
+func CompareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var sa, sb string
+		if i < len(pa) {
+			sa = pa[i]
+		}
+		if i < len(pb) {
+			sb = pb[i]
+		}
+
+		na, errA := strconv.Atoi(sa)
+		nb, errB := strconv.Atoi(sb)
+		if sa == "" {
+			na, errA = 0, nil
+		}
+		if sb == "" {
+			nb, errB = 0, nil
+		}
+
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case sa != sb:
+			// Fall back to a plain comparison for pre-release tags
+			if sa < sb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// IsModule reports whether a plugin command is a WebAssembly module, which
+// runs in process instead of as a separate program
+func IsModule(command string) bool {
+	return strings.EqualFold(filepath.Ext(command), ".wasm")
+}
+
+// Run runs a plugin command on a file and returns what it printed on its
+// standard output and error. env adds variables to its environment; modules
+// only see those.
+func Run(ctx context.Context, command, file string, env []string) ([]byte, []byte, error) {
+	if IsModule(command) {
+		return runModule(ctx, command, file, env)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, file)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
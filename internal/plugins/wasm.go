@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"Ttracker/internal/config"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Plugins compiled to WebAssembly are WASI command modules run with wazero.
+// They follow the protocol of external parsers: the file to parse is their
+// only argument and they print one TODO per line. The module only sees the
+// directory of that file, read-only and mounted at /src, and the keyword
+// variables of its environment. It has no network access.
+
+// moduleMemoryPages caps the memory of a module, in 64 KiB pages (256 MiB)
+const moduleMemoryPages = 4096
+
+// module is a compiled plugin module, kept until its file changes
+type module struct {
+	modTime  time.Time
+	size     int64
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+var (
+	modulesMu sync.Mutex
+	modules   = make(map[string]*module)
+
+	// compilationCache keeps compiled modules on disk between runs of tt
+	compilationCache wazero.CompilationCache
+)
+
+// runModule runs the module at path on a file
+func runModule(ctx context.Context, path, file string, env []string) ([]byte, []byte, error) {
+	m, err := loadModule(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	config := wazero.NewModuleConfig().
+		WithName(""). // modules may run in parallel
+		WithArgs(filepath.Base(path), "/src/"+filepath.Base(abs)).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithFSConfig(wazero.NewFSConfig().WithReadOnlyDirMount(filepath.Dir(abs), "/src"))
+	for _, kv := range env {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			config = config.WithEnv(key, value)
+		}
+	}
+
+	mod, err := m.runtime.InstantiateModule(ctx, m.compiled, config)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		} else {
+			err = fmt.Errorf("exit status %d", exitErr.ExitCode())
+		}
+	}
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// loadModule compiles the module at path, or returns the one compiled before
+// when the file did not change since
+func loadModule(path string) (*module, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+
+	if m, ok := modules[path]; ok {
+		if m.modTime.Equal(info.ModTime()) && m.size == info.Size() {
+			return m, nil
+		}
+		m.runtime.Close(context.Background())
+		delete(modules, path)
+	}
+
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if compilationCache == nil {
+		dir := filepath.Join(config.HomeDir(), "cache", "wasm")
+		if compilationCache, err = wazero.NewCompilationCacheWithDir(dir); err != nil {
+			compilationCache = wazero.NewCompilationCache()
+		}
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCompilationCache(compilationCache).
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(moduleMemoryPages))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("could not set up WASI: %v", err)
+	}
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("could not compile %s: %v", filepath.Base(path), err)
+	}
+
+	m := &module{modTime: info.ModTime(), size: info.Size(), runtime: runtime, compiled: compiled}
+	modules[path] = m
+	return m, nil
+}
@@ -0,0 +1,491 @@
+package plugintest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"Ttracker/internal/keywords"
+	plugin "Ttracker/internal/plugins"
+	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
+)
+
+// GoldenSuffix is appended to a fixture's name to get its expected output
+const GoldenSuffix = ".golden.json"
+
+// protocolLine is the shape every line a plugin prints must have
+var protocolLine = regexp.MustCompile(`^\d+(-\d+)?: .*\S.*$`)
+
+// Expected is a TODO a plugin should report for a fixture
+type Expected struct {
+	Line     int    `json:"line"`
+	EndLine  int    `json:"end_line,omitempty"`
+	Function string `json:"function,omitempty"`
+	Keyword  string `json:"keyword,omitempty"`
+	Comment  string `json:"comment"`
+}
+
+// Options controls a conformance run
+type Options struct {
+	FixturesDir string            // directory holding fixtures and golden files
+	Timeout     time.Duration     // maximum time a plugin may take for one file
+	HugeLines   int               // size of the generated huge input, in lines
+	Update      bool              // rewrite golden files from the plugin's output
+	Matcher     *keywords.Matcher // keywords handed to the plugin
+}
+
+// Check is the outcome of a single conformance check
+type Check struct {
+	Name     string
+	Passed   bool
+	Warning  bool // passed, but with something worth looking at
+	Details  []string
+	Duration time.Duration
+}
+
+// Report collects the checks run against a plugin
+type Report struct {
+	Plugin plugin.PluginConfig
+	Checks []Check
+}
+
+// Passed reports whether every check passed
+func (r *Report) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultFixturesDir returns where a plugin's fixtures live when none are
+// given: the testdata directory next to its manifest or command
+func DefaultFixturesDir(p plugin.PluginConfig) string {
+	if p.Dir != "" {
+		return filepath.Join(p.Dir, "testdata")
+	}
+	return filepath.Join(filepath.Dir(p.Command), "testdata")
+}
+
+// Run runs the conformance checks against a plugin
+func Run(p plugin.PluginConfig, opts Options) (*Report, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.HugeLines <= 0 {
+		opts.HugeLines = 200000
+	}
+	if opts.Matcher == nil {
+		opts.Matcher = keywords.DefaultMatcher()
+	}
+	if len(p.Extensions) == 0 {
+		return nil, fmt.Errorf("plugin %s has no extensions", p.ID)
+	}
+
+	report := &Report{Plugin: p}
+
+	fixtures, err := findFixtures(opts.FixturesDir, p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	if len(fixtures) == 0 {
+		report.Checks = append(report.Checks, Check{
+			Name:    "fixtures",
+			Passed:  true,
+			Warning: true,
+			Details: []string{fmt.Sprintf("no fixtures with extensions %v found in %s", p.Extensions, opts.FixturesDir)},
+		})
+	}
+
+	for _, fixture := range fixtures {
+		report.Checks = append(report.Checks, checkFixture(p, fixture, opts))
+	}
+
+	tmp, err := os.MkdirTemp("", "ttplugin-test-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	ext := p.Extensions[0]
+	report.Checks = append(report.Checks,
+		checkEmpty(p, filepath.Join(tmp, "empty"+ext), opts),
+		checkBinary(p, filepath.Join(tmp, "binary"+ext), opts),
+		checkHuge(p, filepath.Join(tmp, "huge"+ext), opts),
+	)
+
+	return report, nil
+}
+
+// findFixtures lists the files in dir the plugin should be able to parse
+func findFixtures(dir string, extensions []string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read fixtures directory: %v", err)
+	}
+
+	var fixtures []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), GoldenSuffix) {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		for _, supported := range extensions {
+			if ext == supported {
+				fixtures = append(fixtures, filepath.Join(dir, entry.Name()))
+				break
+			}
+		}
+	}
+	sort.Strings(fixtures)
+	return fixtures, nil
+}
+
+// runResult is the raw outcome of running a plugin on one file
+type runResult struct {
+	stdout   []byte
+	stderr   []byte
+	err      error
+	timedOut bool
+	duration time.Duration
+}
+
+// runPlugin runs the plugin command on a file the way ExternalParser does, with a timeout
+func runPlugin(p plugin.PluginConfig, file string, opts Options) runResult {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	stdout, stderr, err := plugin.Run(ctx, p.Command, file, opts.Matcher.Environ())
+	return runResult{
+		stdout:   stdout,
+		stderr:   stderr,
+		err:      err,
+		timedOut: ctx.Err() == context.DeadlineExceeded,
+		duration: time.Since(start),
+	}
+}
+
+// checkProtocol returns a problem for every output line that breaks the protocol
+func checkProtocol(output []byte) []string {
+	var problems []string
+	for i, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !protocolLine.MatchString(line) {
+			problems = append(problems, fmt.Sprintf("output line %d does not match \"<line>[-<end>]: <comment>\": %q", i+1, truncate(line, 60)))
+		}
+	}
+	return problems
+}
+
+// checkLines verifies that every TODO points at a line holding a keyword
+func checkLines(todos []store.Todo, lines []string, matcher *keywords.Matcher) []string {
+	var problems []string
+	for _, todo := range todos {
+		end := todo.LineNumber
+		if todo.EndLine != 0 {
+			end = todo.EndLine
+		}
+		switch {
+		case todo.LineNumber < 1 || todo.LineNumber > len(lines):
+			problems = append(problems, fmt.Sprintf("line %d is outside the file (%d lines)", todo.LineNumber, len(lines)))
+		case end < todo.LineNumber || end > len(lines):
+			problems = append(problems, fmt.Sprintf("line range %d-%d is invalid", todo.LineNumber, end))
+		default:
+			if _, ok := matcher.Contains(lines[todo.LineNumber-1]); !ok {
+				problems = append(problems, fmt.Sprintf("line %d does not contain a TODO keyword: %q", todo.LineNumber, truncate(strings.TrimSpace(lines[todo.LineNumber-1]), 60)))
+			}
+		}
+	}
+	return problems
+}
+
+func checkFixture(p plugin.PluginConfig, fixture string, opts Options) Check {
+	check := Check{Name: "fixture " + filepath.Base(fixture), Passed: true}
+	fail := func(format string, args ...interface{}) {
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf(format, args...))
+	}
+
+	content, err := os.ReadFile(fixture)
+	if err != nil {
+		fail("could not read fixture: %v", err)
+		return check
+	}
+	lines := strings.Split(string(content), "\n")
+
+	res := runPlugin(p, fixture, opts)
+	check.Duration = res.duration
+	if res.timedOut {
+		fail("timed out after %v", opts.Timeout)
+		return check
+	}
+	if res.err != nil {
+		fail("plugin failed: %v %s", res.err, truncate(strings.TrimSpace(string(res.stderr)), 200))
+		return check
+	}
+
+	for _, problem := range checkProtocol(res.stdout) {
+		fail("%s", problem)
+	}
+
+	todos := scan.ParsePluginOutput(fixture, res.stdout, opts.Matcher)
+	for _, problem := range checkLines(todos, lines, opts.Matcher) {
+		fail("%s", problem)
+	}
+
+	// Compare function attribution with the built-in structural parser
+	if scan.SupportsScopes(fixture) {
+		scopes := scan.DetectScopes(fixture, content)
+		for _, todo := range todos {
+			builtin := scan.EnclosingFunction(scopes, todo.LineNumber)
+			if todo.Function != "" && builtin != "" && todo.Function != builtin {
+				check.Warning = true
+				check.Details = append(check.Details, fmt.Sprintf("line %d: plugin reports function %q, built-in detection found %q", todo.LineNumber, todo.Function, builtin))
+			}
+		}
+	}
+
+	actual := toExpected(todos)
+	golden := fixture + GoldenSuffix
+
+	if opts.Update {
+		data, err := json.MarshalIndent(actual, "", "  ")
+		if err != nil {
+			fail("could not marshal golden file: %v", err)
+			return check
+		}
+		if err := os.WriteFile(golden, append(data, '\n'), 0644); err != nil {
+			fail("could not write golden file: %v", err)
+			return check
+		}
+		check.Details = append(check.Details, "updated "+filepath.Base(golden))
+		return check
+	}
+
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		check.Warning = true
+		check.Details = append(check.Details, fmt.Sprintf("no golden file %s, run with --update to create it", filepath.Base(golden)))
+		return check
+	}
+	var expected []Expected
+	if err := json.Unmarshal(data, &expected); err != nil {
+		fail("could not unmarshal golden file: %v", err)
+		return check
+	}
+
+	for _, problem := range compare(expected, actual) {
+		fail("%s", problem)
+	}
+	return check
+}
+
+// toExpected converts parsed TODOs to the golden file format
+func toExpected(todos []store.Todo) []Expected {
+	expected := make([]Expected, 0, len(todos))
+	for _, todo := range todos {
+		expected = append(expected, Expected{
+			Line:     todo.LineNumber,
+			EndLine:  todo.EndLine,
+			Function: todo.Function,
+			Keyword:  todo.Keyword,
+			Comment:  normalizeComment(todo.Comment),
+		})
+	}
+	return expected
+}
+
+// compare lists the differences between the expected and actual TODOs
+func compare(expected, actual []Expected) []string {
+	var problems []string
+
+	byLine := make(map[int]Expected)
+	for _, a := range actual {
+		byLine[a.Line] = a
+	}
+	wanted := make(map[int]bool)
+
+	for _, e := range expected {
+		wanted[e.Line] = true
+		a, ok := byLine[e.Line]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("line %d: expected TODO %q was not reported", e.Line, truncate(e.Comment, 60)))
+			continue
+		}
+		if e.EndLine != a.EndLine {
+			problems = append(problems, fmt.Sprintf("line %d: expected end line %d, got %d", e.Line, e.EndLine, a.EndLine))
+		}
+		if e.Function != a.Function {
+			problems = append(problems, fmt.Sprintf("line %d: expected function %q, got %q", e.Line, e.Function, a.Function))
+		}
+		if e.Keyword != "" && !strings.EqualFold(e.Keyword, a.Keyword) {
+			problems = append(problems, fmt.Sprintf("line %d: expected keyword %q, got %q", e.Line, e.Keyword, a.Keyword))
+		}
+		if e.Comment != a.Comment {
+			problems = append(problems, fmt.Sprintf("line %d: expected comment %q, got %q", e.Line, truncate(e.Comment, 60), truncate(a.Comment, 60)))
+		}
+	}
+
+	for _, a := range actual {
+		if !wanted[a.Line] {
+			problems = append(problems, fmt.Sprintf("line %d: unexpected TODO %q", a.Line, truncate(a.Comment, 60)))
+		}
+	}
+	return problems
+}
+
+// normalizeComment strips comment markers and collapses whitespace so golden
+// files don't depend on how a plugin echoes the comment
+func normalizeComment(comment string) string {
+	var parts []string
+	for _, line := range strings.Split(comment, "\n") {
+		if text := scan.StripCommentMarkers(line); text != "" {
+			parts = append(parts, strings.Join(strings.Fields(text), " "))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func checkEmpty(p plugin.PluginConfig, file string, opts Options) Check {
+	check := Check{Name: "empty input", Passed: true}
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("could not create input: %v", err))
+		return check
+	}
+
+	res := runPlugin(p, file, opts)
+	check.Duration = res.duration
+	switch {
+	case res.timedOut:
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("timed out after %v", opts.Timeout))
+	case res.err != nil:
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("plugin failed on an empty file: %v", res.err))
+	case len(bytes.TrimSpace(res.stdout)) > 0:
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("expected no output, got %q", truncate(string(res.stdout), 60)))
+	}
+	return check
+}
+
+func checkBinary(p plugin.PluginConfig, file string, opts Options) Check {
+	check := Check{Name: "binary input", Passed: true}
+
+	// Random bytes with a keyword buried inside, as found in compiled files
+	data := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	copy(data[1024:], []byte("\x00# TODO: not a comment\x00"))
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("could not create input: %v", err))
+		return check
+	}
+
+	res := runPlugin(p, file, opts)
+	check.Duration = res.duration
+	switch {
+	case res.timedOut:
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("timed out after %v", opts.Timeout))
+	case res.err != nil:
+		// Refusing binary input is fine as long as the plugin exits cleanly
+		check.Warning = true
+		check.Details = append(check.Details, fmt.Sprintf("plugin exited with an error: %v", res.err))
+	default:
+		for _, problem := range checkProtocol(res.stdout) {
+			check.Passed = false
+			check.Details = append(check.Details, problem)
+		}
+	}
+	return check
+}
+
+func checkHuge(p plugin.PluginConfig, file string, opts Options) Check {
+	check := Check{Name: fmt.Sprintf("huge input (%d lines)", opts.HugeLines), Passed: true}
+
+	// Filler lines with a single TODO at the very end
+	marker := firstKeyword(opts.Matcher) + ": end of huge file"
+	comment := commentPrefix(p.Extensions[0])
+	var b strings.Builder
+	for i := 1; i < opts.HugeLines; i++ {
+		fmt.Fprintf(&b, "%s filler line %d\n", comment, i)
+	}
+	fmt.Fprintf(&b, "%s %s\n", comment, marker)
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("could not create input: %v", err))
+		return check
+	}
+
+	res := runPlugin(p, file, opts)
+	check.Duration = res.duration
+	if res.timedOut {
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("timed out after %v", opts.Timeout))
+		return check
+	}
+	if res.err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("plugin failed: %v", res.err))
+		return check
+	}
+
+	todos := scan.ParsePluginOutput(file, res.stdout, opts.Matcher)
+	found := false
+	for _, todo := range todos {
+		if todo.LineNumber == opts.HugeLines {
+			found = true
+		}
+	}
+	if !found {
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("expected the TODO on line %d, got %d TODOs", opts.HugeLines, len(todos)))
+	}
+	return check
+}
+
+// firstKeyword returns the first configured keyword, used to build inputs
+func firstKeyword(m *keywords.Matcher) string {
+	if kws := m.Config().Keywords; len(kws) > 0 {
+		return kws[0].Name
+	}
+	return "TODO"
+}
+
+// commentPrefix guesses the line comment marker for an extension
+func commentPrefix(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".py", ".rb", ".sh", ".bash", ".zsh", ".pl", ".r", ".yaml", ".yml", ".toml":
+		return "#"
+	case ".lua", ".sql", ".hs":
+		return "--"
+	default:
+		return "//"
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
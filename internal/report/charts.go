@@ -0,0 +1,227 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"Ttracker/internal/events"
+	"Ttracker/internal/store"
+)
+
+// Longest history shown by trend charts
+const maxTrendDays = 180
+
+// bar is one bar of a bar chart
+type bar struct {
+	Label string
+	Value int
+	Color string
+}
+
+// severityColors are the colors of the severities in charts and badges
+var severityColors = map[string]string{
+	"critical": "#991b1b",
+	"high":     "#dc2626",
+	"medium":   "#d97706",
+	"low":      "#16a34a",
+}
+
+const defaultColor = "#2563eb"
+
+// severityBars counts TODOs by severity, the most severe first
+func severityBars(todos []store.Todo) []bar {
+	bars := countBars(todos, severityOf)
+	sort.SliceStable(bars, func(i, j int) bool { return rank(bars[i].Label) < rank(bars[j].Label) })
+	for i := range bars {
+		bars[i].Color = severityColors[bars[i].Label]
+	}
+	return bars
+}
+
+// countBars counts TODOs by the label key returns, the largest count first
+func countBars(todos []store.Todo, key func(store.Todo) string) []bar {
+	counts := make(map[string]int)
+	for _, todo := range todos {
+		counts[key(todo)]++
+	}
+	bars := make([]bar, 0, len(counts))
+	for label, count := range counts {
+		bars = append(bars, bar{Label: label, Value: count})
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Value != bars[j].Value {
+			return bars[i].Value > bars[j].Value
+		}
+		return bars[i].Label < bars[j].Label
+	})
+	return bars
+}
+
+// topBars keeps the n largest bars
+func topBars(bars []bar, n int) []bar {
+	if len(bars) > n {
+		return bars[:n]
+	}
+	return bars
+}
+
+// barChart draws a horizontal bar chart as inline SVG
+func barChart(title string, bars []bar) template.HTML {
+	const (
+		width      = 380
+		labelWidth = 130
+		barHeight  = 18
+		gap        = 6
+		top        = 28
+	)
+	max := 1
+	for _, b := range bars {
+		if b.Value > max {
+			max = b.Value
+		}
+	}
+	height := top + len(bars)*(barHeight+gap) + 4
+	if len(bars) == 0 {
+		height = top + 24
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg class="chart" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="%s">`,
+		width, height, width, height, html.EscapeString(title))
+	fmt.Fprintf(&svg, `<text x="0" y="16" class="chart-title">%s</text>`, html.EscapeString(title))
+	if len(bars) == 0 {
+		fmt.Fprintf(&svg, `<text x="0" y="%d" class="muted">No TODOs</text>`, top+14)
+	}
+	for i, b := range bars {
+		y := top + i*(barHeight+gap)
+		w := (width - labelWidth - 50) * b.Value / max
+		if w < 2 {
+			w = 2
+		}
+		color := b.Color
+		if color == "" {
+			color = defaultColor
+		}
+		label := b.Label
+		if len([]rune(label)) > 18 {
+			label = string([]rune(label)[:17]) + "…"
+		}
+		fmt.Fprintf(&svg, `<g><title>%s: %d</title>`, html.EscapeString(b.Label), b.Value)
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="end">%s</text>`, labelWidth-8, y+13, html.EscapeString(label))
+		fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="%d" height="%d" rx="3" fill="%s"/>`, labelWidth, y, w, barHeight, color)
+		fmt.Fprintf(&svg, `<text x="%d" y="%d">%d</text></g>`, labelWidth+w+6, y+13, b.Value)
+	}
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
+
+// point is the number of TODOs at the end of a day
+type point struct {
+	Day   time.Time
+	Count int
+}
+
+// trend rebuilds the daily number of TODOs of the projects include accepts
+// from the TODO events of the daemon's log, working back from the current
+// count. Days before a count would turn negative are left out, the log
+// misses changes made then. It returns nil when the log holds no TODO events
+// for them.
+func trend(current int, history []events.Event, include func(string) bool, now time.Time) []point {
+	var changes []events.Event
+	for _, event := range history {
+		if (event.Type == events.TodoAdded || event.Type == events.TodoRemoved) && include(event.Project) {
+			changes = append(changes, event)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Time.Before(changes[j].Time) })
+
+	today := day(now)
+	first := day(changes[0].Time)
+	if limit := today.AddDate(0, 0, -(maxTrendDays - 1)); first.Before(limit) {
+		first = limit
+	}
+
+	// Walk back from today, undoing the changes made after each day
+	var points []point
+	count := current
+	i := len(changes) - 1
+	for d := today; !d.Before(first); d = d.AddDate(0, 0, -1) {
+		end := d.AddDate(0, 0, 1)
+		for ; i >= 0 && !changes[i].Time.Before(end); i-- {
+			if changes[i].Type == events.TodoAdded {
+				count--
+			} else {
+				count++
+			}
+		}
+		if count < 0 {
+			break
+		}
+		points = append(points, point{Day: d, Count: count})
+	}
+	for l, r := 0, len(points)-1; l < r; l, r = l+1, r-1 {
+		points[l], points[r] = points[r], points[l]
+	}
+	return points
+}
+
+// day returns the start of the day of t, in local time
+func day(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// trendChart draws the number of TODOs over time as an inline SVG line
+// chart, nothing when there are fewer than two days of history
+func trendChart(points []point) template.HTML {
+	if len(points) < 2 {
+		return ""
+	}
+	const (
+		width  = 760
+		height = 180
+		left   = 40
+		right  = 10
+		top    = 28
+		bottom = 22
+	)
+	max := 1
+	for _, p := range points {
+		if p.Count > max {
+			max = p.Count
+		}
+	}
+	plotW, plotH := width-left-right, height-top-bottom
+	x := func(i int) int { return left + i*plotW/(len(points)-1) }
+	y := func(count int) int { return top + plotH - count*plotH/max }
+
+	var coords []string
+	for i, p := range points {
+		coords = append(coords, fmt.Sprintf("%d,%d", x(i), y(p.Count)))
+	}
+	last := points[len(points)-1]
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg class="chart" viewBox="0 0 %d %d" width="100%%" role="img" aria-label="TODOs over time">`, width, height)
+	fmt.Fprintf(&svg, `<text x="0" y="16" class="chart-title">TODOs over time</text>`)
+	fmt.Fprintf(&svg, `<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, left, top+plotH, width-right, top+plotH)
+	fmt.Fprintf(&svg, `<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, left, top, left, top+plotH)
+	fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="end">%d</text>`, left-6, top+10, max)
+	fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="end">0</text>`, left-6, top+plotH)
+	fmt.Fprintf(&svg, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(coords, " "), defaultColor)
+	for i, p := range points {
+		fmt.Fprintf(&svg, `<circle cx="%d" cy="%d" r="2.5" fill="%s"><title>%s: %d</title></circle>`,
+			x(i), y(p.Count), defaultColor, p.Day.Format("2006-01-02"), p.Count)
+	}
+	fmt.Fprintf(&svg, `<text x="%d" y="%d">%s</text>`, left, height-4, points[0].Day.Format("Jan 2"))
+	fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="end">%s · %d</text>`, width-right, height-4, last.Day.Format("Jan 2"), last.Count)
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
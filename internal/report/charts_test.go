@@ -0,0 +1,66 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"Ttracker/internal/events"
+)
+
+func TestTrend(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	at := func(daysAgo int, typ string) events.Event {
+		return events.Event{Type: typ, Project: "p", Time: now.AddDate(0, 0, -daysAgo)}
+	}
+	added, removed := events.TodoAdded, events.TodoRemoved
+
+	tests := []struct {
+		name    string
+		current int
+		history []events.Event
+		want    string // counts from the first day to today
+	}{
+		{"no history", 3, nil, ""},
+		{"other projects only", 3, []events.Event{{Type: added, Project: "q", Time: now}}, ""},
+		{
+			name:    "added and removed",
+			current: 3,
+			history: []events.Event{at(3, added), at(3, added), at(2, removed), at(0, added)},
+			want:    "3 2 2 3",
+		},
+		{
+			name:    "scans and project events are not counted",
+			current: 1,
+			history: []events.Event{at(1, added), at(1, events.ScanCompleted), at(0, events.ProjectAdded)},
+			want:    "1 1",
+		},
+		{
+			// The log missing removals would make the first days negative
+			name:    "days that cannot be rebuilt are left out",
+			current: 1,
+			history: []events.Event{at(3, added), at(1, added), at(1, added)},
+			want:    "1 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := trend(tt.current, tt.history, func(name string) bool { return name == "p" }, now)
+			counts := make([]string, len(points))
+			for i, p := range points {
+				counts[i] = fmt.Sprint(p.Count)
+				if i > 0 && !p.Day.Equal(points[i-1].Day.AddDate(0, 0, 1)) {
+					t.Errorf("point %d is on %v after %v", i, p.Day, points[i-1].Day)
+				}
+			}
+			if got := strings.Join(counts, " "); got != tt.want {
+				t.Errorf("trend = %q, want %q", got, tt.want)
+			}
+			if len(points) > 0 && !points[len(points)-1].Day.Equal(day(now)) {
+				t.Errorf("trend ends on %v, want today", points[len(points)-1].Day)
+			}
+		})
+	}
+}
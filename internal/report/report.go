@@ -0,0 +1,435 @@
+// Package report builds a static HTML site from the TODO store, to browse
+// TODOs without tt, e.g. as a CI artifact.
+package report
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"Ttracker/internal/events"
+	"Ttracker/internal/keywords"
+	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
+)
+
+// Project is a project included in the report
+type Project struct {
+	Name  string
+	Root  string // the project directory, paths are shown relative to it
+	Todos []store.Todo
+}
+
+// Options configures a report
+type Options struct {
+	ContextLines int            // lines of source shown around each TODO
+	History      []events.Event // the daemon's event log, for trends
+	Now          time.Time
+}
+
+// Build writes index.html and one page per project to dir and returns the
+// path of the index
+func Build(dir string, projects []Project, opts Options) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("could not create %s: %v", dir, err)
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+	slugs := projectSlugs(projects)
+
+	index := indexPage{Generated: opts.Now.Format("2006-01-02 15:04 MST")}
+	var all []store.Todo
+	for i, project := range projects {
+		page := newProjectPage(project, slugs[i], opts)
+		if err := writePage(filepath.Join(dir, page.File), projectTemplate, page); err != nil {
+			return "", err
+		}
+
+		index.Projects = append(index.Projects, page.Summary)
+		index.Total += page.Summary.Total
+		index.Files += page.Summary.Files
+		all = append(all, project.Todos...)
+	}
+
+	included := make(map[string]bool)
+	var perProject []bar
+	for _, summary := range index.Projects {
+		included[summary.Name] = true
+		perProject = append(perProject, bar{Label: summary.Name, Value: summary.Total})
+	}
+	index.Charts = []template.HTML{
+		barChart("TODOs per project", perProject),
+		barChart("By severity", severityBars(all)),
+		barChart("By keyword", countBars(all, func(t store.Todo) string { return keywordOf(t) })),
+	}
+	index.Trend = trendChart(trend(index.Total, opts.History, func(name string) bool { return included[name] }, opts.Now))
+
+	path := filepath.Join(dir, "index.html")
+	if err := writePage(path, indexTemplate, index); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// writePage renders a template to path
+func writePage(path string, tmpl *template.Template, data interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not write %s: %v", path, err)
+	}
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return fmt.Errorf("could not render %s: %v", path, err)
+	}
+	return f.Close()
+}
+
+// indexPage is the data of index.html
+type indexPage struct {
+	Generated string
+	Projects  []projectSummary
+	Total     int
+	Files     int
+	Charts    []template.HTML
+	Trend     template.HTML
+}
+
+// projectSummary is a project's row on the index
+type projectSummary struct {
+	Name       string
+	File       string
+	Root       string
+	Total      int
+	Files      int
+	Severities map[string]int
+}
+
+// projectPage is the data of a project's page
+type projectPage struct {
+	Generated  string
+	Summary    projectSummary
+	File       string
+	Keywords   []string
+	Severities []string
+	Charts     []template.HTML
+	Trend      template.HTML
+	Tree       *dirNode
+	Rows       []row
+	Sources    []fileSection
+	Stale      int
+}
+
+// row is a TODO in the table of a project
+type row struct {
+	ID       string
+	FileID   string
+	Path     string
+	Lines    string
+	Keyword  string
+	Severity string
+	Scope    string
+	Comment  string
+}
+
+// fileSection holds the excerpts of the TODOs of one file
+type fileSection struct {
+	ID       string
+	Path     string
+	Excerpts []excerpt
+}
+
+// excerpt is the source around one TODO
+type excerpt struct {
+	ID      string
+	Lines   string
+	Keyword string
+	Comment string
+	Source  []sourceLine
+	Stale   bool
+	Error   string
+}
+
+// sourceLine is a line of an excerpt, Marked for the TODO's own lines
+type sourceLine struct {
+	Number int
+	Text   string
+	Marked bool
+}
+
+// dirNode is a directory of the tree on a project page
+type dirNode struct {
+	Name  string
+	Count int
+	Dirs  []*dirNode
+	Files []fileLink
+}
+
+// fileLink is a file of the tree, linking to its excerpts
+type fileLink struct {
+	Name  string
+	ID    string
+	Count int
+}
+
+func newProjectPage(project Project, slug string, opts Options) projectPage {
+	todos := append([]store.Todo(nil), project.Todos...)
+	sort.Slice(todos, func(i, j int) bool {
+		if todos[i].FilePath != todos[j].FilePath {
+			return todos[i].FilePath < todos[j].FilePath
+		}
+		return todos[i].LineNumber < todos[j].LineNumber
+	})
+
+	page := projectPage{
+		Generated: opts.Now.Format("2006-01-02 15:04 MST"),
+		File:      slug + ".html",
+		Tree:      &dirNode{Name: "/"},
+	}
+	page.Summary = projectSummary{
+		Name:       project.Name,
+		File:       page.File,
+		Root:       project.Root,
+		Total:      len(todos),
+		Severities: make(map[string]int),
+	}
+
+	keywordSet := make(map[string]bool)
+	severitySet := make(map[string]bool)
+	var section *fileSection
+	for _, todo := range todos {
+		rel := relPath(todo.FilePath, project.Root)
+		fileID := "f-" + shortHash(todo.FilePath)
+		if section == nil || section.Path != rel {
+			page.Sources = append(page.Sources, fileSection{ID: fileID, Path: rel})
+			section = &page.Sources[len(page.Sources)-1]
+			page.Tree.addFile(rel, fileID)
+		}
+		page.Tree.count(rel)
+
+		keyword, severity := keywordOf(todo), severityOf(todo)
+		keywordSet[keyword] = true
+		severitySet[severity] = true
+		page.Summary.Severities[severity]++
+
+		id := fmt.Sprintf("t-%s-%d", todo.Fingerprint(), todo.LineNumber)
+		comment := summary(todo)
+		page.Rows = append(page.Rows, row{
+			ID:       id,
+			FileID:   fileID,
+			Path:     rel,
+			Lines:    lineRange(todo),
+			Keyword:  keyword,
+			Severity: severity,
+			Scope:    scopeOf(todo),
+			Comment:  comment,
+		})
+
+		ex := excerpt{ID: id, Lines: lineRange(todo), Keyword: keyword, Comment: comment}
+		snippet, err := scan.LoadSnippet(todo, opts.ContextLines)
+		if err != nil {
+			ex.Error = fmt.Sprintf("Source unavailable: %v", err)
+		} else {
+			ex.Stale = snippet.Stale
+			if snippet.Stale {
+				page.Stale++
+			}
+			height := 1
+			if todo.EndLine > todo.LineNumber {
+				height = todo.EndLine - todo.LineNumber + 1
+			}
+			for i, text := range snippet.Lines {
+				n := snippet.StartLine + i
+				ex.Source = append(ex.Source, sourceLine{
+					Number: n,
+					Text:   strings.ReplaceAll(text, "\t", "    "),
+					Marked: n >= snippet.Line && n < snippet.Line+height,
+				})
+			}
+		}
+		section.Excerpts = append(section.Excerpts, ex)
+	}
+	page.Summary.Files = len(page.Sources)
+	page.Tree.sort()
+
+	page.Keywords = sortedKeys(keywordSet)
+	page.Severities = sortedSeverities(severitySet)
+	page.Charts = []template.HTML{
+		barChart("By severity", severityBars(todos)),
+		barChart("By keyword", countBars(todos, keywordOf)),
+		barChart("Top directories", topBars(countBars(todos, func(t store.Todo) string {
+			return filepath.Dir(relPath(t.FilePath, project.Root)) + "/"
+		}), 10)),
+	}
+	page.Trend = trendChart(trend(len(todos), opts.History, func(name string) bool { return name == project.Name }, opts.Now))
+	return page
+}
+
+// addFile adds a file to the tree, creating its directories
+func (d *dirNode) addFile(rel, id string) {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	node := d
+	for _, part := range parts[:len(parts)-1] {
+		node = node.dir(part)
+	}
+	node.Files = append(node.Files, fileLink{Name: parts[len(parts)-1], ID: id})
+}
+
+// count adds a TODO of the file at rel to the counts of the tree
+func (d *dirNode) count(rel string) {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	node := d
+	node.Count++
+	for _, part := range parts[:len(parts)-1] {
+		node = node.dir(part)
+		node.Count++
+	}
+	name := parts[len(parts)-1]
+	for i := range node.Files {
+		if node.Files[i].Name == name {
+			node.Files[i].Count++
+		}
+	}
+}
+
+// dir returns the subdirectory name, creating it when needed
+func (d *dirNode) dir(name string) *dirNode {
+	for _, sub := range d.Dirs {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	sub := &dirNode{Name: name}
+	d.Dirs = append(d.Dirs, sub)
+	return sub
+}
+
+func (d *dirNode) sort() {
+	sort.Slice(d.Dirs, func(i, j int) bool { return d.Dirs[i].Name < d.Dirs[j].Name })
+	sort.Slice(d.Files, func(i, j int) bool { return d.Files[i].Name < d.Files[j].Name })
+	for _, sub := range d.Dirs {
+		sub.sort()
+	}
+}
+
+// projectSlugs returns a distinct file name for each project
+func projectSlugs(projects []Project) []string {
+	used := make(map[string]bool)
+	slugs := make([]string, len(projects))
+	for i, project := range projects {
+		var b strings.Builder
+		dash := false
+		for _, r := range strings.ToLower(project.Name) {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				b.WriteRune(r)
+				dash = false
+			} else if !dash && b.Len() > 0 {
+				b.WriteByte('-')
+				dash = true
+			}
+		}
+		base := "project-" + strings.TrimSuffix(b.String(), "-")
+		slug := base
+		for n := 2; used[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[slug] = true
+		slugs[i] = slug
+	}
+	return slugs
+}
+
+// relPath returns path relative to the project root when it is inside it
+func relPath(path, root string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+func shortHash(text string) string {
+	sum := sha1.Sum([]byte(text))
+	return hex.EncodeToString(sum[:6])
+}
+
+// summary joins the lines of a TODO's comment into one line
+func summary(todo store.Todo) string {
+	lines := strings.Split(strings.TrimSpace(todo.Comment), "\n")
+	for i := range lines {
+		if i > 0 {
+			lines[i] = scan.StripCommentMarkers(lines[i])
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+}
+
+func lineRange(todo store.Todo) string {
+	if todo.EndLine > todo.LineNumber {
+		return fmt.Sprintf("%d-%d", todo.LineNumber, todo.EndLine)
+	}
+	return fmt.Sprint(todo.LineNumber)
+}
+
+func scopeOf(todo store.Todo) string {
+	if todo.Function != "" {
+		return todo.Function
+	}
+	if todo.Scope != nil {
+		return todo.Scope.Kind + " " + todo.Scope.Path
+	}
+	return ""
+}
+
+func keywordOf(todo store.Todo) string {
+	if todo.Keyword == "" {
+		return "TODO"
+	}
+	return strings.ToUpper(todo.Keyword)
+}
+
+func severityOf(todo store.Todo) string {
+	if todo.Severity == "" {
+		return keywords.SeverityMedium
+	}
+	return todo.Severity
+}
+
+// severityOrder ranks severities from the most to the least severe
+var severityOrder = map[string]int{
+	keywords.SeverityCritical: 0,
+	keywords.SeverityHigh:     1,
+	keywords.SeverityMedium:   2,
+	keywords.SeverityLow:      3,
+}
+
+func sortedSeverities(set map[string]bool) []string {
+	list := sortedKeys(set)
+	sort.SliceStable(list, func(i, j int) bool { return rank(list[i]) < rank(list[j]) })
+	return list
+}
+
+func rank(severity string) int {
+	if r, ok := severityOrder[severity]; ok {
+		return r
+	}
+	return len(severityOrder)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for key := range set {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
+}
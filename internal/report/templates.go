@@ -0,0 +1,205 @@
+package report
+
+import "html/template"
+
+// Pages are self-contained: styles and scripts are inlined and nothing is
+// loaded from elsewhere, so the report can be opened from a CI artifact.
+
+const layout = `{{define "head"}}<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body { font: 14px/1.45 -apple-system, "Segoe UI", Roboto, sans-serif; color: #1f2937; margin: 0; background: #f9fafb; }
+header { background: #111827; color: #f9fafb; padding: 14px 28px; }
+header a { color: #93c5fd; }
+header h1 { margin: 4px 0; font-size: 22px; }
+main { padding: 20px 28px; max-width: 1400px; }
+h2 { font-size: 17px; margin: 28px 0 10px; }
+a { color: #1d4ed8; text-decoration: none; }
+a:hover { text-decoration: underline; }
+.muted, .count { color: #6b7280; }
+.count { font-size: 12px; }
+.cards { display: flex; gap: 14px; flex-wrap: wrap; }
+.card { background: #fff; border: 1px solid #e5e7eb; border-radius: 8px; padding: 12px 18px; min-width: 120px; }
+.card b { display: block; font-size: 24px; }
+.charts { display: flex; gap: 18px; flex-wrap: wrap; align-items: flex-start; }
+.chart { background: #fff; border: 1px solid #e5e7eb; border-radius: 8px; padding: 10px; font-size: 12px; fill: #374151; }
+.chart .chart-title { font-weight: 600; font-size: 13px; }
+.chart .axis { stroke: #d1d5db; }
+table { border-collapse: collapse; width: 100%; background: #fff; border: 1px solid #e5e7eb; }
+th, td { text-align: left; padding: 6px 10px; border-bottom: 1px solid #f3f4f6; vertical-align: top; }
+th { background: #f3f4f6; cursor: pointer; user-select: none; white-space: nowrap; }
+td:first-child { white-space: nowrap; font-family: ui-monospace, monospace; font-size: 13px; }
+.filters { display: flex; gap: 10px; margin-bottom: 10px; align-items: center; flex-wrap: wrap; }
+.filters input { flex: 1; min-width: 220px; padding: 6px 8px; }
+.filters select { padding: 6px; }
+.kw { font-family: ui-monospace, monospace; font-weight: 600; }
+.sev { border-radius: 4px; padding: 1px 6px; font-size: 12px; color: #fff; background: #2563eb; }
+.sev-critical { background: #991b1b; } .sev-high { background: #dc2626; } .sev-medium { background: #d97706; } .sev-low { background: #16a34a; }
+.stale { border-radius: 4px; padding: 1px 6px; font-size: 12px; background: #fef3c7; color: #92400e; }
+.tree ul { list-style: none; padding-left: 18px; margin: 2px 0; }
+.tree > ul { padding-left: 0; }
+.tree summary { cursor: pointer; }
+.file { background: #fff; border: 1px solid #e5e7eb; border-radius: 8px; padding: 4px 16px 12px; margin-bottom: 14px; }
+.file h3 { font-size: 14px; font-family: ui-monospace, monospace; }
+.excerpt { margin: 10px 0; }
+.excerpt:target { outline: 2px solid #93c5fd; border-radius: 4px; }
+.excerpt pre { margin: 4px 0; background: #f9fafb; border: 1px solid #f3f4f6; border-radius: 4px; padding: 6px 0; overflow-x: auto; font-size: 12.5px; }
+.line { display: block; padding: 0 10px; white-space: pre; }
+.line.marked { background: #fef9c3; }
+.num { display: inline-block; min-width: 42px; color: #9ca3af; text-align: right; margin-right: 12px; }
+</style>{{end}}
+
+{{define "sortable"}}<script>
+(function () {
+  document.querySelectorAll("table.sortable").forEach(function (table) {
+    var body = table.tBodies[0];
+    table.querySelectorAll("th").forEach(function (th, column) {
+      var ascending = true;
+      th.addEventListener("click", function () {
+        var rows = Array.prototype.slice.call(body.rows);
+        rows.sort(function (a, b) {
+          var x = a.cells[column].textContent, y = b.cells[column].textContent;
+          var order = x.localeCompare(y, undefined, { numeric: true });
+          return ascending ? order : -order;
+        });
+        ascending = !ascending;
+        rows.forEach(function (row) { body.appendChild(row); });
+      });
+    });
+  });
+})();
+</script>{{end}}`
+
+const indexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<title>TODO report</title>
+{{template "head"}}
+</head>
+<body>
+<header>
+<h1>TODO report</h1>
+<div>Generated {{.Generated}} by tt</div>
+</header>
+<main>
+<div class="cards">
+<div class="card"><b>{{.Total}}</b>TODOs</div>
+<div class="card"><b>{{len .Projects}}</b>projects</div>
+<div class="card"><b>{{.Files}}</b>files</div>
+</div>
+
+<h2>Projects</h2>
+<table class="sortable">
+<thead><tr><th>Project</th><th>TODOs</th><th>Files</th><th>Critical</th><th>High</th><th>Medium</th><th>Low</th><th>Path</th></tr></thead>
+<tbody>
+{{range .Projects}}<tr>
+<td><a href="{{.File}}">{{.Name}}</a></td><td>{{.Total}}</td><td>{{.Files}}</td>
+<td>{{index .Severities "critical"}}</td><td>{{index .Severities "high"}}</td><td>{{index .Severities "medium"}}</td><td>{{index .Severities "low"}}</td>
+<td class="muted">{{.Root}}</td>
+</tr>
+{{else}}<tr><td colspan="8" class="muted">No projects</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Statistics</h2>
+<div class="charts">{{range .Charts}}{{.}}{{end}}</div>
+{{if .Trend}}<h2>History</h2>
+{{.Trend}}{{end}}
+</main>
+{{template "sortable"}}
+</body>
+</html>
+`
+
+const projectHTML = `{{define "dir"}}<li><details open><summary>{{.Name}}/ <span class="count">{{.Count}}</span></summary>
+<ul>{{range .Dirs}}{{template "dir" .}}{{end}}{{range .Files}}<li><a href="#{{.ID}}">{{.Name}}</a> <span class="count">{{.Count}}</span></li>{{end}}</ul>
+</details></li>{{end}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<title>{{.Summary.Name}} · TODO report</title>
+{{template "head"}}
+</head>
+<body>
+<header>
+<a href="index.html">← All projects</a>
+<h1>{{.Summary.Name}}</h1>
+<div>{{.Summary.Root}} · generated {{.Generated}} by tt</div>
+</header>
+<main>
+<div class="cards">
+<div class="card"><b>{{.Summary.Total}}</b>TODOs</div>
+<div class="card"><b>{{.Summary.Files}}</b>files</div>
+{{if .Stale}}<div class="card"><b>{{.Stale}}</b>in files changed since the last scan</div>{{end}}
+</div>
+
+<h2>Statistics</h2>
+<div class="charts">{{range .Charts}}{{.}}{{end}}</div>
+{{if .Trend}}<h2>History</h2>
+{{.Trend}}{{end}}
+
+<h2>Directories</h2>
+<div class="tree"><ul>{{range .Tree.Dirs}}{{template "dir" .}}{{end}}{{range .Tree.Files}}<li><a href="#{{.ID}}">{{.Name}}</a> <span class="count">{{.Count}}</span></li>{{end}}</ul></div>
+
+<h2>TODOs</h2>
+<div class="filters">
+<input id="search" type="search" placeholder="Search files, functions and comments" autofocus>
+<select id="keyword"><option value="">All keywords</option>{{range .Keywords}}<option>{{.}}</option>{{end}}</select>
+<select id="severity"><option value="">All severities</option>{{range .Severities}}<option>{{.}}</option>{{end}}</select>
+<span id="shown" class="muted"></span>
+</div>
+<table id="todos" class="sortable">
+<thead><tr><th>Location</th><th>Keyword</th><th>Severity</th><th>Scope</th><th>Comment</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr data-keyword="{{.Keyword}}" data-severity="{{.Severity}}">
+<td><a href="#{{.ID}}">{{.Path}}:{{.Lines}}</a></td><td class="kw">{{.Keyword}}</td><td><span class="sev sev-{{.Severity}}">{{.Severity}}</span></td><td>{{.Scope}}</td><td>{{.Comment}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+
+<h2>Sources</h2>
+{{range .Sources}}<section class="file" id="{{.ID}}">
+<h3>{{.Path}}</h3>
+{{range .Excerpts}}<div class="excerpt" id="{{.ID}}">
+<div><a href="#{{.ID}}">{{.Lines}}</a> <span class="kw">{{.Keyword}}</span> {{.Comment}}{{if .Stale}} <span class="stale">file changed since the scan</span>{{end}}</div>
+{{if .Error}}<p class="muted">{{.Error}}</p>{{else}}<pre>{{range .Source}}<span class="line{{if .Marked}} marked{{end}}"><span class="num">{{.Number}}</span>{{.Text}}</span>{{end}}</pre>{{end}}
+</div>
+{{end}}</section>
+{{end}}
+</main>
+<script>
+(function () {
+  var search = document.getElementById("search");
+  var keyword = document.getElementById("keyword");
+  var severity = document.getElementById("severity");
+  var shown = document.getElementById("shown");
+  var rows = Array.prototype.slice.call(document.querySelectorAll("#todos tbody tr"));
+
+  function apply() {
+    var terms = search.value.toLowerCase().split(" ").filter(function (term) { return term !== ""; });
+    var count = 0;
+    rows.forEach(function (row) {
+      var text = row.textContent.toLowerCase();
+      var match = (keyword.value === "" || row.dataset.keyword === keyword.value) &&
+        (severity.value === "" || row.dataset.severity === severity.value) &&
+        terms.every(function (term) { return text.indexOf(term) >= 0; });
+      row.hidden = !match;
+      if (match) {
+        count++;
+      }
+    });
+    shown.textContent = count + " of " + rows.length + " TODOs";
+  }
+
+  [search, keyword, severity].forEach(function (input) { input.addEventListener("input", apply); });
+  apply();
+})();
+</script>
+{{template "sortable"}}
+</body>
+</html>
+`
+
+var (
+	indexTemplate   = template.Must(template.Must(template.New("layout").Parse(layout)).New("index").Parse(indexHTML))
+	projectTemplate = template.Must(template.Must(template.New("layout").Parse(layout)).New("project").Parse(projectHTML))
+)
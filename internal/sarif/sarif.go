@@ -0,0 +1,332 @@
+// Package sarif writes TODOs and check violations as SARIF 2.1.0, the
+// format code scanning services and IDE viewers read.
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"Ttracker/internal/check"
+	"Ttracker/internal/keywords"
+	"Ttracker/internal/scan"
+)
+
+const (
+	schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	version = "2.1.0"
+
+	// rootBase names the directory result paths are relative to
+	rootBase = "SRCROOT"
+
+	// fingerprintKey holds TODO fingerprints in partialFingerprints
+	fingerprintKey = "ttFingerprint/v1"
+)
+
+// Log is a SARIF log file
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []*Run `json:"runs"`
+}
+
+// Run is the output of one run of tt over a directory
+type Run struct {
+	Tool               Tool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]ArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	ColumnKind         string                      `json:"columnKind"`
+	Results            []Result                    `json:"results"`
+
+	ruleIndex map[string]int
+}
+
+// Tool describes tt and the rules its results refer to
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the tool component that produced the results
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules"`
+}
+
+// Rule is a reportingDescriptor: a keyword or a policy of tt check
+type Rule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name,omitempty"`
+	ShortDescription     Message           `json:"shortDescription"`
+	DefaultConfiguration *RuleConfig       `json:"defaultConfiguration,omitempty"`
+	Properties           map[string]string `json:"properties,omitempty"`
+}
+
+// RuleConfig is the level results of a rule have by default
+type RuleConfig struct {
+	Level string `json:"level"`
+}
+
+// Message is a plain text message
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is one TODO or violation
+type Result struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             Message           `json:"message"`
+	Locations           []Location        `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	Properties          map[string]string `json:"properties,omitempty"`
+}
+
+// Location is where a result is found
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a region of a file
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is a file, or a directory, relative to a base when
+// URIBaseID is set
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region is a range of lines and columns, both counted from 1. Columns count
+// Unicode code points and the end column is exclusive.
+type Region struct {
+	StartLine   int      `json:"startLine"`
+	StartColumn int      `json:"startColumn,omitempty"`
+	EndLine     int      `json:"endLine,omitempty"`
+	EndColumn   int      `json:"endColumn,omitempty"`
+	Snippet     *Message `json:"snippet,omitempty"`
+}
+
+// NewLog returns an empty log
+func NewLog() *Log {
+	return &Log{Schema: schema, Version: version, Runs: []*Run{}}
+}
+
+// AddRun starts a run whose files are relative to root
+func (l *Log) AddRun(root string) *Run {
+	uri := filepath.ToSlash(root)
+	if !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri // Windows drive letters
+	}
+	run := &Run{
+		Tool: Tool{Driver: Driver{
+			Name:           "tt",
+			InformationURI: "https://github.com/AdrielMendezRios/Ttracker",
+			Rules:          []Rule{},
+		}},
+		OriginalURIBaseIDs: map[string]ArtifactLocation{
+			rootBase: {URI: "file://" + uriOf(strings.TrimSuffix(uri, "/")+"/")},
+		},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []Result{},
+		ruleIndex:  make(map[string]int),
+	}
+	l.Runs = append(l.Runs, run)
+	return run
+}
+
+// Write writes the log as indented JSON
+func (l *Log) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
+
+// AddTodo adds a TODO as a result of the rule of its keyword
+func (r *Run) AddTodo(item check.Item) {
+	keyword := keywordOf(item)
+	rule := r.rule(strings.ToLower(keyword), func() Rule {
+		return Rule{
+			ID:                   strings.ToLower(keyword),
+			Name:                 keyword,
+			ShortDescription:     Message{Text: fmt.Sprintf("%s comment", keyword)},
+			DefaultConfiguration: &RuleConfig{Level: level(item.Severity)},
+		}
+	})
+	r.Results = append(r.Results, Result{
+		RuleID:              r.Tool.Driver.Rules[rule].ID,
+		RuleIndex:           rule,
+		Level:               level(item.Severity),
+		Message:             Message{Text: commentText(item.Comment)},
+		Locations:           []Location{r.location(item)},
+		PartialFingerprints: map[string]string{fingerprintKey: item.Fingerprint},
+		Properties:          properties(item),
+	})
+}
+
+// ruleDescriptions describe the policies of tt check, %s is the keyword
+var ruleDescriptions = map[string]string{
+	check.RuleForbidden:     "%s is not allowed",
+	check.RuleMissingOwner:  "%s without an owner",
+	check.RuleOverdue:       "%s past its due date",
+	check.RuleMaxPerPackage: "Too many TODOs in one directory",
+	check.RuleNew:           "%s not in the baseline",
+}
+
+// AddViolation adds a violation of a tt check policy. Violations of a TODO
+// have a rule per keyword and policy, e.g. fixme/forbidden-keyword, and
+// always are errors: they fail the check.
+func (r *Run) AddViolation(v check.Violation) {
+	id, description := v.Rule, ruleDescriptions[v.Rule]
+	if v.Item != nil {
+		keyword := keywordOf(*v.Item)
+		id = strings.ToLower(keyword) + "/" + v.Rule
+		description = fmt.Sprintf(description, keyword)
+	}
+	rule := r.rule(id, func() Rule {
+		return Rule{
+			ID:                   id,
+			ShortDescription:     Message{Text: description},
+			DefaultConfiguration: &RuleConfig{Level: "error"},
+			Properties:           map[string]string{"policy": v.Rule},
+		}
+	})
+
+	result := Result{
+		RuleID:    id,
+		RuleIndex: rule,
+		Level:     "error",
+		Message:   Message{Text: v.Message},
+	}
+	if v.Item != nil {
+		result.Locations = []Location{r.location(*v.Item)}
+		result.PartialFingerprints = map[string]string{fingerprintKey: v.Item.Fingerprint + "/" + v.Rule}
+		result.Properties = properties(*v.Item)
+	} else {
+		// Package limits point at the directory
+		result.Locations = []Location{{PhysicalLocation: PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{URI: uriOf(directoryOf(v.Package)), URIBaseID: rootBase},
+		}}}
+		result.PartialFingerprints = map[string]string{fingerprintKey: v.Package + "/" + v.Rule}
+	}
+	r.Results = append(r.Results, result)
+}
+
+// rule returns the index of the rule with id, adding the one made by create
+// the first time
+func (r *Run) rule(id string, create func() Rule) int {
+	if i, ok := r.ruleIndex[id]; ok {
+		return i
+	}
+	r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, create())
+	r.ruleIndex[id] = len(r.Tool.Driver.Rules) - 1
+	return r.ruleIndex[id]
+}
+
+// location places a TODO in its file. Columns are found in the source when
+// the TODO is still where the scan found it: from its keyword to the end of
+// its last line.
+func (r *Run) location(item check.Item) Location {
+	region := &Region{StartLine: item.Line, Snippet: &Message{Text: item.Comment}}
+	if item.EndLine > item.Line {
+		region.EndLine = item.EndLine
+	}
+
+	if snippet, err := scan.LoadSnippet(item.Todo, 0); err == nil && snippet.Line == item.Line && len(snippet.Lines) > 0 {
+		first, last := snippet.Lines[0], snippet.Lines[len(snippet.Lines)-1]
+		region.StartColumn = keywordColumn(first, item)
+		region.EndColumn = utf8.RuneCountInString(strings.TrimRight(last, " \t")) + 1
+		if region.EndLine == 0 && region.EndColumn <= region.StartColumn {
+			region.EndColumn = 0
+		}
+	}
+
+	return Location{PhysicalLocation: PhysicalLocation{
+		ArtifactLocation: ArtifactLocation{URI: uriOf(item.File), URIBaseID: rootBase},
+		Region:           region,
+	}}
+}
+
+// keywordColumn returns the column of the TODO's keyword in the source line
+// it starts on, the start of its comment when the keyword is not found
+func keywordColumn(line string, item check.Item) int {
+	start := 0
+	first := strings.TrimSpace(strings.SplitN(item.Comment, "\n", 2)[0])
+	if i := strings.Index(line, first); i >= 0 && first != "" {
+		start = i
+	}
+	if item.Keyword != "" {
+		re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(item.Keyword))
+		if loc := re.FindStringIndex(line[start:]); loc != nil {
+			start += loc[0]
+		}
+	}
+	return utf8.RuneCountInString(line[:start]) + 1
+}
+
+// level maps a keyword severity to a SARIF level
+func level(severity string) string {
+	switch severity {
+	case keywords.SeverityCritical, keywords.SeverityHigh:
+		return "error"
+	case keywords.SeverityLow:
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+func keywordOf(item check.Item) string {
+	if item.Keyword == "" {
+		return "TODO"
+	}
+	return strings.ToUpper(item.Keyword)
+}
+
+// commentText is a TODO's comment on one line without comment markers
+func commentText(comment string) string {
+	var parts []string
+	for _, line := range strings.Split(comment, "\n") {
+		if text := scan.StripCommentMarkers(line); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func properties(item check.Item) map[string]string {
+	props := map[string]string{"keyword": item.Keyword}
+	if item.Severity != "" {
+		props["severity"] = item.Severity
+	}
+	if item.Function != "" {
+		props["function"] = item.Function
+	}
+	if item.Owner != "" {
+		props["owner"] = item.Owner
+	}
+	if item.Due != "" {
+		props["due"] = item.Due
+	}
+	return props
+}
+
+func directoryOf(pkg string) string {
+	if pkg == "." || pkg == "" {
+		return "./"
+	}
+	return pkg + "/"
+}
+
+// uriOf escapes a slash separated path for use in a URI
+func uriOf(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
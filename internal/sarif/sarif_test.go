@@ -0,0 +1,159 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Ttracker/internal/check"
+)
+
+// writeTree writes files, keyed by their slash separated path, below a new
+// directory and returns it
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// describeLocation writes a result's location as uri:region
+func describeLocation(result Result) string {
+	if len(result.Locations) == 0 {
+		return ""
+	}
+	loc := result.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URIBaseID != rootBase {
+		return "no base " + loc.ArtifactLocation.URI
+	}
+	if loc.Region == nil {
+		return loc.ArtifactLocation.URI
+	}
+	r := loc.Region
+	return fmt.Sprintf("%s:%d.%d-%d.%d", loc.ArtifactLocation.URI, r.StartLine, r.StartColumn, r.EndLine, r.EndColumn)
+}
+
+func TestTodoResults(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":         "package a\n\n// TODO: go thing\nfunc f() {}\n\n// FIXME: spans\n// two lines\nfunc g() {}\n",
+		"c.go":         "package c\n\n// FIXME: spans\n// two lines\nfunc g() {}\n",
+		"lib/b.py":     "x = 1  # TODO: trailing\n# todo: café ünïcode\n",
+		"sub dir/é.go": "package sub\n\nfunc f() {\n\t// TODO: indented\n}\n",
+	})
+	report, err := check.Run(root, check.Policy{}, check.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The TODOs of a file changed since the scan may have moved, they get no
+	// columns
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte("package a\n\n\n// TODO: go thing\nfunc f() {}\n\n// FIXME: spans\n// two lines\nfunc g() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := NewLog()
+	run := log.AddRun(root)
+	for _, item := range report.Todos {
+		run.AddTodo(item)
+	}
+
+	var got []string
+	for _, result := range run.Results {
+		rule := run.Tool.Driver.Rules[result.RuleIndex]
+		if rule.ID != result.RuleID {
+			t.Errorf("result of rule %s points at rule %s", result.RuleID, rule.ID)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s %q", result.RuleID, result.Level, describeLocation(result), result.Message.Text))
+	}
+	want := []string{
+		`todo warning a.go:3.0-0.0 "TODO: go thing"`,
+		`fixme error a.go:6.0-7.0 "FIXME: spans two lines"`,
+		`fixme error c.go:3.4-4.13 "FIXME: spans two lines"`,
+		`todo warning lib/b.py:1.10-0.24 "TODO: trailing"`,
+		`todo warning lib/b.py:2.3-0.21 "todo: café ünïcode"`,
+		`todo warning sub%20dir/%C3%A9.go:4.5-0.19 "TODO: indented"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("results:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+
+	var rules []string
+	for _, rule := range run.Tool.Driver.Rules {
+		rules = append(rules, rule.ID+" "+rule.DefaultConfiguration.Level)
+	}
+	if got, want := strings.Join(rules, ", "), "todo warning, fixme error"; got != want {
+		t.Errorf("rules %s, want %s", got, want)
+	}
+	if base := run.OriginalURIBaseIDs[rootBase].URI; !strings.HasPrefix(base, "file://") || !strings.HasSuffix(base, "/") {
+		t.Errorf("%s is %s, want a file URI of a directory", rootBase, base)
+	}
+}
+
+func TestViolationResults(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":     "package a\n\n// FIXME: no owner\nfunc f() {}\n",
+		"lib/b.go": "package lib\n\n// FIXME(alice): one\n// TODO: two\nfunc g() {}\n",
+	})
+	policy := check.Policy{
+		Forbid:        []check.ForbidRule{{Keywords: []string{"FIXME"}}},
+		RequireOwner:  true,
+		MaxPerPackage: 1,
+	}
+	report, err := check.Run(root, policy, check.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := NewLog()
+	run := log.AddRun(root)
+	for _, v := range report.Violations {
+		run.AddViolation(v)
+	}
+
+	var got []string
+	for _, result := range run.Results {
+		rule := run.Tool.Driver.Rules[result.RuleIndex]
+		if rule.ID != result.RuleID || rule.Properties["policy"] == "" {
+			t.Errorf("result of rule %s points at rule %+v", result.RuleID, rule)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s", result.RuleID, result.Level, describeLocation(result)))
+	}
+	want := []string{
+		"fixme/forbidden-keyword error a.go:3.4-0.19",
+		"fixme/missing-owner error a.go:3.4-0.19",
+		"fixme/forbidden-keyword error lib/b.go:3.4-0.21",
+		"todo/missing-owner error lib/b.go:4.4-0.13",
+		"max-per-package error lib/",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("results:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+	if n := len(run.Tool.Driver.Rules); n != 4 {
+		t.Errorf("%d rules, want 4", n)
+	}
+
+	// The log is valid JSON with the SARIF header
+	var buf bytes.Buffer
+	if err := log.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["version"] != version || decoded["$schema"] != schema {
+		t.Errorf("header %v %v", decoded["version"], decoded["$schema"])
+	}
+}
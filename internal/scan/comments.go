@@ -0,0 +1,112 @@
+package scan
+
+import (
+	"strings"
+
+	"Ttracker/internal/keywords"
+)
+
+// commentLine is a single source line of comment text
+type commentLine struct {
+	Line     int
+	Text     string // raw text including the comment markers
+	Trailing bool   // the comment follows code on the same line
+	Prose    bool   // the line is documentation that never starts a TODO
+}
+
+// todoSpan is a TODO that may continue over several comment lines
+type todoSpan struct {
+	StartLine int
+	EndLine   int
+	Text      string // raw comment lines joined with "\n"
+	Keyword   keywords.Keyword
+}
+
+// StripCommentMarkers removes comment delimiters (//, /* */, #, --) and the
+// leading * of block comment lines from a line of comment text
+func StripCommentMarkers(text string) string {
+	text = strings.TrimSpace(text)
+	for _, marker := range []string{"//", "/*", "#", "--"} {
+		if strings.HasPrefix(text, marker) {
+			text = strings.TrimPrefix(text, marker)
+			break
+		}
+	}
+	text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "*/"))
+	if strings.HasPrefix(text, "*") {
+		text = strings.TrimSpace(strings.TrimLeft(text, "*"))
+	}
+	return text
+}
+
+// mergeTodoLines finds TODOs in a run of comment lines. A TODO continues on the
+// following consecutive lines until a blank comment line or a line holding
+// another keyword. TODOs trailing code are never continued.
+//
+// A keyword that starts a line in the middle of a sentence is a wrapped word,
+// as in "draws the source around a\n// TODO, or ...", unless it is followed
+// by a colon or parentheses.
+func mergeTodoLines(lines []commentLine, matcher *keywords.Matcher) []todoSpan {
+	var spans []todoSpan
+	var current *todoSpan
+
+	flush := func() {
+		if current != nil {
+			spans = append(spans, *current)
+			current = nil
+		}
+	}
+
+	for i, line := range lines {
+		kw, at, found := matcher.MatchIndex(line.Text)
+		if found && (line.Prose || i > 0 && continuesSentence(lines[i-1], line) && !isTag(line.Text, at+len(kw.Name))) {
+			found = false
+		}
+		if found {
+			flush()
+			current = &todoSpan{
+				StartLine: line.Line,
+				EndLine:   line.Line,
+				Text:      line.Text,
+				Keyword:   kw,
+			}
+			if line.Trailing {
+				flush()
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if line.Trailing || line.Line != current.EndLine+1 || StripCommentMarkers(line.Text) == "" {
+			flush()
+			continue
+		}
+		current.EndLine = line.Line
+		current.Text += "\n" + line.Text
+	}
+	flush()
+
+	return spans
+}
+
+// continuesSentence reports whether line carries on the sentence of the
+// comment line before it
+func continuesSentence(prev, line commentLine) bool {
+	if prev.Line != line.Line-1 || prev.Trailing || line.Trailing || strings.HasSuffix(strings.TrimSpace(prev.Text), "*/") {
+		return false
+	}
+	text := StripCommentMarkers(prev.Text)
+	return text != "" && !strings.ContainsAny(text[len(text)-1:], ".:;!?")
+}
+
+// isTag reports whether the text after a keyword ending at end makes it a
+// tag such as TODO: or TODO(alice) rather than a word of a sentence
+func isTag(text string, end int) bool {
+	if end > len(text) {
+		return false
+	}
+	rest := strings.TrimLeft(text[end:], " \t")
+	return rest == "" || strings.ContainsAny(rest[:1], ":([")
+}
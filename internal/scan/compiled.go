@@ -0,0 +1,66 @@
+package scan
+
+import (
+	"Ttracker/internal/keywords"
+	"Ttracker/internal/store"
+	"Ttracker/pkg/ttapi"
+)
+
+// CompiledParser runs a parser compiled in through ttapi, converting its
+// TODOs at the boundary so ttapi does not depend on the store's types
+type CompiledParser struct {
+	Name    string
+	Scanner ttapi.Scanner
+}
+
+// SupportedExtensions returns file extensions supported by this parser
+func (cp *CompiledParser) SupportedExtensions() []string {
+	return cp.Scanner.SupportedExtensions()
+}
+
+// ParseFile runs the parser and returns its TODOs
+func (cp *CompiledParser) ParseFile(filePath string) ([]store.Todo, error) {
+	found, err := cp.Scanner.ParseFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	todos := make([]store.Todo, len(found))
+	for i, t := range found {
+		todos[i] = store.Todo{
+			Comment:    t.Comment,
+			FilePath:   filePath,
+			LineNumber: t.LineNumber,
+			Function:   t.Function,
+			Keyword:    t.Keyword,
+			Severity:   t.Severity,
+		}
+		if t.EndLine > t.LineNumber {
+			todos[i].EndLine = t.EndLine
+		}
+	}
+
+	// Fill in scopes the parser didn't report using the built-in structural parser
+	annotateScopes(filePath, todos)
+
+	return todos, nil
+}
+
+// setMatcher hands the keywords of matcher to parsers that want them
+func (cp *CompiledParser) setMatcher(matcher *keywords.Matcher) error {
+	ks, ok := cp.Scanner.(ttapi.KeywordScanner)
+	if !ok {
+		return nil
+	}
+	cfg := matcher.Config()
+	apiCfg := ttapi.Config{CaseSensitive: cfg.IsCaseSensitive(), WordBoundary: cfg.IsWordBoundary()}
+	for _, kw := range cfg.Keywords {
+		apiCfg.Keywords = append(apiCfg.Keywords, ttapi.Keyword{Name: kw.Name, Severity: kw.Severity})
+	}
+	m, err := ttapi.NewMatcher(apiCfg)
+	if err != nil {
+		return err
+	}
+	ks.SetMatcher(m)
+	return nil
+}
@@ -0,0 +1,74 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Ttracker/internal/keywords"
+	"Ttracker/pkg/ttapi"
+)
+
+// lineParser reports every line of a .tttest file that starts with a keyword
+type lineParser struct {
+	matcher *ttapi.Matcher
+}
+
+func (p *lineParser) SupportedExtensions() []string { return []string{".tttest"} }
+
+func (p *lineParser) SetMatcher(m *ttapi.Matcher) { p.matcher = m }
+
+func (p *lineParser) ParseFile(filePath string) ([]ttapi.Todo, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var todos []ttapi.Todo
+	for i, line := range strings.Split(string(data), "\n") {
+		if kw, ok := p.matcher.Match(line); ok {
+			todos = append(todos, ttapi.Todo{LineNumber: i + 1, Comment: line, Keyword: kw.Name})
+		}
+	}
+	return todos, nil
+}
+
+func init() {
+	ttapi.Register("line-test", func() ttapi.Scanner { return &lineParser{matcher: ttapi.DefaultMatcher()} })
+}
+
+// TestCompiledParserPerManager checks that managers with different keywords
+// each get their own compiled-in parser
+func TestCompiledParserPerManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.tttest")
+	if err := os.WriteFile(path, []byte("# TODO: one\n# HACK: two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing plugins only leave the built-in parsers
+	defaults, _ := NewManager("")
+	hacks, _ := NewManager("")
+	if err := hacks.SetKeywords(keywords.Config{Keywords: []keywords.Keyword{{Name: "HACK"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := defaults.SetKeywords(keywords.Default()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		mgr  *Manager
+		want string
+	}{{defaults, "TODO"}, {hacks, "HACK"}} {
+		parser, err := tt.mgr.GetParser(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		todos, err := parser.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) != 1 || todos[0].Keyword != tt.want || todos[0].FilePath != path {
+			t.Errorf("got %+v, want one %s in %s", todos, tt.want, path)
+		}
+	}
+}
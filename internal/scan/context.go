@@ -0,0 +1,166 @@
+package scan
+
+import (
+	"os"
+	"strings"
+
+	"Ttracker/internal/config"
+	"Ttracker/internal/store"
+)
+
+// AddContext stores the source around each TODO of a file, as configured
+func AddContext(filePath string, todos []store.Todo, settings config.ContextSettings) {
+	if len(todos) == 0 || settings.Lines == 0 && !settings.Signature {
+		return
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return
+	}
+	lines, err := readLines(filePath)
+	if err != nil {
+		return
+	}
+
+	for i := range todos {
+		snippet := snippetAt(lines, todos[i], todos[i].LineNumber, settings.Lines)
+		if !settings.Signature {
+			snippet.Signature, snippet.SignatureLine = "", 0
+		}
+		snippet.ModTime = info.ModTime()
+		todos[i].Context = snippet
+	}
+}
+
+// LoadSnippet returns n lines of source before and after a TODO with the
+// signature of its enclosing function. The snippet stored by the scan is
+// used when the file did not change since. Otherwise the file is read again,
+// the TODO is looked up near its old line and the snippet is marked stale
+// when the file changed since the scan or the TODO is no longer where the
+// scan found it.
+func LoadSnippet(todo store.Todo, n int) (*store.Snippet, error) {
+	info, err := os.Stat(todo.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stores written before scans recorded the file's time only have it
+	// in the snippet
+	scanned := todo.ModTime
+	stored := todo.Context
+	if scanned.IsZero() && stored != nil {
+		scanned = stored.ModTime
+	}
+	changed := !scanned.IsZero() && !info.ModTime().Equal(scanned)
+	unchanged := stored != nil && info.ModTime().Equal(stored.ModTime)
+	if unchanged && covers(stored, todo, n) && (stored.Signature != "" || todo.Scope == nil) {
+		return trimSnippet(stored, todo, n), nil
+	}
+
+	lines, err := readLines(todo.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	line, found := locateTodo(lines, todo)
+	snippet := snippetAt(lines, todo, line, n)
+	snippet.ModTime = info.ModTime()
+	snippet.Stale = changed || !found || line != todo.LineNumber
+	return snippet, nil
+}
+
+// snippetAt cuts n lines before and after a TODO now found at line
+func snippetAt(lines []string, todo store.Todo, line, n int) *store.Snippet {
+	last := line + todoHeight(todo) - 1
+	start, end := line-n, last+n
+	if start < 1 {
+		start = 1
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	snippet := &store.Snippet{StartLine: start, Line: line}
+	if start <= end {
+		snippet.Lines = append([]string(nil), lines[start-1:end]...)
+	}
+
+	// The enclosing scope moved along with the TODO
+	if todo.Scope != nil {
+		sigLine := todo.Scope.StartLine + line - todo.LineNumber
+		if sigLine >= 1 && sigLine <= len(lines) && sigLine <= line {
+			snippet.Signature = strings.TrimSpace(lines[sigLine-1])
+			snippet.SignatureLine = sigLine
+		}
+	}
+	return snippet
+}
+
+// trimSnippet keeps n lines of a stored snippet before and after its TODO
+func trimSnippet(stored *store.Snippet, todo store.Todo, n int) *store.Snippet {
+	snippet := *stored
+	first := stored.Line - n
+	if first < stored.StartLine {
+		first = stored.StartLine
+	}
+	last := stored.Line + todoHeight(todo) - 1 + n
+	if end := stored.StartLine + len(stored.Lines) - 1; last > end {
+		last = end
+	}
+	snippet.StartLine = first
+	snippet.Lines = nil
+	if first <= last {
+		snippet.Lines = stored.Lines[first-stored.StartLine : last-stored.StartLine+1]
+	}
+	return &snippet
+}
+
+// covers reports whether a stored snippet holds n lines around its TODO
+func covers(stored *store.Snippet, todo store.Todo, n int) bool {
+	start := stored.Line - n
+	if start < 1 {
+		start = 1
+	}
+	end := stored.StartLine + len(stored.Lines) - 1
+	return stored.StartLine <= start && end >= stored.Line+todoHeight(todo)-1+n
+}
+
+// locateTodo finds the line of a TODO in the current content of its file,
+// the nearest line to where the scan found it that holds its first line
+func locateTodo(lines []string, todo store.Todo) (int, bool) {
+	first := strings.SplitN(strings.TrimSpace(todo.Comment), "\n", 2)[0]
+	first = strings.Join(strings.Fields(first), " ")
+	holds := func(line int) bool {
+		if line < 1 || line > len(lines) {
+			return false
+		}
+		return strings.Contains(strings.Join(strings.Fields(lines[line-1]), " "), first)
+	}
+
+	for distance := 0; distance <= len(lines); distance++ {
+		if holds(todo.LineNumber - distance) {
+			return todo.LineNumber - distance, true
+		}
+		if holds(todo.LineNumber + distance) {
+			return todo.LineNumber + distance, true
+		}
+	}
+	return todo.LineNumber, false
+}
+
+// todoHeight returns the number of lines a TODO spans
+func todoHeight(todo store.Todo) int {
+	if todo.EndLine > todo.LineNumber {
+		return todo.EndLine - todo.LineNumber + 1
+	}
+	return 1
+}
+
+// readLines reads the lines of a file
+func readLines(filePath string) ([]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	return strings.Split(text, "\n"), nil
+}
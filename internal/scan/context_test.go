@@ -0,0 +1,57 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Ttracker/internal/config"
+	"Ttracker/internal/ignore"
+)
+
+func TestLoadSnippetStale(t *testing.T) {
+	for _, settings := range []config.ContextSettings{{}, {Lines: 1}} {
+		root := t.TempDir()
+		path := filepath.Join(root, "a.go")
+		src := "package a\n\n// TODO: stale\nfunc f() {}\n"
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ignoreMgr, err := ignore.NewProjectIgnoreManager(root, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		todos, _, err := ScanDir(root, NewBuiltinManager(), ignoreMgr, ScanOptions{Context: settings})
+		if err != nil || len(todos) != 1 {
+			t.Fatalf("ScanDir found %v, %v", todos, err)
+		}
+		if todos[0].ModTime.IsZero() {
+			t.Fatalf("context %+v: the scan did not record the file's time", settings)
+		}
+
+		snippet, err := LoadSnippet(todos[0], 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snippet.Stale {
+			t.Errorf("context %+v: snippet of an unchanged file is stale", settings)
+		}
+
+		// An edit that leaves the TODO where it was still changed the file
+		if err := os.WriteFile(path, []byte(src+"\nfunc g() {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		later := todos[0].ModTime.Add(time.Second)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+		snippet, err = LoadSnippet(todos[0], 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !snippet.Stale || snippet.Line != 3 {
+			t.Errorf("context %+v: snippet of a changed file at line %d, stale %v", settings, snippet.Line, snippet.Stale)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package scan
+
+import (
+	"path/filepath"
+	"strings"
+
+	"Ttracker/internal/keywords"
+	"Ttracker/internal/store"
+)
+
+// Fragment is a run of consecutive lines of a file, such as the lines of a
+// diff hunk
+type Fragment struct {
+	StartLine int
+	Lines     []string
+}
+
+// KnowsComments reports whether ParseFragments knows the comment syntax of
+// the file's language
+func KnowsComments(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".go" || scopeLangs[ext] != nil
+}
+
+// ParseFragments finds the TODOs in fragments of a file whose full source is
+// not at hand. Comments are found with the comment syntax of the file's
+// language, as for highlighting, so files of unknown languages yield none.
+// Fragments are read separately: a block comment opened before a fragment
+// is not seen.
+func (m *Manager) ParseFragments(filePath string, fragments []Fragment) []store.Todo {
+	matcher := m.matcher
+	if matcher == nil {
+		matcher = keywords.DefaultMatcher()
+	}
+
+	var todos []store.Todo
+	for _, fragment := range fragments {
+		var lines []commentLine
+		for i, tokens := range Highlight(filePath, fragment.Lines) {
+			code := false
+			for _, token := range tokens {
+				if token.Kind != TokenComment {
+					code = code || strings.TrimSpace(token.Text) != ""
+					continue
+				}
+				lines = append(lines, commentLine{Line: fragment.StartLine + i, Text: token.Text, Trailing: code})
+				break
+			}
+		}
+
+		for _, span := range mergeTodoLines(lines, matcher) {
+			todo := store.Todo{
+				Comment:    span.Text,
+				FilePath:   filePath,
+				LineNumber: span.StartLine,
+				Keyword:    span.Keyword.Name,
+				Severity:   span.Keyword.Severity,
+			}
+			if span.EndLine > span.StartLine {
+				todo.EndLine = span.EndLine
+			}
+			todos = append(todos, todo)
+		}
+	}
+	return todos
+}
+
+// commentParser reads whole files with ParseFragments, for the files of
+// languages no parser handles
+type commentParser struct {
+	mgr *Manager
+}
+
+func (p commentParser) SupportedExtensions() []string { return nil }
+
+func (p commentParser) ParseFile(filePath string) ([]store.Todo, error) {
+	lines, err := readLines(filePath)
+	if err != nil {
+		return nil, err
+	}
+	todos := p.mgr.ParseFragments(filePath, []Fragment{{StartLine: 1, Lines: lines}})
+	annotateScopes(filePath, todos)
+	return todos, nil
+}
@@ -1,22 +1,24 @@
 package scan
 
 import (
+	"Ttracker/internal/keywords"
 	"Ttracker/internal/store"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"regexp"
+	"os"
+	"sort"
 	"strings"
 )
 
-// Regular expression to match TODO and FIXME comments
-// More relaxed pattern to catch different formats
-// var todoRegex = regexp.MustCompile(`(?i)(todo|fixme)`)
-var todoRegex = regexp.MustCompile(`.*\s(TODO|FIXME)[\s:\(\[]`)
-
 // GoParser implements the Scanner for Go source Files.
-type GoParser struct{}
+type GoParser struct {
+	// Matcher finds TODO keywords in comments; the default keywords are used when nil
+	Matcher *keywords.Matcher
+	// Quiet stops the parser from printing the TODOs it finds
+	Quiet bool
+}
 
 func (g *GoParser) SupportedExtensions() []string {
 	return []string{".go"}
@@ -25,58 +27,309 @@ func (g *GoParser) SupportedExtensions() []string {
 func (g *GoParser) ParseFile(filePath string) ([]store.Todo, error) {
 	var todos []store.Todo
 
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Go file: %v", err)
+	}
+
 	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
+	node, err := parser.ParseFile(fset, filePath, src, parser.ParseComments)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Go file: %v", err)
 	}
 
-	fmt.Printf("Checking for TODOs in %s\n", filePath)
+	logf := func(format string, args ...interface{}) {
+		if !g.Quiet {
+			fmt.Printf(format, args...)
+		}
+	}
+	logf("Checking for TODOs in %s\n", filePath)
+
+	matcher := g.Matcher
+	if matcher == nil {
+		matcher = keywords.DefaultMatcher()
+	}
+
+	scopes := goScopes(node, fset)
+	docs := docComments(node)
+	pkgName := node.Name.Name
 
 	// Iterate over all comment groups
 	for _, group := range node.Comments {
+		// Split the group into source lines so block comments and runs of
+		// line comments are handled the same way
+		var lines []commentLine
 		for _, c := range group.List {
-			text := c.Text
-			if todoRegex.MatchString(text) {
-				pos := fset.Position(c.Pos())
-				funcName := findEnclosingFunction(node, c.Pos(), fset)
-
-				// Get a preview of the comment for logging
-				preview := text
-				if len(text) > 40 {
-					preview = text[:40] + "..."
+			// Positions ignore //line directives, lines and columns must
+			// match the file itself
+			pos := fset.PositionFor(c.Pos(), false)
+			trailing := len(strings.TrimSpace(string(src[pos.Offset-pos.Column+1:pos.Offset]))) > 0
+			for i, text := range strings.Split(c.Text, "\n") {
+				lines = append(lines, commentLine{Line: pos.Line + i, Text: text, Trailing: trailing && i == 0})
+			}
+		}
+		if name, ok := docs[group]; ok {
+			markDocProse(lines, name)
+		}
+
+		for _, span := range mergeTodoLines(lines, matcher) {
+			var scope *store.Scope
+			if s := EnclosingScope(scopes, span.StartLine); s != nil {
+				copied := *s
+				scope = &copied
+			}
+
+			// Get a preview of the comment for logging
+			preview := span.Text
+			if len(preview) > 40 {
+				preview = preview[:40] + "..."
+			}
+			logf("Found TODO at line %d: %s\n", span.StartLine, strings.TrimSpace(preview))
+
+			todo := store.Todo{
+				Comment:    span.Text,
+				FilePath:   filePath,
+				LineNumber: span.StartLine,
+				Function:   EnclosingFunction(scopes, span.StartLine),
+				Scope:      scope,
+				Package:    pkgName,
+				Keyword:    span.Keyword.Name,
+				Severity:   span.Keyword.Severity,
+			}
+			if span.EndLine > span.StartLine {
+				todo.EndLine = span.EndLine
+			}
+			todos = append(todos, todo)
+		}
+	}
+
+	logf("Found %d TODOs in file %s\n", len(todos), filePath)
+	return todos, nil
+}
+
+// docComments maps the doc comments of a file's package, declarations and
+// fields to the name they document
+func docComments(node *ast.File) map[*ast.CommentGroup]string {
+	docs := make(map[*ast.CommentGroup]string)
+	add := func(doc *ast.CommentGroup, names ...*ast.Ident) {
+		if doc != nil && len(names) > 0 {
+			docs[doc] = names[0].Name
+		}
+	}
+
+	add(node.Doc, node.Name)
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch d := n.(type) {
+		case *ast.FuncDecl:
+			add(d.Doc, d.Name)
+		case *ast.GenDecl:
+			if len(d.Specs) > 0 {
+				switch sp := d.Specs[0].(type) {
+				case *ast.TypeSpec:
+					add(d.Doc, sp.Name)
+				case *ast.ValueSpec:
+					add(d.Doc, sp.Names...)
+				}
+			}
+		case *ast.TypeSpec:
+			add(d.Doc, d.Name)
+		case *ast.ValueSpec:
+			add(d.Doc, d.Names...)
+		case *ast.Field:
+			add(d.Doc, d.Names...)
+		}
+		return true
+	})
+	return docs
+}
+
+// markDocProse marks the lines of a doc comment that never start a TODO: a
+// first sentence that begins with the documented name, as in "// Todo is a
+// TODO found in a file", and indented code blocks
+func markDocProse(lines []commentLine, name string) {
+	for i := range lines {
+		text := strings.TrimPrefix(lines[i].Text, "//")
+		if i == 0 && strings.HasPrefix(StripCommentMarkers(lines[i].Text)+" ", name+" ") {
+			lines[i].Prose = true
+		}
+		if strings.HasPrefix(text, "\t") {
+			lines[i].Prose = true
+		}
+	}
+}
+
+// goScopes collects the package doc comment, declarations, methods and
+// closures of a Go file as scopes. Doc comments count as part of the
+// declaration they document.
+func goScopes(node *ast.File, fset *token.FileSet) []store.Scope {
+	var scopes []store.Scope
+
+	lineOf := func(pos token.Pos) int {
+		return fset.PositionFor(pos, false).Line
+	}
+	startOf := func(doc *ast.CommentGroup, pos token.Pos) int {
+		if doc != nil {
+			return lineOf(doc.Pos())
+		}
+		return lineOf(pos)
+	}
+
+	if node.Doc != nil {
+		scopes = append(scopes, store.Scope{
+			Kind:      "package",
+			Name:      node.Name.Name,
+			Path:      node.Name.Name,
+			StartLine: lineOf(node.Doc.Pos()),
+			EndLine:   lineOf(node.Name.End()),
+		})
+	}
+
+	for _, decl := range node.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			scope := store.Scope{
+				Kind:      "function",
+				Name:      d.Name.Name,
+				Path:      d.Name.Name,
+				StartLine: startOf(d.Doc, d.Pos()),
+				EndLine:   lineOf(d.End()),
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				scope.Kind = "method"
+				scope.Receiver = receiverType(d.Recv.List[0].Type)
+				scope.Path = fmt.Sprintf("(%s).%s", scope.Receiver, d.Name.Name)
+				if !strings.HasPrefix(scope.Receiver, "*") {
+					scope.Path = scope.Receiver + "." + d.Name.Name
+				}
+			}
+			scopes = append(scopes, scope)
+			if d.Body != nil {
+				scopes = append(scopes, closureScopes(d.Body, scope.Path, 0, fset)...)
+			}
+
+		case *ast.GenDecl:
+			kind := d.Tok.String()
+			if kind == "import" {
+				continue
+			}
+
+			var names []string
+			for _, spec := range d.Specs {
+				var specScope store.Scope
+				// Ungrouped declarations keep their doc comment on the GenDecl
+				doc := d.Doc
+				if d.Lparen.IsValid() {
+					doc = nil
+				}
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					specScope = store.Scope{
+						Kind:      kind,
+						Name:      sp.Name.Name,
+						Path:      sp.Name.Name,
+						StartLine: startOf(firstDoc(sp.Doc, doc), sp.Pos()),
+						EndLine:   lineOf(sp.End()),
+					}
+				case *ast.ValueSpec:
+					specNames := make([]string, len(sp.Names))
+					for i, n := range sp.Names {
+						specNames[i] = n.Name
+					}
+					name := strings.Join(specNames, ", ")
+					specScope = store.Scope{
+						Kind:      kind,
+						Name:      name,
+						Path:      name,
+						StartLine: startOf(firstDoc(sp.Doc, doc), sp.Pos()),
+						EndLine:   lineOf(sp.End()),
+					}
+				default:
+					continue
 				}
-				fmt.Printf("Found TODO at line %d: %s\n", pos.Line, strings.TrimSpace(preview))
+				if sp, ok := spec.(*ast.ValueSpec); ok && sp.Comment != nil {
+					specScope.EndLine = lineOf(sp.Comment.End())
+				}
+				names = append(names, specScope.Name)
+				scopes = append(scopes, specScope)
+				scopes = append(scopes, closureScopes(spec, specScope.Path, 0, fset)...)
+			}
 
-				todos = append(todos, store.Todo{
-					Comment:    text,
-					FilePath:   filePath,
-					LineNumber: pos.Line,
-					Function:   funcName,
+			// Grouped declarations also get a scope for comments between specs
+			if d.Lparen.IsValid() && len(names) > 0 {
+				scopes = append(scopes, store.Scope{
+					Kind:      kind,
+					Name:      names[0],
+					Path:      names[0],
+					StartLine: startOf(d.Doc, d.Pos()),
+					EndLine:   lineOf(d.End()),
 				})
 			}
 		}
 	}
 
-	fmt.Printf("Found %d TODOs in file %s\n", len(todos), filePath)
-	return todos, nil
+	sort.SliceStable(scopes, func(i, j int) bool {
+		return scopes[i].StartLine < scopes[j].StartLine
+	})
+	return scopes
 }
 
-// findEnclosingFunction traverses the AST to locate the function containing pos
-func findEnclosingFunction(node *ast.File, pos token.Pos, fset *token.FileSet) string {
-	var funcName string
+// closureScopes returns a scope for every func literal inside node, named the
+// way the Go runtime names them: Outer.func1, Outer.func1.1, ...
+func closureScopes(node ast.Node, parentPath string, depth int, fset *token.FileSet) []store.Scope {
+	var scopes []store.Scope
+	count := 0
+
 	ast.Inspect(node, func(n ast.Node) bool {
-		if n == nil {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok {
 			return true
 		}
 
-		if fn, ok := n.(*ast.FuncDecl); ok {
-			if fn.Pos() <= pos && pos <= fn.End() {
-				funcName = fn.Name.Name
-				return false // found it; breakout
-			}
+		count++
+		name := fmt.Sprintf("func%d", count)
+		if depth > 0 {
+			name = fmt.Sprintf("%d", count)
 		}
-		return true
+		path := parentPath + "." + name
+
+		scopes = append(scopes, store.Scope{
+			Kind:      "closure",
+			Name:      name,
+			Path:      path,
+			StartLine: fset.PositionFor(lit.Pos(), false).Line,
+			EndLine:   fset.PositionFor(lit.End(), false).Line,
+		})
+		scopes = append(scopes, closureScopes(lit.Body, path, depth+1, fset)...)
+		return false // nested literals are handled by the recursive call
 	})
-	return funcName
+	return scopes
+}
+
+// firstDoc returns the first non-nil doc comment
+func firstDoc(docs ...*ast.CommentGroup) *ast.CommentGroup {
+	for _, doc := range docs {
+		if doc != nil {
+			return doc
+		}
+	}
+	return nil
+}
+
+// receiverType renders a method receiver such as *ProjectWatcher, dropping type parameters
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(t.X)
+	case *ast.ParenExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return receiverType(t.X) + "." + t.Sel.Name
+	}
+	return ""
 }
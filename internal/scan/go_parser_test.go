@@ -0,0 +1,133 @@
+package scan
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// tagRe matches a comment line written as a TODO, keyword then a colon or
+// parentheses
+var tagRe = regexp.MustCompile(`^\s*(//|/\*|\*)\s*(TODO|FIXME)\s*[:(]`)
+
+// TestRepoDocComments parses the repository's own Go files, whose doc
+// comments talk about TODOs a lot, and expects only real TODOs to be found
+func TestRepoDocComments(t *testing.T) {
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := &GoParser{Quiet: true}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		todos, err := parser.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		for _, todo := range todos {
+			first := strings.SplitN(todo.Comment, "\n", 2)[0]
+			if !tagRe.MatchString(first) {
+				t.Errorf("%s:%d: prose reported as a TODO: %s", rel, todo.LineNumber, first)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDocCommentProse(t *testing.T) {
+	src := `// Package demo shows TODOs in doc comments.
+package demo
+
+// Todo is a TODO found in a file.
+type Todo struct {
+	// Comment is the text of the
+	// TODO, markers included
+	Comment string
+}
+
+// Load reads TODOs. Known forms:
+//
+//	TODO(alice): ...
+//
+// TODO: cache the result
+func Load() {
+	// Some explanation.
+	// TODO fix the loop
+	//   on empty input
+
+	// Walks the
+	// TODO list
+	x := 1 // TODO: trailing
+	_ = x
+}
+`
+	path := filepath.Join(t.TempDir(), "demo.go")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	todos, err := (&GoParser{Quiet: true}).ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+	for _, todo := range todos {
+		got = append(got, todo.LineNumber)
+	}
+	want := []int{15, 18, 23}
+	if len(got) != len(want) {
+		t.Fatalf("TODOs at lines %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("TODOs at lines %v, want %v", got, want)
+		}
+	}
+	if todos[1].EndLine != 19 {
+		t.Errorf("TODO at line 18 ends at %d, want 19", todos[1].EndLine)
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	src := `package demo
+
+//line other.go:100:1
+func f() {
+	x := 1 // TODO: after a line directive
+	_ = x
+}
+
+/*line gen.y:1:200*/ var y = 2 // TODO: column past the offset
+`
+	path := filepath.Join(t.TempDir(), "demo.go")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	todos, err := (&GoParser{Quiet: true}).ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[0].LineNumber != 5 || todos[0].Function != "f" || todos[1].LineNumber != 9 {
+		t.Errorf("got %+v, want TODOs in f at line 5 and at line 9", todos)
+	}
+}
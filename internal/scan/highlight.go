@@ -0,0 +1,162 @@
+package scan
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// TokenKind is the syntactic class of a piece of source
+type TokenKind int
+
+const (
+	TokenText TokenKind = iota
+	TokenKeyword
+	TokenString
+	TokenNumber
+	TokenComment
+)
+
+// Token is a piece of a source line
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+// Keywords highlighted in every language, the common ones are enough for a
+// snippet
+var highlightKeywords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"def": true, "default": true, "defer": true, "do": true, "elif": true, "else": true,
+	"end": true, "enum": true, "except": true, "export": true, "extends": true, "false": true,
+	"finally": true, "fn": true, "for": true, "from": true, "fun": true, "func": true,
+	"function": true, "go": true, "if": true, "impl": true, "import": true, "in": true,
+	"interface": true, "let": true, "local": true, "map": true, "match": true, "mod": true,
+	"module": true, "mut": true, "new": true, "nil": true, "None": true, "null": true,
+	"package": true, "private": true, "protected": true, "pub": true, "public": true,
+	"raise": true, "range": true, "return": true, "select": true, "self": true, "static": true,
+	"struct": true, "super": true, "switch": true, "this": true, "throw": true, "trait": true,
+	"true": true, "True": true, "False": true, "try": true, "type": true, "use": true,
+	"var": true, "void": true, "while": true, "with": true, "yield": true,
+}
+
+// Highlight splits source lines into tokens. Comments and strings are found
+// with the comment syntax of the file's language; files of unknown languages
+// are returned as plain text.
+func Highlight(filePath string, lines []string) [][]Token {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	lang, ok := scopeLangs[ext]
+	if !ok && ext == ".go" {
+		lang, ok = cLang, true
+	}
+
+	result := make([][]Token, len(lines))
+	inBlock := false
+	for i, line := range lines {
+		if !ok {
+			result[i] = []Token{{Kind: TokenText, Text: line}}
+			continue
+		}
+		result[i], inBlock = highlightLine(line, lang, inBlock)
+	}
+	return result
+}
+
+// highlightLine tokenizes one line, inBlock tells whether it starts inside a
+// block comment, the result whether the next line does
+func highlightLine(line string, lang *scopeLang, inBlock bool) ([]Token, bool) {
+	var tokens []Token
+	add := func(kind TokenKind, text string) {
+		if text == "" {
+			return
+		}
+		if n := len(tokens); n > 0 && tokens[n-1].Kind == kind {
+			tokens[n-1].Text += text
+			return
+		}
+		tokens = append(tokens, Token{Kind: kind, Text: text})
+	}
+
+	for j := 0; j < len(line); {
+		rest := line[j:]
+
+		if inBlock {
+			end := strings.Index(rest, lang.blockComment[1])
+			if end < 0 {
+				add(TokenComment, rest)
+				break
+			}
+			end += len(lang.blockComment[1])
+			add(TokenComment, rest[:end])
+			inBlock = false
+			j += end
+			continue
+		}
+
+		if start := lang.blockComment[0]; start != "" && strings.HasPrefix(rest, start) {
+			inBlock = true
+			add(TokenComment, start)
+			j += len(start)
+			continue
+		}
+		isLineComment := false
+		for _, marker := range lang.lineComments {
+			if strings.HasPrefix(rest, marker) {
+				isLineComment = true
+				break
+			}
+		}
+		if isLineComment {
+			add(TokenComment, rest)
+			break
+		}
+
+		c := line[j]
+		switch {
+		case c == '"' || c == '`' || c == '\'' && lang != rustLang:
+			end := j + 1
+			for end < len(line) && line[end] != c {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(line) {
+				end++
+			} else {
+				end = len(line)
+			}
+			add(TokenString, line[j:end])
+			j = end
+		case isWordStart(c):
+			end := j + 1
+			for end < len(line) && (isWordStart(line[end]) || isDigit(line[end])) {
+				end++
+			}
+			kind := TokenText
+			if highlightKeywords[line[j:end]] {
+				kind = TokenKeyword
+			}
+			add(kind, line[j:end])
+			j = end
+		case isDigit(c):
+			end := j + 1
+			for end < len(line) && (isDigit(line[end]) || isWordStart(line[end]) || line[end] == '.') {
+				end++
+			}
+			add(TokenNumber, line[j:end])
+			j = end
+		default:
+			add(TokenText, line[j:j+1])
+			j++
+		}
+	}
+	return tokens, inBlock
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -1,10 +1,12 @@
 package scan
 
 import (
+	"Ttracker/internal/keywords"
 	plugin "Ttracker/internal/plugins"
 	"Ttracker/internal/store"
+	"Ttracker/pkg/ttapi"
+	"context"
 	"fmt"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -13,12 +15,17 @@ import (
 // Manager handles all file parsing operations for finding TODOs
 type Manager struct {
 	Parsers []Scanner
+	matcher *keywords.Matcher // set by SetKeywords, the default keywords when nil
 }
 
-// ExternalParser implements Scanner for external plugin-based parsers
+// ExternalParser implements Scanner for external plugin-based parsers, run as
+// programs or, for .wasm commands, as WebAssembly modules
 type ExternalParser struct {
 	Command             string
 	ExtensionsSupported []string
+	// Matcher is handed to the plugin through its environment and used to
+	// classify the comments it reports; the default keywords are used when nil
+	Matcher *keywords.Matcher
 }
 
 // SupportedExtensions returns file extensions supported by this parser
@@ -28,12 +35,28 @@ func (ep *ExternalParser) SupportedExtensions() []string {
 
 // ParseFile executes the external parser and returns extracted TODOs
 func (ep *ExternalParser) ParseFile(filePath string) ([]store.Todo, error) {
-	cmd := exec.Command(ep.Command, filePath)
-	output, err := cmd.Output()
+	matcher := ep.Matcher
+	if matcher == nil {
+		matcher = keywords.DefaultMatcher()
+	}
+
+	output, _, err := plugin.Run(context.Background(), ep.Command, filePath, matcher.Environ())
 	if err != nil {
 		return nil, fmt.Errorf("error executing external parser: %v", err)
 	}
 
+	todos := ParsePluginOutput(filePath, output, matcher)
+
+	// Fill in scopes the plugin didn't report using the built-in structural parser
+	annotateScopes(filePath, todos)
+
+	return todos, nil
+}
+
+// ParsePluginOutput turns the output of an external parser into TODOs. Each
+// line has the form "<line>[-<end>]: <comment> [in function <name>]"; lines
+// that don't follow it are skipped.
+func ParsePluginOutput(filePath string, output []byte, matcher *keywords.Matcher) []store.Todo {
 	var todos []store.Todo
 	lines := strings.Split(string(output), "\n")
 	for _, line := range lines {
@@ -48,7 +71,8 @@ func (ep *ExternalParser) ParseFile(filePath string) ([]store.Todo, error) {
 			continue
 		}
 
-		lineNumber := parseLineNumber(parts[0])
+		// The line may be a range ("12-14") for TODOs spanning several lines
+		lineNumber, endLine := parseLineRange(parts[0])
 		commentText := strings.TrimSpace(parts[1])
 
 		// Extract function information if available
@@ -62,17 +86,40 @@ func (ep *ExternalParser) ParseFile(filePath string) ([]store.Todo, error) {
 			functionName = functionMatch[2]
 		}
 
+		// Line breaks of multi-line comments are escaped as \n
+		commentText = commentUnescaper.Replace(commentText)
+
 		todo := store.Todo{
 			Comment:    commentText,
 			FilePath:   filePath,
 			LineNumber: lineNumber,
 			Function:   functionName,
 		}
+		if endLine > lineNumber {
+			todo.EndLine = endLine
+		}
+		if kw, ok := matcher.Match(commentText); ok {
+			todo.Keyword = kw.Name
+			todo.Severity = kw.Severity
+		}
 
 		todos = append(todos, todo)
 	}
 
-	return todos, nil
+	return todos
+}
+
+// commentUnescaper restores line breaks and backslashes escaped by plugins
+var commentUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+
+// parseLineRange parses "12" or "12-14" into a start and end line
+func parseLineRange(field string) (int, int) {
+	start, end, found := strings.Cut(strings.TrimSpace(field), "-")
+	if !found {
+		n := parseLineNumber(start)
+		return n, n
+	}
+	return parseLineNumber(start), parseLineNumber(end)
 }
 
 // parseLineNumber converts a string to an integer safely
@@ -82,13 +129,27 @@ func parseLineNumber(line string) int {
 	return num
 }
 
-// NewManager creates a manager with all available parsers
-func NewManager(configPath string) (*Manager, error) {
-	// Initialize with built-in parsers
+// NewBuiltinManager creates a manager with the built-in parsers and those
+// compiled in through ttapi only. The plugins registered on the machine are
+// not loaded, so the files parsed do not depend on where tt runs.
+func NewBuiltinManager() *Manager {
 	manager := &Manager{
 		Parsers: []Scanner{&GoParser{}},
 	}
 
+	// Parsers compiled in through ttapi take precedence over external plugins.
+	// Each manager has its own, so managers with different keywords don't
+	// share a parser.
+	for _, reg := range ttapi.Registered() {
+		manager.Parsers = append(manager.Parsers, &CompiledParser{Name: reg.Name, Scanner: reg.New()})
+	}
+	return manager
+}
+
+// NewManager creates a manager with all available parsers
+func NewManager(configPath string) (*Manager, error) {
+	manager := NewBuiltinManager()
+
 	// Load plugin configurations
 	pluginMgr, err := plugin.NewPluginManager()
 	if err != nil {
@@ -111,6 +172,38 @@ func NewManager(configPath string) (*Manager, error) {
 	return manager, nil
 }
 
+// SetKeywords configures every parser to match the keywords in cfg
+func (m *Manager) SetKeywords(cfg keywords.Config) error {
+	matcher, err := keywords.NewMatcher(cfg)
+	if err != nil {
+		return err
+	}
+	m.matcher = matcher
+
+	for _, parser := range m.Parsers {
+		switch p := parser.(type) {
+		case *GoParser:
+			p.Matcher = matcher
+		case *ExternalParser:
+			p.Matcher = matcher
+		case *CompiledParser:
+			if err := p.setMatcher(matcher); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetQuiet stops the built-in parsers from printing their progress
+func (m *Manager) SetQuiet(quiet bool) {
+	for _, parser := range m.Parsers {
+		if p, ok := parser.(*GoParser); ok {
+			p.Quiet = quiet
+		}
+	}
+}
+
 // GetParser selects an appropriate parser based on file extension
 func (m *Manager) GetParser(path string) (Scanner, error) {
 	ext := strings.ToLower(filepath.Ext(path))
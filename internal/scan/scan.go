@@ -2,10 +2,12 @@ package scan
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 
+	"Ttracker/internal/config"
 	"Ttracker/internal/ignore"
 	"Ttracker/internal/store"
 )
@@ -19,6 +21,53 @@ func RunScan(projectPath, projectName, pluginConfigPath, storeFile string) error
 
 	fmt.Printf("Starting scan for project '%s' at path: %s\n", projectName, projectPath)
 
+	currentTodos, err := FindTodos(projectPath, projectName, pluginConfigPath)
+	if err != nil {
+		return err
+	}
+	return SaveTodos(projectName, currentTodos, storeFile)
+}
+
+// FindTodos scans a project with its configured keywords, ignore rules and
+// context settings. It neither reads nor writes the store.
+func FindTodos(projectPath, projectName, pluginConfigPath string) ([]store.Todo, error) {
+	// Create manager
+	mgr, err := NewManager(pluginConfigPath)
+	if err != nil {
+		log.Printf("Warning: %v\n", err)
+		// Continue with available parsers
+	}
+
+	// Match the keywords configured for this project
+	useGit := true
+	var snippets config.ContextSettings
+	if cfg, err := config.LoadConfig(); err == nil {
+		if err := mgr.SetKeywords(cfg.KeywordsFor(projectName)); err != nil {
+			log.Printf("Warning: invalid keyword configuration, using defaults: %v\n", err)
+		}
+		useGit = cfg.UseGitIgnore(projectName)
+		snippets = cfg.ContextFor(projectName)
+	}
+
+	// Load the default patterns and the project's ignore files, nested ones
+	// are read during the walk
+	ignoreMgr, err := ignore.NewProjectIgnoreManager(projectPath, useGit)
+	if err != nil {
+		log.Printf("Warning: Failed to load ignore file: %v\n", err)
+	}
+
+	currentTodos, fileCount, err := ScanDir(projectPath, mgr, ignoreMgr, ScanOptions{Context: snippets, Log: os.Stdout})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Scan complete. Found %d TODOs in %d files for project '%s'\n",
+		len(currentTodos), fileCount, projectName)
+	return currentTodos, nil
+}
+
+// SaveTodos replaces the TODOs of a project in the store
+func SaveTodos(projectName string, todos []store.Todo, storeFile string) error {
 	// Ensure the store file's directory exists
 	storeDir := filepath.Dir(storeFile)
 	if err := os.MkdirAll(storeDir, 0755); err != nil {
@@ -39,44 +88,53 @@ func RunScan(projectPath, projectName, pluginConfigPath, storeFile string) error
 		}
 	}
 
-	// Create a new collection to hold current TODOs
-	currentTodos := make([]store.Todo, 0)
-
-	// Create manager
-	mgr, err := NewManager(pluginConfigPath)
-	if err != nil {
-		log.Printf("Warning: %v\n", err)
-		// Continue with available parsers
+	// If the store exists and the project has TODOs, we need to update it
+	if storeExists {
+		// Update existing project or add new one
+		st.UpdateProject(projectName, todos)
+	} else {
+		// Create new project entry
+		st.AddProject(projectName, todos)
 	}
 
-	// Initialize ignore manager with default patterns
-	ignoreMgr := ignore.NewIgnoreManager()
-	for _, pattern := range ignore.GetDefaultPatterns() {
-		ignoreMgr.AddPattern(pattern.Pattern, pattern.IsDir)
+	// Save the updated store
+	if err := st.Save(storeFile); err != nil {
+		return fmt.Errorf("failed to save store: %v", err)
 	}
 
-	// Try to load project-specific ignore file
-	ignoreFile := filepath.Join(projectPath, ".ttignore")
-	if err := ignoreMgr.LoadFromFile(ignoreFile); err != nil {
-		// Not an error if file doesn't exist
-		if !os.IsNotExist(err) {
-			log.Printf("Warning: Failed to load ignore file: %v\n", err)
+	return nil
+}
+
+// ScanOptions tunes ScanDir
+type ScanOptions struct {
+	Context config.ContextSettings // the source stored with each TODO
+	Log     io.Writer              // where progress is written, nowhere when nil
+	// Fallback reads files no parser handles with the comment syntax of
+	// their language, see ParseFragments
+	Fallback bool
+}
+
+// ScanDir finds the TODOs in the files below root that ignoreMgr does not
+// ignore. It neither reads nor writes the store. It returns the TODOs found
+// and the number of files scanned, which leaves out files no parser read.
+func ScanDir(root string, mgr *Manager, ignoreMgr *ignore.IgnoreManager, opts ScanOptions) ([]store.Todo, int, error) {
+	logf := func(format string, args ...interface{}) {
+		if opts.Log != nil {
+			fmt.Fprintf(opts.Log, format, args...)
 		}
 	}
+	logf("Walking directory: %s\n", root)
 
-	fmt.Printf("Walking directory: %s\n", projectPath)
-
-	todoCount := 0
+	todos := make([]store.Todo, 0)
 	fileCount := 0
-
-	err = filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			fmt.Printf("Error accessing %s: %v\n", path, err)
+			logf("Error accessing %s: %v\n", path, err)
 			return nil // continue walking
 		}
 
 		// Check if path should be ignored
-		if ignoreMgr.ShouldIgnore(path) {
+		if ignoreMgr.IsIgnored(path, info.IsDir()) {
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
@@ -93,52 +151,50 @@ func RunScan(projectPath, projectName, pluginConfigPath, storeFile string) error
 			return nil
 		}
 
-		fileCount++
-
 		parser, err := mgr.GetParser(path)
 		if err != nil {
 			// Not an error, just means we don't have a parser for this file type
+			if !opts.Fallback || !KnowsComments(path) {
+				return nil
+			}
+			parser = commentParser{mgr}
+		}
+
+		// The version of the file is recorded with its TODOs, so readers
+		// can tell when it changed since the scan
+		stat, err := os.Stat(path)
+		if err != nil {
+			logf("Error accessing %s: %v\n", path, err)
 			return nil
 		}
 
-		fmt.Printf("Parsing file: %s\n", path)
-		todos, err := parser.ParseFile(path)
+		logf("Parsing file: %s\n", path)
+		found, err := parser.ParseFile(path)
+		if err != nil && opts.Fallback && KnowsComments(path) {
+			logf("Error parsing %s, reading its comments only: %v\n", path, err)
+			found, err = commentParser{mgr}.ParseFile(path)
+		}
 		if err != nil {
-			fmt.Printf("Error parsing %s: %v\n", path, err)
+			logf("Error parsing %s: %v\n", path, err)
 			return nil
 		}
+		fileCount++
 
-		if len(todos) > 0 {
-			fmt.Printf("Found %d TODOs in %s\n", len(todos), path)
-			todoCount += len(todos)
+		if len(found) > 0 {
+			logf("Found %d TODOs in %s\n", len(found), path)
+			for i := range found {
+				found[i].ModTime = stat.ModTime()
+			}
+			AddContext(path, found, opts.Context)
 		}
 
 		// Add the found TODOs to our current collection
-		currentTodos = append(currentTodos, todos...)
+		todos = append(todos, found...)
 
 		return nil
 	})
-
 	if err != nil {
-		return fmt.Errorf("error walking directory: %v", err)
+		return nil, fileCount, fmt.Errorf("error walking directory: %v", err)
 	}
-
-	fmt.Printf("Scan complete. Found %d TODOs in %d files for project '%s'\n",
-		todoCount, fileCount, projectName)
-
-	// If the store exists and the project has TODOs, we need to update it
-	if storeExists {
-		// Update existing project or add new one
-		st.UpdateProject(projectName, currentTodos)
-	} else {
-		// Create new project entry
-		st.AddProject(projectName, currentTodos)
-	}
-
-	// Save the updated store
-	if err := st.Save(storeFile); err != nil {
-		return fmt.Errorf("failed to save store: %v", err)
-	}
-
-	return nil
+	return todos, fileCount, nil
 }
@@ -0,0 +1,779 @@
+package scan
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"Ttracker/internal/store"
+)
+
+// scopeStyle describes how a language delimits the body of a scope
+type scopeStyle int
+
+const (
+	braceScopes  scopeStyle = iota // bodies are wrapped in { }
+	indentScopes                   // bodies end when the indentation drops back
+	endScopes                      // bodies end with an `end` at the header's indentation
+)
+
+// headerRule recognises the line that opens a scope
+type headerRule struct {
+	kind string
+	re   *regexp.Regexp
+	// nameGroup is the submatch holding the scope name
+	nameGroup int
+	// inClassOnly restricts the rule to lines directly inside a class-like scope
+	inClassOnly bool
+}
+
+// scopeLang holds everything the structural parser needs to know about a language
+type scopeLang struct {
+	style        scopeStyle
+	lineComments []string
+	blockComment [2]string
+	quotes       []quoteRule // string delimiters, longer ones first
+	headers      []headerRule
+}
+
+// quoteRule describes a kind of string literal
+type quoteRule struct {
+	open, close string
+	multiline   bool // the string may span lines without escaping the newlines
+}
+
+var (
+	doubleQuoted = quoteRule{open: `"`, close: `"`}
+	singleQuoted = quoteRule{open: `'`, close: `'`}
+	backQuoted   = quoteRule{open: "`", close: "`"}
+	tripleQuoted = quoteRule{open: `"""`, close: `"""`, multiline: true}
+)
+
+// spanning returns q for a language whose strings of this kind may span lines
+func (q quoteRule) spanning() quoteRule {
+	q.multiline = true
+	return q
+}
+
+// Words that look like calls or declarations in C-like code but never open a named scope
+var controlKeywords = map[string]bool{
+	"if": true, "else": true, "for": true, "foreach": true, "while": true, "do": true,
+	"switch": true, "case": true, "catch": true, "try": true, "finally": true,
+	"return": true, "new": true, "throw": true, "delete": true, "sizeof": true,
+	"await": true, "yield": true, "using": true, "lock": true, "synchronized": true,
+	"with": true, "when": true, "match": true, "loop": true, "defer": true, "elif": true,
+}
+
+var classHeader = headerRule{
+	kind:      "class",
+	re:        regexp.MustCompile(`\b(class|struct|interface|enum|trait|object|record|namespace|module|protocol|extension)\s+([A-Za-z_$][\w$]*)`),
+	nameGroup: 2,
+}
+
+var (
+	cLikeFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`^\s*(?:[\w<>\[\],.*&:~?]+\s+)+\**&?([~\w:]+)\s*\([^;]*$`),
+		nameGroup: 1,
+	}
+	jsFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`\bfunction\s*\*?\s*([A-Za-z_$][\w$]*)\s*\(`),
+		nameGroup: 1,
+	}
+	jsArrowFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`\b(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`),
+		nameGroup: 1,
+	}
+	jsMethod = headerRule{
+		kind:        "method",
+		re:          regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|async|get|set|readonly|override|abstract)\s+)*\*?#?([A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\s*\([^;]*$`),
+		nameGroup:   1,
+		inClassOnly: true,
+	}
+	rustImpl = headerRule{
+		kind:      "impl",
+		re:        regexp.MustCompile(`\bimpl(?:\s*<[^{]*?>)?\s+(?:[\w:]+(?:<[^{]*?>)?\s+for\s+)?([\w:]+)`),
+		nameGroup: 1,
+	}
+	rustFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`\bfn\s+(\w+)`),
+		nameGroup: 1,
+	}
+	kotlinFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`\bfun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(\w+)\s*\(`),
+		nameGroup: 1,
+	}
+	swiftFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`\b(?:func\s+(\w+)|(init)\s*[?!]?\s*\()`),
+		nameGroup: 1,
+	}
+	phpFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`\bfunction\s+&?(\w+)\s*\(`),
+		nameGroup: 1,
+	}
+	scalaFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`\bdef\s+(\w+)`),
+		nameGroup: 1,
+	}
+	shellFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`^\s*(?:function\s+([\w.:-]+)|([\w.:-]+)\s*\(\s*\))`),
+		nameGroup: 1,
+	}
+	pythonClass = headerRule{
+		kind:      "class",
+		re:        regexp.MustCompile(`^\s*class\s+(\w+)`),
+		nameGroup: 1,
+	}
+	pythonFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`^\s*(?:async\s+)?def\s+(\w+)`),
+		nameGroup: 1,
+	}
+	rubyClass = headerRule{
+		kind:      "class",
+		re:        regexp.MustCompile(`^\s*(class|module)\s+([\w:]+)`),
+		nameGroup: 2,
+	}
+	rubyFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`^\s*def\s+(?:self\.)?([\w?!=]+)`),
+		nameGroup: 1,
+	}
+	luaFunction = headerRule{
+		kind:      "function",
+		re:        regexp.MustCompile(`^\s*(?:local\s+)?function\s+([\w.:]+)`),
+		nameGroup: 1,
+	}
+)
+
+var (
+	cLang = &scopeLang{
+		style:        braceScopes,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       []quoteRule{tripleQuoted, doubleQuoted, singleQuoted, backQuoted},
+		headers:      []headerRule{classHeader, cLikeFunction},
+	}
+	jsLang = &scopeLang{
+		style:        braceScopes,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       []quoteRule{backQuoted.spanning(), doubleQuoted, singleQuoted},
+		headers:      []headerRule{classHeader, jsFunction, jsArrowFunction, jsMethod},
+	}
+	rustLang = &scopeLang{
+		style:        braceScopes,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       []quoteRule{doubleQuoted.spanning()},
+		headers:      []headerRule{classHeader, rustImpl, rustFunction},
+	}
+	kotlinLang = &scopeLang{
+		style:        braceScopes,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       []quoteRule{tripleQuoted, doubleQuoted, singleQuoted, backQuoted},
+		headers:      []headerRule{classHeader, kotlinFunction},
+	}
+	swiftLang = &scopeLang{
+		style:        braceScopes,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       []quoteRule{tripleQuoted, doubleQuoted, singleQuoted},
+		headers:      []headerRule{classHeader, swiftFunction},
+	}
+	phpLang = &scopeLang{
+		style:        braceScopes,
+		lineComments: []string{"//", "#"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       []quoteRule{doubleQuoted.spanning(), singleQuoted.spanning(), backQuoted.spanning()},
+		headers:      []headerRule{classHeader, phpFunction},
+	}
+	scalaLang = &scopeLang{
+		style:        braceScopes,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       []quoteRule{tripleQuoted, doubleQuoted, singleQuoted, backQuoted},
+		headers:      []headerRule{classHeader, scalaFunction},
+	}
+	shellLang = &scopeLang{
+		style:        braceScopes,
+		lineComments: []string{"#"},
+		quotes:       []quoteRule{doubleQuoted.spanning(), singleQuoted.spanning(), backQuoted.spanning()},
+		headers:      []headerRule{shellFunction},
+	}
+	pythonLang = &scopeLang{
+		style:        indentScopes,
+		lineComments: []string{"#"},
+		quotes:       []quoteRule{tripleQuoted, {open: `'''`, close: `'''`, multiline: true}, doubleQuoted, singleQuoted},
+		headers:      []headerRule{pythonClass, pythonFunction},
+	}
+	rubyLang = &scopeLang{
+		style:        endScopes,
+		lineComments: []string{"#"},
+		quotes:       []quoteRule{doubleQuoted.spanning(), singleQuoted.spanning(), backQuoted.spanning()},
+		headers:      []headerRule{rubyClass, rubyFunction},
+	}
+	luaLang = &scopeLang{
+		style:        endScopes,
+		lineComments: []string{"--"},
+		quotes:       []quoteRule{{open: "[[", close: "]]", multiline: true}, doubleQuoted, singleQuoted},
+		headers:      []headerRule{luaFunction},
+	}
+)
+
+// scopeLangs maps file extensions to the structural rules used for them
+var scopeLangs = map[string]*scopeLang{
+	".c": cLang, ".h": cLang, ".cc": cLang, ".cpp": cLang, ".cxx": cLang, ".hpp": cLang,
+	".java": cLang, ".cs": cLang, ".m": cLang,
+	".js": jsLang, ".jsx": jsLang, ".mjs": jsLang, ".cjs": jsLang, ".ts": jsLang, ".tsx": jsLang,
+	".rs":    rustLang,
+	".kt":    kotlinLang,
+	".kts":   kotlinLang,
+	".swift": swiftLang,
+	".php":   phpLang,
+	".scala": scalaLang,
+	".sh":    shellLang, ".bash": shellLang, ".zsh": shellLang,
+	".py":  pythonLang,
+	".rb":  rubyLang,
+	".lua": luaLang,
+}
+
+// isClassKind reports whether scopes of this kind hold methods rather than functions
+func isClassKind(kind string) bool {
+	switch kind {
+	case "class", "struct", "interface", "enum", "trait", "object", "record", "impl", "protocol", "extension", "module":
+		return true
+	}
+	return false
+}
+
+// SupportsScopes reports whether the structural parser knows the file's language
+func SupportsScopes(filePath string) bool {
+	_, ok := scopeLangs[strings.ToLower(filepath.Ext(filePath))]
+	return ok
+}
+
+// DetectScopes returns every class, method, function and impl block found in
+// content, ordered by start line. Detection is best effort: it relies on
+// common formatting conventions rather than a full grammar.
+func DetectScopes(filePath string, content []byte) []store.Scope {
+	lang, ok := scopeLangs[strings.ToLower(filepath.Ext(filePath))]
+	if !ok {
+		return nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	code, continued := stripComments(lines, lang)
+
+	switch lang.style {
+	case indentScopes, endScopes:
+		return detectIndentScopes(lines, code, continued, lang)
+	default:
+		return detectBraceScopes(code, lang)
+	}
+}
+
+// EnclosingScope returns the innermost scope containing line, or nil
+func EnclosingScope(scopes []store.Scope, line int) *store.Scope {
+	var best *store.Scope
+	for i := range scopes {
+		s := &scopes[i]
+		if s.StartLine <= line && line <= s.EndLine {
+			if best == nil || s.StartLine > best.StartLine ||
+				(s.StartLine == best.StartLine && s.EndLine <= best.EndLine) {
+				best = s
+			}
+		}
+	}
+	return best
+}
+
+// annotateScopes fills in the scope of todos that don't have one yet and uses
+// the innermost function or method as Function when a parser didn't report it
+func annotateScopes(filePath string, todos []store.Todo) {
+	if len(todos) == 0 || !SupportsScopes(filePath) {
+		return
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return
+	}
+	scopes := DetectScopes(filePath, content)
+	if len(scopes) == 0 {
+		return
+	}
+
+	for i := range todos {
+		if todos[i].Scope != nil {
+			continue
+		}
+		scope := EnclosingScope(scopes, todos[i].LineNumber)
+		if scope == nil {
+			continue
+		}
+		s := *scope
+		todos[i].Scope = &s
+		if todos[i].Function == "" {
+			todos[i].Function = EnclosingFunction(scopes, todos[i].LineNumber)
+		}
+	}
+}
+
+// EnclosingFunction returns the qualified path of the innermost function, method or closure around line
+func EnclosingFunction(scopes []store.Scope, line int) string {
+	var best *store.Scope
+	for i := range scopes {
+		s := &scopes[i]
+		if s.Kind != "function" && s.Kind != "method" && s.Kind != "closure" {
+			continue
+		}
+		if s.StartLine <= line && line <= s.EndLine && (best == nil || s.StartLine >= best.StartLine) {
+			best = s
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.Path
+}
+
+// stripComments blanks out comments and string literals so that braces and
+// keywords inside them don't confuse scope detection. Only the delimiters of
+// strings are kept. Block comments and strings spanning lines carry over to
+// the next line; continued reports the lines that start inside a string.
+func stripComments(lines []string, lang *scopeLang) (code []string, continued []bool) {
+	code = make([]string, len(lines))
+	continued = make([]bool, len(lines))
+	inBlock := false
+	var quote *quoteRule
+
+	for i, line := range lines {
+		continued[i] = quote != nil
+		escapedEOL := false
+
+		var b strings.Builder
+		for j := 0; j < len(line); j++ {
+			rest := line[j:]
+
+			if inBlock {
+				if end := lang.blockComment[1]; end != "" && strings.HasPrefix(rest, end) {
+					inBlock = false
+					j += len(end) - 1
+				}
+				continue
+			}
+
+			if quote != nil {
+				if line[j] == '\\' {
+					j++
+					escapedEOL = j >= len(line)
+				} else if strings.HasPrefix(rest, quote.close) {
+					b.WriteString(quote.close)
+					j += len(quote.close) - 1
+					quote = nil
+				}
+				continue
+			}
+
+			if start := lang.blockComment[0]; start != "" && strings.HasPrefix(rest, start) {
+				inBlock = true
+				j += len(start) - 1
+				continue
+			}
+
+			isLineComment := false
+			for _, marker := range lang.lineComments {
+				if strings.HasPrefix(rest, marker) {
+					isLineComment = true
+					break
+				}
+			}
+			if isLineComment {
+				break
+			}
+
+			// Rust lifetimes use a lone quote, only char literals are quoted
+			if lang == rustLang && line[j] == '\'' {
+				if n := rustCharLiteral(rest); n > 0 {
+					b.WriteString("' '")
+					j += n - 1
+					continue
+				}
+			}
+
+			opened := false
+			for k := range lang.quotes {
+				if q := &lang.quotes[k]; strings.HasPrefix(rest, q.open) {
+					quote = q
+					b.WriteString(q.open)
+					j += len(q.open) - 1
+					opened = true
+					break
+				}
+			}
+			if !opened {
+				b.WriteByte(line[j])
+			}
+		}
+		code[i] = b.String()
+
+		// A string that cannot span lines ends with its line unless the
+		// newline is escaped
+		if quote != nil && !quote.multiline && !escapedEOL {
+			quote = nil
+		}
+	}
+	return code, continued
+}
+
+// rustCharLiteral returns the length of the Rust char literal s starts with,
+// e.g. '{', '\n' or '\u{7D}', and 0 when its quote starts a lifetime such as 'a
+func rustCharLiteral(s string) int {
+	if len(s) < 3 {
+		return 0
+	}
+	if s[1] == '\\' {
+		// The escaped character is never the closing quote
+		if end := strings.IndexByte(s[3:], '\''); end >= 0 && end < 10 {
+			return end + 4
+		}
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(s[1:])
+	if 1+size < len(s) && s[1+size] == '\'' {
+		return size + 2
+	}
+	return 0
+}
+
+// matchHeader returns the first header rule matching line and the scope name it declares
+func matchHeader(line string, lang *scopeLang, inClass bool) (string, string, bool) {
+	for _, rule := range lang.headers {
+		if rule.inClassOnly && !inClass {
+			continue
+		}
+		m := rule.re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		name := ""
+		for g := rule.nameGroup; g < len(m) && name == ""; g++ {
+			name = m[g]
+		}
+		if name == "" || controlKeywords[name] {
+			continue
+		}
+		if rule.re == cLikeFunction.re && startsWithControlWord(line) {
+			continue
+		}
+
+		kind := rule.kind
+		if rule.re == classHeader.re || rule.re == rubyClass.re {
+			kind = m[1]
+		}
+		return kind, name, true
+	}
+	return "", "", false
+}
+
+// startsWithControlWord reports whether a C-like line begins with a statement keyword
+func startsWithControlWord(line string) bool {
+	fields := strings.Fields(strings.TrimLeft(line, "}"))
+	return len(fields) > 0 && controlKeywords[fields[0]]
+}
+
+// qualify splits a possibly qualified name into its own name and the path segments it adds
+func qualify(parent, name string) (string, string) {
+	name = strings.NewReplacer("::", ".", ":", ".").Replace(name)
+	short := name
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		short = name[idx+1:]
+	}
+	if parent == "" {
+		return short, name
+	}
+	return short, parent + "." + name
+}
+
+// maxHeaderLines is how far a brace may follow the header that owns it
+const maxHeaderLines = 4
+
+var (
+	// cDeclarator matches the name and parameters of a C function whose
+	// return type is on the line before, as in the GNU and K&R styles
+	cDeclarator = regexp.MustCompile(`^\s*\**&?([A-Za-z_~][\w:~]*)\s*\([^;]*$`)
+	// cReturnType matches a line holding only a return type, e.g. "static int"
+	cReturnType = regexp.MustCompile(`^\s*[A-Za-z_][\w:<>,\s*&]*$`)
+)
+
+// matchSplitHeader recognises a C function header whose return type is on
+// prev, the line before line
+func matchSplitHeader(prev, line string) (string, bool) {
+	m := cDeclarator.FindStringSubmatch(line)
+	if m == nil || controlKeywords[m[1]] || !cReturnType.MatchString(prev) {
+		return "", false
+	}
+	// Labels such as public: are not types
+	if strings.HasSuffix(strings.TrimSpace(prev), ":") || startsWithControlWord(prev) {
+		return "", false
+	}
+	return m[1], true
+}
+
+// braceFrame is an open { on the stack, optionally owning a scope
+type braceFrame struct {
+	scope int // index into scopes, or -1 for anonymous blocks
+}
+
+func detectBraceScopes(code []string, lang *scopeLang) []store.Scope {
+	var scopes []store.Scope
+	var stack []braceFrame
+
+	type pendingHeader struct {
+		kind, name string
+		line       int
+		last       int  // last line of the header so far
+		params     int  // line closing the parameter list, 0 while it is open
+		topLevel   bool // the header is outside every function and class
+	}
+	var pending *pendingHeader
+	parenDepth := 0
+	prev := "" // the previous line when it holds code
+
+	// currentScope returns the innermost open named scope
+	currentScope := func() int {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].scope >= 0 {
+				return stack[i].scope
+			}
+		}
+		return -1
+	}
+	// inClassBody reports whether the innermost open brace belongs to a class-like scope
+	inClassBody := func() bool {
+		if len(stack) == 0 {
+			return false
+		}
+		top := stack[len(stack)-1].scope
+		return top >= 0 && isClassKind(scopes[top].Kind)
+	}
+
+	for i, line := range code {
+		lineNo := i + 1
+		// A header that hasn't opened a body within a few lines was a
+		// declaration or an expression-bodied function
+		if pending != nil && lineNo-pending.last > maxHeaderLines {
+			pending = nil
+		}
+		if kind, name, ok := matchHeader(line, lang, inClassBody()); ok {
+			pending = &pendingHeader{kind: kind, name: name, line: lineNo, last: lineNo, topLevel: len(stack) == 0}
+			parenDepth = 0
+		} else if lang == cLang && (len(stack) == 0 || inClassBody()) {
+			// The return type of a GNU or K&R style definition is on a line
+			// of its own, the scope starts there
+			if name, ok := matchSplitHeader(prev, line); ok {
+				pending = &pendingHeader{kind: "function", name: name, line: lineNo - 1, last: lineNo, topLevel: len(stack) == 0}
+				parenDepth = 0
+			}
+		}
+
+		for j := 0; j < len(line); j++ {
+			switch line[j] {
+			case '(':
+				parenDepth++
+			case ')':
+				if parenDepth > 0 {
+					parenDepth--
+				}
+				if parenDepth == 0 && pending != nil && pending.params == 0 {
+					pending.params = lineNo
+				}
+			case ';':
+				if parenDepth != 0 || pending == nil {
+					continue
+				}
+				// K&R definitions declare their parameters between the
+				// parameter list and the body
+				if lang == cLang && pending.topLevel && pending.params > 0 && pending.params < lineNo {
+					pending.last = lineNo
+					continue
+				}
+				pending = nil
+			case '{':
+				frame := braceFrame{scope: -1}
+				if pending != nil {
+					parentPath := ""
+					parentIsClass := inClassBody()
+					if p := currentScope(); p >= 0 {
+						parentPath = scopes[p].Path
+					}
+					name, path := qualify(parentPath, pending.name)
+					kind := pending.kind
+					if kind == "function" && (parentIsClass || strings.Contains(pending.name, "::")) {
+						kind = "method"
+					}
+					scopes = append(scopes, store.Scope{
+						Kind:      kind,
+						Name:      name,
+						Path:      path,
+						StartLine: pending.line,
+						EndLine:   lineNo,
+					})
+					frame.scope = len(scopes) - 1
+					pending = nil
+				}
+				stack = append(stack, frame)
+			case '}':
+				if len(stack) == 0 {
+					continue
+				}
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if top.scope >= 0 {
+					scopes[top.scope].EndLine = lineNo
+				}
+			}
+		}
+
+		if strings.TrimSpace(line) != "" {
+			prev = line
+		} else {
+			prev = ""
+		}
+	}
+
+	// Anything left open runs to the end of the file
+	for _, frame := range stack {
+		if frame.scope >= 0 {
+			scopes[frame.scope].EndLine = len(code)
+		}
+	}
+	return scopes
+}
+
+// indentOf returns the width of a line's leading whitespace, counting tabs as 4
+func indentOf(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+var endKeyword = regexp.MustCompile(`^\s*end\b`)
+
+func detectIndentScopes(lines, code []string, continued []bool, lang *scopeLang) []store.Scope {
+	var scopes []store.Scope
+
+	type openScope struct {
+		index  int
+		indent int
+	}
+	var stack []openScope
+	parenDepth := 0
+
+	// extend marks line as part of every open scope indented less than indent
+	extend := func(indent, line int) {
+		for _, open := range stack {
+			if open.indent < indent {
+				scopes[open.index].EndLine = line
+			}
+		}
+	}
+
+	// countBrackets tracks the brackets left open at the end of line
+	countBrackets := func(line string) {
+		parenDepth += strings.Count(line, "(") + strings.Count(line, "[") + strings.Count(line, "{")
+		parenDepth -= strings.Count(line, ")") + strings.Count(line, "]") + strings.Count(line, "}")
+		if parenDepth < 0 {
+			parenDepth = 0
+		}
+	}
+
+	for i, line := range code {
+		lineNo := i + 1
+		if continued[i] {
+			// The lines of a string belong to every scope it is in, whatever
+			// their indentation
+			extend(math.MaxInt, lineNo)
+			countBrackets(line)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			// Comment lines belong to the bodies they are indented into
+			if strings.TrimSpace(lines[i]) != "" {
+				extend(indentOf(lines[i]), lineNo)
+			}
+			continue
+		}
+		indent := indentOf(line)
+
+		if parenDepth == 0 {
+			if lang.style == endScopes && endKeyword.MatchString(line) {
+				if len(stack) > 0 && stack[len(stack)-1].indent == indent {
+					scopes[stack[len(stack)-1].index].EndLine = lineNo
+					stack = stack[:len(stack)-1]
+				}
+				extend(indent, lineNo)
+				continue
+			}
+			if lang.style == indentScopes {
+				for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+
+		if parenDepth > 0 {
+			// Continuation lines of a bracketed expression or signature
+			extend(len(line)+1, lineNo)
+		} else {
+			extend(indent, lineNo)
+		}
+
+		inClass := len(stack) > 0 && isClassKind(scopes[stack[len(stack)-1].index].Kind)
+		if kind, name, ok := matchHeader(line, lang, inClass); ok && parenDepth == 0 {
+			parentPath := ""
+			if len(stack) > 0 {
+				parentPath = scopes[stack[len(stack)-1].index].Path
+			}
+			short, path := qualify(parentPath, name)
+			if kind == "function" && (inClass || strings.ContainsAny(name, ".:")) {
+				kind = "method"
+			}
+			scopes = append(scopes, store.Scope{
+				Kind:      kind,
+				Name:      short,
+				Path:      path,
+				StartLine: lineNo,
+				EndLine:   lineNo,
+			})
+			stack = append(stack, openScope{index: len(scopes) - 1, indent: indent})
+		}
+
+		countBrackets(line)
+	}
+
+	return scopes
+}
@@ -0,0 +1,290 @@
+package scan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"Ttracker/internal/store"
+)
+
+// formatScopes describes scopes as "kind path start-end"
+func formatScopes(scopes []store.Scope) []string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = fmt.Sprintf("%s %s %d-%d", s.Kind, s.Path, s.StartLine, s.EndLine)
+	}
+	return out
+}
+
+func TestDetectScopes(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		src  string
+		want []string
+	}{
+		{
+			name: "c functions and prototypes",
+			file: "a.c",
+			src: `#include <stdio.h>
+
+int helper(int x);
+
+int helper(int x) {
+    if (x) {
+        return 1;
+    }
+    return 0;
+}
+`,
+			want: []string{"function helper 5-10"},
+		},
+		{
+			name: "gnu style definition",
+			file: "a.c",
+			src: `static int
+main (int argc, char **argv)
+{
+    /* { in a comment */
+    puts("}");
+    return 0;
+}
+`,
+			want: []string{"function main 1-7"},
+		},
+		{
+			name: "k&r definition",
+			file: "a.c",
+			src: `int
+add(a, b)
+    int a;
+    int b;
+{
+    return a + b;
+}
+
+int
+prototype(void);
+`,
+			want: []string{"function add 1-7"},
+		},
+		{
+			name: "java class and methods",
+			file: "A.java",
+			src: `public class A {
+    private int n;
+
+    public A(int n) {
+        this.n = n;
+    }
+
+    public int get() {
+        return n;
+    }
+}
+`,
+			want: []string{"class A 1-11", "method A.A 4-6", "method A.get 8-10"},
+		},
+		{
+			name: "javascript functions and template literals",
+			file: "a.js",
+			src: "function render(x) {\n" +
+				"  const html = `\n" +
+				"}\n" +
+				"${x}`;\n" +
+				"  return html;\n" +
+				"}\n" +
+				"\n" +
+				"const add = (a, b) => {\n" +
+				"  return a + b;\n" +
+				"};\n" +
+				"\n" +
+				"class Widget {\n" +
+				"  draw() {\n" +
+				"    return '{';\n" +
+				"  }\n" +
+				"}\n",
+			want: []string{"function render 1-6", "function add 8-10", "class Widget 12-16", "method Widget.draw 13-15"},
+		},
+		{
+			name: "rust impl blocks, char literals and lifetimes",
+			file: "a.rs",
+			src: `struct Parser<'a> {
+    src: &'a str,
+}
+
+impl<'a> Parser<'a> {
+    fn open(&self) -> char {
+        '{'
+    }
+
+    fn text(&self) -> &'a str {
+        let s = "}
+";
+        s
+    }
+}
+`,
+			want: []string{"struct Parser 1-3", "impl Parser 5-15", "method Parser.open 6-8", "method Parser.text 10-14"},
+		},
+		{
+			name: "kotlin raw strings",
+			file: "a.kt",
+			src: `class Greeter {
+    fun greet(): String {
+        return """
+}
+"""
+    }
+}
+`,
+			want: []string{"class Greeter 1-7", "method Greeter.greet 2-6"},
+		},
+		{
+			name: "swift",
+			file: "a.swift",
+			src: `struct Point {
+    init(x: Int) {
+    }
+
+    func norm() -> Int {
+        return 0
+    }
+}
+`,
+			want: []string{"struct Point 1-8", "method Point.init 2-3", "method Point.norm 5-7"},
+		},
+		{
+			name: "php",
+			file: "a.php",
+			src: `<?php
+class Repo {
+    public function find($id) {
+        # a { in a comment
+        return "
+}";
+    }
+}
+`,
+			want: []string{"class Repo 2-8", "method Repo.find 3-7"},
+		},
+		{
+			name: "scala",
+			file: "a.scala",
+			src: `object Main {
+  def run(): Unit = {
+    println("{")
+  }
+}
+`,
+			want: []string{"object Main 1-5", "method Main.run 2-4"},
+		},
+		{
+			name: "shell",
+			file: "a.sh",
+			src: `build() {
+  echo "{"
+  echo '
+}'
+}
+
+function clean {
+  rm -rf out
+}
+`,
+			want: []string{"function build 1-5", "function clean 7-9"},
+		},
+		{
+			name: "python classes and multi-line strings",
+			file: "a.py",
+			src: `class Job:
+    def run(self):
+        return 1
+
+
+def f():
+    doc = """
+Usage:
+  tool run
+"""
+    x = 1
+    # trailing comment
+    return x
+
+
+def g():
+    s = "one line"
+    return s
+`,
+			want: []string{"class Job 1-3", "method Job.run 2-3", "function f 6-13", "function g 16-18"},
+		},
+		{
+			name: "python strings with escaped newlines",
+			file: "a.py",
+			src: `def f():
+    s = "a \
+b"
+    return s
+`,
+			want: []string{"function f 1-4"},
+		},
+		{
+			name: "ruby",
+			file: "a.rb",
+			src: `module Tools
+  class Runner
+    def run
+      puts "end"
+    end
+
+    def self.build
+      'x'
+    end
+  end
+end
+`,
+			want: []string{"module Tools 1-11", "class Tools.Runner 2-10", "method Tools.Runner.run 3-5", "method Tools.Runner.build 7-9"},
+		},
+		{
+			name: "lua long strings",
+			file: "a.lua",
+			src: `local function render()
+  local s = [[
+end
+]]
+  return s
+end
+`,
+			want: []string{"function render 1-6"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatScopes(DetectScopes(tt.file, []byte(tt.src)))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("scopes:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+			}
+		})
+	}
+}
+
+func TestEnclosingFunction(t *testing.T) {
+	src := `class A {
+    void f() {
+        Runnable r = new Runnable() {
+            public void run() {
+                work();
+            }
+        };
+    }
+}
+`
+	scopes := DetectScopes("A.java", []byte(src))
+	for line, want := range map[int]string{1: "", 2: "A.f", 5: "A.f.run", 7: "A.f", 9: ""} {
+		if got := EnclosingFunction(scopes, line); got != want {
+			t.Errorf("EnclosingFunction(line %d) = %q, want %q", line, got, want)
+		}
+	}
+}
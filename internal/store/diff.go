@@ -0,0 +1,133 @@
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fingerprint identifies a TODO independently of its line number, so a TODO
+// keeps its fingerprint when code above it is added or removed. It is made
+// of the file, the keyword and the comment text with whitespace collapsed.
+func (t Todo) Fingerprint() string {
+	text := strings.Join(strings.Fields(t.Comment), " ")
+	sum := sha1.Sum([]byte(t.FilePath + "\x00" + strings.ToUpper(t.Keyword) + "\x00" + text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// TodoChange is a TODO that was moved or edited between two scans
+type TodoChange struct {
+	Before Todo `json:"before"`
+	After  Todo `json:"after"`
+}
+
+// TodoDiff lists the differences between two scans of a project
+type TodoDiff struct {
+	Added   []Todo       `json:"added"`
+	Removed []Todo       `json:"removed"`
+	Changed []TodoChange `json:"changed"`
+}
+
+// Empty reports whether the scans found the same TODOs
+func (d TodoDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the TODOs of a project before and after a scan. TODOs are
+// matched by fingerprint first, a matched TODO whose position or details
+// differ is changed. A TODO left unmatched on both sides at the same file and
+// line had its text edited and is changed as well. The rest were added or
+// removed.
+func Diff(before, after []Todo) TodoDiff {
+	before, after = sortedTodos(before), sortedTodos(after)
+
+	// Pair TODOs with the same fingerprint in line order, so identical
+	// comments in one file are matched one to one
+	pending := make(map[string][]int)
+	for i, todo := range before {
+		fp := todo.Fingerprint()
+		pending[fp] = append(pending[fp], i)
+	}
+
+	var diff TodoDiff
+	matched := make([]bool, len(before))
+	var unmatched []Todo
+	for _, todo := range after {
+		fp := todo.Fingerprint()
+		if len(pending[fp]) == 0 {
+			unmatched = append(unmatched, todo)
+			continue
+		}
+		i := pending[fp][0]
+		pending[fp] = pending[fp][1:]
+		matched[i] = true
+		if !sameTodo(before[i], todo) {
+			diff.Changed = append(diff.Changed, TodoChange{Before: before[i], After: todo})
+		}
+	}
+
+	// Old TODOs not found again, by position
+	removed := make(map[string]Todo)
+	var removedKeys []string
+	for i, todo := range before {
+		if !matched[i] {
+			key := positionKey(todo)
+			removed[key] = todo
+			removedKeys = append(removedKeys, key)
+		}
+	}
+
+	for _, todo := range unmatched {
+		key := positionKey(todo)
+		if old, ok := removed[key]; ok {
+			diff.Changed = append(diff.Changed, TodoChange{Before: old, After: todo})
+			delete(removed, key)
+			continue
+		}
+		diff.Added = append(diff.Added, todo)
+	}
+	for _, key := range removedKeys {
+		if todo, ok := removed[key]; ok {
+			diff.Removed = append(diff.Removed, todo)
+		}
+	}
+
+	sort.SliceStable(diff.Changed, func(i, j int) bool {
+		return todoLess(diff.Changed[i].After, diff.Changed[j].After)
+	})
+	return diff
+}
+
+// sameTodo reports whether two TODOs are alike. Their source snippets and
+// file times are left out, code around a TODO changing does not change the
+// TODO.
+func sameTodo(a, b Todo) bool {
+	a.Context, b.Context = nil, nil
+	a.ModTime, b.ModTime = time.Time{}, time.Time{}
+	return reflect.DeepEqual(a, b)
+}
+
+// positionKey identifies a TODO by its file and line
+func positionKey(todo Todo) string {
+	return fmt.Sprintf("%s:%d", todo.FilePath, todo.LineNumber)
+}
+
+// sortedTodos returns a copy of todos ordered by file and line
+func sortedTodos(todos []Todo) []Todo {
+	sorted := append([]Todo(nil), todos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return todoLess(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+func todoLess(a, b Todo) bool {
+	if a.FilePath != b.FilePath {
+		return a.FilePath < b.FilePath
+	}
+	return a.LineNumber < b.LineNumber
+}
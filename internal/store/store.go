@@ -4,14 +4,46 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Todo represents a TODO comment found in source code.
 type Todo struct {
-	Comment    string `json:"comment"`     // The TODO text (including any extra info)
-	FilePath   string `json:"file_path"`   // The file in which it was found
-	LineNumber int    `json:"line_number"` // The line number
-	Function   string `json:"function"`    // The enclosing function name (if any)
+	Comment    string    `json:"comment"`            // The TODO text (including any extra info)
+	FilePath   string    `json:"file_path"`          // The file in which it was found
+	LineNumber int       `json:"line_number"`        // The line number
+	EndLine    int       `json:"end_line,omitempty"` // The last line of a TODO continued over several lines
+	Function   string    `json:"function"`           // The enclosing function name (if any)
+	Scope      *Scope    `json:"scope,omitempty"`    // The innermost enclosing scope (if detected)
+	Package    string    `json:"package,omitempty"`  // The package or module the file belongs to (if known)
+	Keyword    string    `json:"keyword,omitempty"`  // The keyword that marked the comment, e.g. TODO or FIXME
+	Severity   string    `json:"severity,omitempty"` // The severity configured for the keyword
+	ModTime    time.Time `json:"mod_time"`           // The file's modification time when it was scanned
+	Context    *Snippet  `json:"context,omitempty"`  // The source around the TODO, when the scan captures it
+}
+
+// Scope describes a code construct (class, method, function, impl block...) enclosing a TODO.
+type Scope struct {
+	Kind      string `json:"kind"`               // The kind of construct, e.g. "class" or "method"
+	Name      string `json:"name"`               // The construct's own name
+	Path      string `json:"path"`               // The fully qualified name, e.g. "Type.Method"
+	Receiver  string `json:"receiver,omitempty"` // The receiver type of a method, e.g. "*ProjectWatcher"
+	StartLine int    `json:"start_line"`         // First line of the construct
+	EndLine   int    `json:"end_line"`           // Last line of the construct
+}
+
+// Snippet is the source around a TODO, as captured by a scan or read again
+// from disk when the file changed since.
+type Snippet struct {
+	StartLine     int       `json:"start_line"`               // The line number of the first line
+	Lines         []string  `json:"lines"`                    // The source lines, the TODO's own included
+	Line          int       `json:"line"`                     // The TODO's line, which moves when the file changed
+	Signature     string    `json:"signature,omitempty"`      // The first line of the enclosing function or scope
+	SignatureLine int       `json:"signature_line,omitempty"` // The line number of the signature
+	ModTime       time.Time `json:"mod_time"`                 // The file's modification time when the snippet was read
+	Stale         bool      `json:"stale,omitempty"`          // The file changed since the scan
 }
 
 // Store holds TODOs for each project.
@@ -43,13 +75,18 @@ func (s *Store) AddTodo(projectName string, todo Todo) {
 	s.Projects[projectName] = append(s.Projects[projectName], todo)
 }
 
-// Save writes the store to disk as JSON.
+// Save writes the store to disk as JSON. The file is replaced in one step so
+// readers such as the daemon's API never see a partially written store.
 func (s *Store) Save(filePath string) error {
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error marshalling store: %v", err)
 	}
-	return os.WriteFile(filePath, data, 0644)
+	tmp := filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filePath)
 }
 
 // LoadStore loads a Store from the specified JSON file.
@@ -145,3 +182,45 @@ func (s *Store) GetProject(projectName string) ([]Todo, bool) {
 func (s *Store) RemoveProject(projectName string) {
 	delete(s.Projects, projectName)
 }
+
+// RemoveFile removes the TODOs of a file, or of every file under a directory,
+// from a project and returns them
+func (s *Store) RemoveFile(projectName, path string) []Todo {
+	todos, exists := s.Projects[projectName]
+	if !exists {
+		return nil
+	}
+
+	kept := make([]Todo, 0, len(todos))
+	var removed []Todo
+	for _, todo := range todos {
+		if isUnder(todo.FilePath, path) {
+			removed = append(removed, todo)
+		} else {
+			kept = append(kept, todo)
+		}
+	}
+	s.Projects[projectName] = kept
+	return removed
+}
+
+// RenameFile moves the TODOs of a file, or of every file under a directory,
+// to a new path and returns the changes
+func (s *Store) RenameFile(projectName, oldPath, newPath string) []TodoChange {
+	var changes []TodoChange
+	for i, todo := range s.Projects[projectName] {
+		if !isUnder(todo.FilePath, oldPath) {
+			continue
+		}
+		moved := todo
+		moved.FilePath = newPath + strings.TrimPrefix(todo.FilePath, oldPath)
+		s.Projects[projectName][i] = moved
+		changes = append(changes, TodoChange{Before: todo, After: moved})
+	}
+	return changes
+}
+
+// isUnder reports whether path is dir or a file beneath it
+func isUnder(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
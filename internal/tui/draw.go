@@ -0,0 +1,389 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"Ttracker/internal/keywords"
+	"Ttracker/internal/store"
+)
+
+// span is a piece of text drawn in one style
+type span struct {
+	style string
+	text  string
+}
+
+// render draws spans on a row of width columns, cutting or padding them.
+// base is applied to the whole row, e.g. to show the selection.
+func render(width int, base string, spans ...span) string {
+	var b strings.Builder
+	used := 0
+	for _, s := range spans {
+		text := []rune(sanitize(s.text))
+		if len(text) == 0 {
+			continue
+		}
+		if used+len(text) > width {
+			text = []rune(fit(string(text), width-used))
+		}
+		b.WriteString(base + s.style + string(text) + styleReset)
+		used += len(text)
+		if used >= width {
+			break
+		}
+	}
+	if used < width {
+		b.WriteString(base + strings.Repeat(" ", width-used) + styleReset)
+	}
+	return b.String()
+}
+
+// sanitize expands tabs and drops control characters that would move the
+// cursor
+func sanitize(text string) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// layout is the size of the panes
+type layout struct {
+	width        int
+	body         int // rows between the header and the footer
+	treeW, treeH int
+	prevW, prevH int
+	side         bool // the preview is right of the tree instead of below it
+}
+
+func (u *ui) layout() layout {
+	w, h := u.term.size()
+	body := h - 2
+	if body < 1 {
+		body = 1
+	}
+	l := layout{width: w, body: body, treeW: w, treeH: body}
+	if !u.preview || u.switching {
+		return l
+	}
+	if w >= 100 {
+		l.side = true
+		l.treeW = w * 55 / 100
+		l.prevW = w - l.treeW - 1
+		l.prevH = body
+	} else if body >= 12 {
+		l.prevH = body * 2 / 5
+		l.treeH = body - l.prevH - 1
+		l.prevW = w
+	}
+	return l
+}
+
+// treeHeight returns the number of tree rows on screen
+func (u *ui) treeHeight() int {
+	return u.layout().treeH
+}
+
+// draw draws the whole screen
+func (u *ui) draw() {
+	l := u.layout()
+
+	// Scroll the selection into view
+	if u.cursor < u.offset {
+		u.offset = u.cursor
+	}
+	if u.cursor >= u.offset+l.treeH {
+		u.offset = u.cursor - l.treeH + 1
+	}
+	if last := len(u.rows) - l.treeH; u.offset > last {
+		u.offset = last
+	}
+	if u.offset < 0 {
+		u.offset = 0
+	}
+
+	screen := []string{u.header(l.width)}
+	switch {
+	case u.switching:
+		screen = append(screen, u.switcherLines(l.width, l.body)...)
+	case l.prevH == 0:
+		screen = append(screen, u.treeLines(l.treeW, l.treeH)...)
+	case l.side:
+		tree := u.treeLines(l.treeW, l.treeH)
+		preview := u.previewLines(l.prevW, l.prevH)
+		for i := range tree {
+			screen = append(screen, tree[i]+styled(styleFaint, "│")+preview[i])
+		}
+	default:
+		screen = append(screen, u.treeLines(l.treeW, l.treeH)...)
+		screen = append(screen, styled(styleFaint, strings.Repeat("─", l.width)))
+		screen = append(screen, u.previewLines(l.prevW, l.prevH)...)
+	}
+	screen = append(screen, u.footer(l.width))
+	u.term.draw(screen)
+}
+
+func (u *ui) header(width int) string {
+	name := u.project
+	if name == "" {
+		name = "All projects"
+	}
+	total := 0
+	for _, n := range u.tree {
+		total += n.count
+	}
+	count := fmt.Sprintf("%d TODOs", total)
+	if u.query != "" {
+		count = fmt.Sprintf("%d matching", total)
+	}
+	info := " " + name + "  ·  " + count
+	if u.opts.Source != "" {
+		info += "  ·  " + u.opts.Source
+	}
+	return render(width, styleReverse, span{styleBold, " tt ui "}, span{"", info})
+}
+
+func (u *ui) footer(width int) string {
+	switch {
+	case u.searching:
+		return render(width, "",
+			span{styleBold, "/"}, span{"", u.query}, span{styleReverse, " "},
+			span{styleFaint, "   enter keep  esc clear  ↑↓ move"})
+	case u.switching:
+		return render(width, "", span{styleFaint, "↑↓ choose  enter switch  esc cancel"})
+	case u.message != "":
+		return render(width, "", span{"", u.message})
+	}
+	var filter span
+	if u.query != "" {
+		filter = span{styleYellow, "filter: " + u.query + " (esc clears)  "}
+	}
+	return render(width, "", filter,
+		span{styleFaint, "↑↓ move  ←→ fold  enter/e open  / search  p project  tab preview  r refresh  q quit"})
+}
+
+// treeLines draws the visible part of the tree
+func (u *ui) treeLines(width, height int) []string {
+	lines := make([]string, 0, height)
+	if len(u.rows) == 0 {
+		text := "No TODOs found. Use 'tt track' to track a project first."
+		switch {
+		case u.query != "":
+			text = fmt.Sprintf("No TODOs match %q", u.query)
+		case u.project != "":
+			text = fmt.Sprintf("No TODOs found in %s", u.project)
+		case len(u.opts.Projects) > 0:
+			text = "No TODOs found in any tracked project"
+		}
+		lines = append(lines, render(width, "", span{styleFaint, " " + text}))
+	}
+	for i := u.offset; i < len(u.rows) && len(lines) < height; i++ {
+		base := ""
+		if i == u.cursor {
+			base = styleReverse
+		}
+		lines = append(lines, render(width, base, u.rowSpans(u.rows[i])...))
+	}
+	for len(lines) < height {
+		lines = append(lines, render(width, ""))
+	}
+	return lines
+}
+
+// rowSpans returns what a tree row shows
+func (u *ui) rowSpans(n *node) []span {
+	indent := " " + strings.Repeat("  ", n.depth)
+	if n.kind == todoNode {
+		spans := []span{{"", indent + "  "}, {styleFaint, fmt.Sprintf("%d: ", n.todo.LineNumber)}}
+		return append(spans, keywordSpans(n.label, n.todo)...)
+	}
+
+	marker := "▸ "
+	if u.expanded(n) {
+		marker = "▾ "
+	}
+	style := ""
+	switch n.kind {
+	case projectNode:
+		style = styleBold
+	case dirNode:
+		style = styleBold + styleCyan
+	}
+	return []span{{"", indent + marker}, {style, n.label}, {styleFaint, fmt.Sprintf(" (%d)", n.count)}}
+}
+
+// keywordSpans highlights the keyword of a TODO in its comment with the
+// color of its severity
+func keywordSpans(text string, todo *store.Todo) []span {
+	i := -1
+	if todo.Keyword != "" {
+		i = strings.Index(text, todo.Keyword)
+	}
+	if i < 0 {
+		return []span{{"", text}}
+	}
+	style := styleBold
+	switch todo.Severity {
+	case keywords.SeverityCritical, keywords.SeverityHigh:
+		style += styleRed
+	case keywords.SeverityMedium:
+		style += styleYellow
+	case keywords.SeverityLow:
+		style += styleGreen
+	}
+	end := i + len(todo.Keyword)
+	return []span{{"", text[:i]}, {style, text[i:end]}, {"", text[end:]}}
+}
+
+// source is the content of a file shown in the preview
+type source struct {
+	lines []string
+	err   error
+}
+
+// source reads a file, once until the TODOs are refreshed
+func (u *ui) source(path string) source {
+	if s, ok := u.files[path]; ok {
+		return s
+	}
+	var s source
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.err = err
+	} else {
+		s.lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+	u.files[path] = s
+	return s
+}
+
+// previewLines draws the preview of the selected row: the source around a
+// TODO, or the content of a directory or project
+func (u *ui) previewLines(width, height int) []string {
+	var lines []string
+	n := u.selected()
+	if n != nil && n.kind == fileNode && len(n.children) > 0 {
+		n = n.children[0]
+	}
+
+	switch {
+	case n == nil:
+	case n.kind == todoNode:
+		lines = u.sourceLines(n, width, height)
+	default:
+		lines = append(lines, render(width, "", span{styleBold, " " + n.label}, span{styleFaint, fmt.Sprintf("  %d TODOs", n.count)}))
+		for _, child := range n.children {
+			if len(lines) == height {
+				break
+			}
+			lines = append(lines, render(width, "", span{"", "   " + child.label}, span{styleFaint, fmt.Sprintf(" (%d)", child.count)}))
+		}
+	}
+
+	for len(lines) < height {
+		lines = append(lines, render(width, ""))
+	}
+	return lines
+}
+
+// sourceLines draws the lines of source around a TODO, with the TODO's
+// lines highlighted
+func (u *ui) sourceLines(n *node, width, height int) []string {
+	todo := n.todo
+	where := relPath(n.path, u.opts.Projects[n.project]) + ":" + strconv.Itoa(todo.LineNumber)
+	if todo.EndLine > todo.LineNumber {
+		where += "-" + strconv.Itoa(todo.EndLine)
+	}
+	scope := ""
+	if todo.Function != "" {
+		scope = "  in " + todo.Function
+	} else if todo.Scope != nil {
+		scope = "  in " + todo.Scope.Kind + " " + todo.Scope.Path
+	}
+	lines := []string{render(width, "", span{styleBold, " " + where}, span{styleFaint, scope})}
+
+	src := u.source(n.path)
+	if src.err != nil {
+		return append(lines, render(width, "", span{styleRed, fmt.Sprintf(" Could not read the file: %v", src.err)}))
+	}
+
+	first, last := todo.LineNumber, todo.EndLine
+	if last < first {
+		last = first
+	}
+	avail := height - 1
+	start := first - avail/3
+	if start+avail-1 > len(src.lines) {
+		start = len(src.lines) - avail + 1
+	}
+	if start < 1 {
+		start = 1
+	}
+	end := start + avail - 1
+	if end > len(src.lines) {
+		end = len(src.lines)
+	}
+
+	numWidth := len(strconv.Itoa(end))
+	for i := start; i <= end; i++ {
+		numStyle, textStyle := styleFaint, ""
+		if i >= first && i <= last {
+			numStyle, textStyle = styleBold+styleYellow, styleYellow
+		}
+		lines = append(lines, render(width, "",
+			span{numStyle, fmt.Sprintf(" %*d │ ", numWidth, i)},
+			span{textStyle, src.lines[i-1]}))
+	}
+	return lines
+}
+
+// switcherLines draws the project switcher
+func (u *ui) switcherLines(width, height int) []string {
+	names := u.projectNames()
+	lines := []string{render(width, "", span{styleBold, " Switch project"}), render(width, "")}
+
+	total := 0
+	for _, todos := range u.st.Projects {
+		total += len(todos)
+	}
+	options := []string{"All projects"}
+	counts := []int{total}
+	for _, name := range names {
+		options = append(options, name)
+		counts = append(counts, len(u.st.Projects[name]))
+	}
+
+	visible := height - len(lines)
+	first := 0
+	if u.choice >= visible {
+		first = u.choice - visible + 1
+	}
+	for i := first; i < len(options) && len(lines) < height; i++ {
+		base, marker := "", "   "
+		if i == u.choice {
+			base = styleReverse
+		}
+		if i == 0 && u.project == "" || i > 0 && names[i-1] == u.project {
+			marker = " • "
+		}
+		path := ""
+		if i > 0 {
+			path = "  " + u.opts.Projects[names[i-1]]
+		}
+		lines = append(lines, render(width, base,
+			span{"", marker + options[i]},
+			span{styleFaint, fmt.Sprintf(" (%d)%s", counts[i], path)}))
+	}
+
+	for len(lines) < height {
+		lines = append(lines, render(width, ""))
+	}
+	return lines
+}
@@ -0,0 +1,42 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runEditor opens path at line in $VISUAL or $EDITOR, falling back to vi,
+// and waits for it to exit
+func runEditor(path string, line int) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	fields := strings.Fields(editor)
+	args := append(fields[1:], editorArgs(filepath.Base(fields[0]), path, line)...)
+	cmd := exec.Command(fields[0], args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// editorArgs returns the arguments opening path at line in an editor. Most
+// terminal editors take +line, the others take path:line or a flag.
+func editorArgs(editor, path string, line int) []string {
+	switch editor {
+	case "code", "code-insiders", "codium", "cursor":
+		return []string{"--goto", fmt.Sprintf("%s:%d", path, line)}
+	case "subl", "sublime_text", "hx", "helix", "zed":
+		return []string{fmt.Sprintf("%s:%d", path, line)}
+	case "idea", "goland", "pycharm", "webstorm", "clion":
+		return []string{"--line", strconv.Itoa(line), path}
+	}
+	return []string{"+" + strconv.Itoa(line), path}
+}
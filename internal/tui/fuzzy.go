@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fuzzyMatch reports whether the runes of query appear in text in order,
+// ignoring case, and scores the match: consecutive runes and runes at the
+// start of words score higher. Spaces in query separate terms that must all
+// match.
+func fuzzyMatch(query, text string) (int, bool) {
+	total := 0
+	for _, term := range strings.Fields(query) {
+		score, ok := matchTerm(term, text)
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+func matchTerm(term, text string) (int, bool) {
+	pattern := []rune(strings.ToLower(term))
+	runes := []rune(text)
+
+	score, i, previous := 0, 0, -2
+	for j, r := range runes {
+		if i == len(pattern) {
+			break
+		}
+		if unicode.ToLower(r) != pattern[i] {
+			continue
+		}
+		score++
+		if j == previous+1 {
+			score += 2
+		}
+		if j == 0 || !unicode.IsLetter(runes[j-1]) && !unicode.IsDigit(runes[j-1]) {
+			score++
+		}
+		previous = j
+		i++
+	}
+	return score, i == len(pattern)
+}
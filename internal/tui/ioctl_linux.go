@@ -0,0 +1,9 @@
+package tui
+
+import "golang.org/x/sys/unix"
+
+// ioctl requests reading and setting the terminal mode
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
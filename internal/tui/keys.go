@@ -0,0 +1,165 @@
+package tui
+
+import (
+	"os"
+	"unicode/utf8"
+)
+
+// keyCode identifies a key that is not a printable rune
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyEnter
+	keyEsc
+	keyBackspace
+	keyTab
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyCtrlC
+	keyCtrlU
+)
+
+// key is a key press
+type key struct {
+	code keyCode
+	r    rune // the rune typed, for keyRune
+}
+
+// keyReader reads keys from the terminal. It can be paused while another
+// program, such as the editor, reads the terminal.
+type keyReader struct {
+	in     *os.File
+	keys   chan key
+	pause  chan struct{}
+	resume chan struct{}
+}
+
+func newKeyReader(in *os.File) *keyReader {
+	r := &keyReader{
+		in:     in,
+		keys:   make(chan key, 64),
+		pause:  make(chan struct{}),
+		resume: make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// run sends the keys typed to r.keys until reading fails. It only reads
+// once the terminal has input, so a pause never loses a key.
+func (r *keyReader) run() {
+	defer close(r.keys)
+	buf := make([]byte, 256)
+	for {
+		select {
+		case <-r.pause:
+			<-r.resume
+		default:
+		}
+
+		ready, err := waitInput(r.in)
+		if err != nil {
+			return
+		}
+		if !ready {
+			continue
+		}
+		n, err := r.in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			r.keys <- k
+		}
+	}
+}
+
+// stop pauses reading until start is called. Keys typed ahead are dropped.
+// It returns false when the reader has already stopped for good.
+func (r *keyReader) stop() bool {
+	for {
+		select {
+		case r.pause <- struct{}{}:
+			return true
+		case _, ok := <-r.keys:
+			if !ok {
+				return false
+			}
+		}
+	}
+}
+
+// start resumes reading after stop
+func (r *keyReader) start() {
+	r.resume <- struct{}{}
+}
+
+// escapes maps the escape sequences of special keys to their codes
+var escapes = map[string]keyCode{
+	"[A": keyUp, "[B": keyDown, "[C": keyRight, "[D": keyLeft,
+	"OA": keyUp, "OB": keyDown, "OC": keyRight, "OD": keyLeft,
+	"[H": keyHome, "[F": keyEnd, "OH": keyHome, "OF": keyEnd,
+	"[1~": keyHome, "[4~": keyEnd, "[7~": keyHome, "[8~": keyEnd,
+	"[5~": keyPageUp, "[6~": keyPageDown,
+}
+
+// parseKeys splits the bytes read from the terminal into keys
+func parseKeys(data []byte) []key {
+	var keys []key
+	for len(data) > 0 {
+		switch b := data[0]; {
+		case b == 0x1b:
+			if len(data) == 1 {
+				keys = append(keys, key{code: keyEsc})
+				data = data[1:]
+				continue
+			}
+			// A CSI or SS3 sequence ends with a letter or ~
+			end := 1
+			for end < len(data) && end < 8 {
+				c := data[end]
+				end++
+				if end > 2 && (c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '~') {
+					break
+				}
+			}
+			if code, ok := escapes[string(data[1:end])]; ok {
+				keys = append(keys, key{code: code})
+			} else if data[1] != '[' && data[1] != 'O' {
+				// Alt+key, or Esc typed quickly before another key
+				keys = append(keys, key{code: keyEsc})
+				end = 1
+			}
+			data = data[end:]
+		case b == '\r' || b == '\n':
+			keys = append(keys, key{code: keyEnter})
+			data = data[1:]
+		case b == '\t':
+			keys = append(keys, key{code: keyTab})
+			data = data[1:]
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+			data = data[1:]
+		case b == 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+			data = data[1:]
+		case b == 0x15:
+			keys = append(keys, key{code: keyCtrlU})
+			data = data[1:]
+		case b < 0x20:
+			data = data[1:] // other control keys are not used
+		default:
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, key{code: keyRune, r: r})
+			data = data[size:]
+		}
+	}
+	return keys
+}
@@ -0,0 +1,38 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+// errUnsupported is returned by tt ui where raw terminal mode is not implemented
+var errUnsupported = errors.New("tt ui is not supported on this platform")
+
+// termState is the terminal mode saved while in raw mode
+type termState struct{}
+
+// Signals redrawing and closing the interface
+var (
+	resizeSignals []os.Signal
+	quitSignals   = []os.Signal{os.Interrupt}
+)
+
+func openTerminal(in, out *os.File) (*terminal, error) {
+	return nil, errUnsupported
+}
+
+func (t *terminal) enter() error {
+	return errUnsupported
+}
+
+func (t *terminal) leave() {}
+
+func (t *terminal) size() (int, int) {
+	return 80, 24
+}
+
+func waitInput(in *os.File) (bool, error) {
+	return false, errUnsupported
+}
@@ -0,0 +1,75 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// termState is the terminal mode saved while in raw mode
+type termState = unix.Termios
+
+// Signals redrawing and closing the interface
+var (
+	resizeSignals = []os.Signal{unix.SIGWINCH}
+	quitSignals   = []os.Signal{unix.SIGINT, unix.SIGTERM, unix.SIGHUP}
+)
+
+// openTerminal switches the terminal to raw mode and the alternate screen
+func openTerminal(in, out *os.File) (*terminal, error) {
+	saved, err := unix.IoctlGetTermios(int(in.Fd()), ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("not a terminal: %v", err)
+	}
+	t := &terminal{in: in, out: out, saved: saved}
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// enter puts the terminal in raw mode and shows the alternate screen
+func (t *terminal) enter() error {
+	raw := *t.saved
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(int(t.in.Fd()), ioctlSetTermios, &raw); err != nil {
+		return fmt.Errorf("could not set raw mode: %v", err)
+	}
+	// Alternate screen, hidden cursor
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	return nil
+}
+
+// leave restores the terminal as it was before enter
+func (t *terminal) leave() {
+	t.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	unix.IoctlSetTermios(int(t.in.Fd()), ioctlSetTermios, t.saved)
+}
+
+// size returns the width and height of the terminal
+func (t *terminal) size() (int, int) {
+	ws, err := unix.IoctlGetWinsize(int(t.out.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+// waitInput waits up to 50ms for in to have input to read
+func waitInput(in *os.File) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(in.Fd()), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, 50)
+	if err == unix.EINTR {
+		return false, nil
+	}
+	return n > 0, err
+}
@@ -0,0 +1,61 @@
+// Package tui is the full-screen terminal interface of tt ui. It drives the
+// terminal directly with raw mode and ANSI escape sequences.
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// terminal is the controlling terminal in raw mode
+type terminal struct {
+	in, out *os.File
+	saved   *termState // the mode to restore on leave
+}
+
+// draw writes a whole frame, one string per screen row
+func (t *terminal) draw(rows []string) {
+	var b strings.Builder
+	for i, row := range rows {
+		fmt.Fprintf(&b, "\x1b[%d;1H%s\x1b[0m\x1b[K", i+1, row)
+	}
+	b.WriteString("\x1b[J")
+	t.out.WriteString(b.String())
+}
+
+// ANSI styles
+const (
+	styleReset   = "\x1b[0m"
+	styleBold    = "\x1b[1m"
+	styleFaint   = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+	styleRed     = "\x1b[31m"
+	styleGreen   = "\x1b[32m"
+	styleYellow  = "\x1b[33m"
+	styleBlue    = "\x1b[34m"
+	styleCyan    = "\x1b[36m"
+)
+
+// styled wraps text in an ANSI style
+func styled(style, text string) string {
+	if text == "" {
+		return ""
+	}
+	return style + text + styleReset
+}
+
+// fit shortens text to width runes, or pads it with spaces to width
+func fit(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) > width {
+		if width == 1 {
+			return "…"
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
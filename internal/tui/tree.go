@@ -0,0 +1,169 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
+)
+
+// nodeKind is the kind of a row in the tree
+type nodeKind int
+
+const (
+	projectNode nodeKind = iota
+	dirNode
+	fileNode
+	todoNode
+)
+
+// node is a project, directory, file or TODO in the tree
+type node struct {
+	kind     nodeKind
+	key      string // identifies the node across refreshes
+	label    string
+	project  string
+	path     string // absolute path of files and TODOs
+	depth    int
+	count    int // TODOs below the node
+	todo     *store.Todo
+	children []*node
+}
+
+// container reports whether the node can be expanded and collapsed
+func (n *node) container() bool {
+	return n.kind != todoNode
+}
+
+// buildTree groups the TODOs of projects by directory and file, the way
+// tt list shows them. Projects get their own level when there are several.
+// Only TODOs matching query are kept when it is set.
+func buildTree(st *store.Store, projects []string, roots map[string]string, query string) []*node {
+	var tree []*node
+	for _, project := range projects {
+		dirs := projectDirs(st.Projects[project], project, roots[project], query)
+		if len(dirs) == 0 {
+			continue
+		}
+		if len(projects) == 1 {
+			tree = append(tree, dirs...)
+			continue
+		}
+		top := &node{kind: projectNode, key: project, label: project, project: project, children: dirs}
+		for _, dir := range dirs {
+			top.count += dir.count
+		}
+		tree = append(tree, top)
+	}
+	setDepth(tree, 0)
+	return tree
+}
+
+// projectDirs returns the directory nodes of one project
+func projectDirs(todos []store.Todo, project, root, query string) []*node {
+	files := make(map[string][]store.Todo)
+	for _, todo := range todos {
+		if query != "" {
+			if _, ok := fuzzyMatch(query, searchText(todo, root)); !ok {
+				continue
+			}
+		}
+		files[todo.FilePath] = append(files[todo.FilePath], todo)
+	}
+
+	dirs := make(map[string]*node)
+	for path, fileTodos := range files {
+		sort.Slice(fileTodos, func(i, j int) bool { return fileTodos[i].LineNumber < fileTodos[j].LineNumber })
+
+		rel := relPath(path, root)
+		dirName := filepath.Dir(rel)
+		dir, ok := dirs[dirName]
+		if !ok {
+			label := dirName + "/"
+			if dirName == "." {
+				label = "./"
+			}
+			dir = &node{kind: dirNode, key: project + "\x00" + dirName, label: label, project: project}
+			dirs[dirName] = dir
+		}
+
+		file := &node{kind: fileNode, key: project + "\x00" + path, label: filepath.Base(path), project: project, path: path, count: len(fileTodos)}
+		for i := range fileTodos {
+			todo := fileTodos[i]
+			file.children = append(file.children, &node{
+				kind:    todoNode,
+				key:     fmt.Sprintf("%s\x00%s:%d", project, path, todo.LineNumber),
+				label:   todoSummary(todo),
+				project: project,
+				path:    path,
+				count:   1,
+				todo:    &todo,
+			})
+		}
+		dir.children = append(dir.children, file)
+		dir.count += file.count
+	}
+
+	names := make([]string, 0, len(dirs))
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]*node, 0, len(names))
+	for _, name := range names {
+		dir := dirs[name]
+		sort.Slice(dir.children, func(i, j int) bool { return dir.children[i].label < dir.children[j].label })
+		result = append(result, dir)
+	}
+	return result
+}
+
+func setDepth(nodes []*node, depth int) {
+	for _, n := range nodes {
+		n.depth = depth
+		setDepth(n.children, depth+1)
+	}
+}
+
+// flatten lists the rows shown for the tree, skipping the children of
+// collapsed nodes unless expandAll is set
+func flatten(nodes []*node, collapsed map[string]bool, expandAll bool) []*node {
+	var rows []*node
+	for _, n := range nodes {
+		rows = append(rows, n)
+		if n.container() && (expandAll || !collapsed[n.key]) {
+			rows = append(rows, flatten(n.children, collapsed, expandAll)...)
+		}
+	}
+	return rows
+}
+
+// relPath returns path relative to the project root when it is inside it
+func relPath(path, root string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// searchText is what fuzzy search matches a TODO against
+func searchText(todo store.Todo, root string) string {
+	return fmt.Sprintf("%s:%d %s", relPath(todo.FilePath, root), todo.LineNumber, todoSummary(todo))
+}
+
+// todoSummary joins the lines of a TODO's comment into one line, without
+// the comment markers of continuation lines
+func todoSummary(todo store.Todo) string {
+	lines := strings.Split(strings.TrimSpace(todo.Comment), "\n")
+	for i := range lines {
+		if i > 0 {
+			lines[i] = scan.StripCommentMarkers(lines[i])
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+}
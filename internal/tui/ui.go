@@ -0,0 +1,398 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"Ttracker/internal/store"
+)
+
+// Options configures the interface
+type Options struct {
+	Projects map[string]string            // Tracked projects by name, offered by the project switcher
+	Project  string                       // The project shown first, every project when empty
+	Load     func() (*store.Store, error) // Loads the TODOs
+	Changes  <-chan struct{}              // Signals that the TODOs changed and should be loaded again
+	Source   string                       // Where the TODOs come from, shown in the header
+}
+
+// ui is the state of the interface
+type ui struct {
+	opts Options
+	term *terminal
+	keys *keyReader
+	st   *store.Store
+
+	project   string // the project shown, every project when empty
+	tree      []*node
+	rows      []*node // the visible rows of tree
+	cursor    int
+	offset    int
+	collapsed map[string]bool
+
+	query     string
+	searching bool // typing a search
+	switching bool // choosing a project
+	choice    int  // the project selected in the switcher
+	preview   bool
+	message   string
+
+	files map[string]source
+}
+
+// Run shows the interface until the user quits it
+func Run(opts Options) error {
+	st, err := opts.Load()
+	if err != nil {
+		return err
+	}
+	term, err := openTerminal(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer term.leave()
+
+	u := &ui{
+		opts:      opts,
+		term:      term,
+		st:        st,
+		project:   opts.Project,
+		collapsed: make(map[string]bool),
+		preview:   true,
+		files:     make(map[string]source),
+	}
+	u.keys = newKeyReader(os.Stdin)
+	u.rebuild()
+
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, resizeSignals...)
+	defer signal.Stop(resized)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, quitSignals...)
+	defer signal.Stop(quit)
+
+	for {
+		u.draw()
+		select {
+		case k, ok := <-u.keys.keys:
+			if !ok || u.handle(k) {
+				return nil
+			}
+		case <-resized:
+		case <-opts.Changes:
+			u.reload()
+		case <-quit:
+			return nil
+		}
+	}
+}
+
+// reload loads the TODOs again, keeping the selection
+func (u *ui) reload() {
+	st, err := u.opts.Load()
+	if err != nil {
+		u.message = fmt.Sprintf("Could not refresh: %v", err)
+		return
+	}
+	u.st = st
+	u.files = make(map[string]source)
+	u.rebuild()
+	u.message = "Updated at " + time.Now().Format("15:04:05")
+}
+
+// projects returns the projects shown
+func (u *ui) projects() []string {
+	if u.project != "" {
+		return []string{u.project}
+	}
+	return u.projectNames()
+}
+
+// projectNames returns every tracked project and every project with TODOs
+func (u *ui) projectNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range u.opts.Projects {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range u.st.Projects {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rebuild builds the tree again, for new TODOs, a new project or a new search
+func (u *ui) rebuild() {
+	u.tree = buildTree(u.st, u.projects(), u.opts.Projects, u.query)
+	u.refreshRows()
+}
+
+// refreshRows lists the visible rows again and keeps the selected row
+// selected when it is still visible
+func (u *ui) refreshRows() {
+	var selected string
+	if n := u.selected(); n != nil {
+		selected = n.key
+	}
+	u.rows = flatten(u.tree, u.collapsed, u.query != "")
+
+	for i, n := range u.rows {
+		if n.key == selected {
+			u.cursor = i
+			return
+		}
+	}
+	if u.query != "" {
+		// Select the first match
+		for i, n := range u.rows {
+			if n.kind == todoNode {
+				u.cursor = i
+				return
+			}
+		}
+	}
+	u.move(0)
+}
+
+// selected returns the selected row, nil when there are no rows
+func (u *ui) selected() *node {
+	if u.cursor < 0 || u.cursor >= len(u.rows) {
+		return nil
+	}
+	return u.rows[u.cursor]
+}
+
+// expanded reports whether the children of a node are shown
+func (u *ui) expanded(n *node) bool {
+	return u.query != "" || !u.collapsed[n.key]
+}
+
+// move moves the selection by delta rows
+func (u *ui) move(delta int) {
+	u.cursor += delta
+	if u.cursor >= len(u.rows) {
+		u.cursor = len(u.rows) - 1
+	}
+	if u.cursor < 0 {
+		u.cursor = 0
+	}
+}
+
+// handle handles a key and reports whether the user quits
+func (u *ui) handle(k key) bool {
+	u.message = ""
+	if k.code == keyCtrlC {
+		return true
+	}
+	if u.switching {
+		u.handleSwitch(k)
+		return false
+	}
+	if u.searching {
+		u.handleSearch(k)
+		return false
+	}
+
+	switch {
+	case k.code == keyUp || k.r == 'k':
+		u.move(-1)
+	case k.code == keyDown || k.r == 'j':
+		u.move(1)
+	case k.code == keyPageUp:
+		u.move(-u.treeHeight())
+	case k.code == keyPageDown:
+		u.move(u.treeHeight())
+	case k.code == keyHome || k.r == 'g':
+		u.move(-len(u.rows))
+	case k.code == keyEnd || k.r == 'G':
+		u.move(len(u.rows))
+	case k.code == keyLeft || k.r == 'h':
+		u.collapseOrParent()
+	case k.code == keyRight || k.r == 'l':
+		u.expandOrChild()
+	case k.r == ' ':
+		u.toggle()
+	case k.code == keyEnter:
+		if n := u.selected(); n != nil && n.container() {
+			u.toggle()
+		} else {
+			u.open()
+		}
+	case k.r == 'e' || k.r == 'o':
+		u.open()
+	case k.r == '/':
+		u.searching = true
+	case k.code == keyEsc:
+		if u.query != "" {
+			u.query = ""
+			u.rebuild()
+		}
+	case k.r == 'p':
+		u.switching = true
+		u.choice = 0
+		for i, name := range u.projectNames() {
+			if name == u.project {
+				u.choice = i + 1
+			}
+		}
+	case k.code == keyTab || k.r == 'v':
+		u.preview = !u.preview
+	case k.r == 'r':
+		u.reload()
+	case k.r == '+':
+		u.collapsed = make(map[string]bool)
+		u.refreshRows()
+	case k.r == '-':
+		u.collapseAll(u.tree)
+		u.cursor = 0
+		u.refreshRows()
+	case k.r == 'q':
+		return true
+	}
+	return false
+}
+
+// handleSearch handles a key typed in the search prompt
+func (u *ui) handleSearch(k key) {
+	switch k.code {
+	case keyRune:
+		u.query += string(k.r)
+	case keyBackspace:
+		if runes := []rune(u.query); len(runes) > 0 {
+			u.query = string(runes[:len(runes)-1])
+		}
+	case keyCtrlU:
+		u.query = ""
+	case keyEnter:
+		u.searching = false
+		return
+	case keyEsc:
+		u.searching = false
+		u.query = ""
+	case keyUp:
+		u.move(-1)
+		return
+	case keyDown:
+		u.move(1)
+		return
+	default:
+		return
+	}
+	u.rebuild()
+}
+
+// handleSwitch handles a key in the project switcher
+func (u *ui) handleSwitch(k key) {
+	names := u.projectNames()
+	switch {
+	case k.code == keyUp || k.r == 'k':
+		if u.choice > 0 {
+			u.choice--
+		}
+	case k.code == keyDown || k.r == 'j':
+		if u.choice < len(names) {
+			u.choice++
+		}
+	case k.code == keyEnter:
+		u.switching = false
+		u.project = ""
+		if u.choice > 0 {
+			u.project = names[u.choice-1]
+		}
+		u.cursor = 0
+		u.rebuild()
+	case k.code == keyEsc || k.r == 'p' || k.r == 'q':
+		u.switching = false
+	}
+}
+
+// toggle expands or collapses the selected node
+func (u *ui) toggle() {
+	n := u.selected()
+	if n == nil || !n.container() || u.query != "" {
+		return
+	}
+	u.collapsed[n.key] = !u.collapsed[n.key]
+	u.refreshRows()
+}
+
+// collapseOrParent collapses the selected node, or selects its parent when
+// it is already collapsed
+func (u *ui) collapseOrParent() {
+	n := u.selected()
+	if n == nil {
+		return
+	}
+	if n.container() && u.expanded(n) && u.query == "" {
+		u.collapsed[n.key] = true
+		u.refreshRows()
+		return
+	}
+	for i := u.cursor - 1; i >= 0; i-- {
+		if u.rows[i].depth < n.depth {
+			u.cursor = i
+			return
+		}
+	}
+}
+
+// expandOrChild expands the selected node, or selects its first child when
+// it is already expanded
+func (u *ui) expandOrChild() {
+	n := u.selected()
+	if n == nil || !n.container() {
+		return
+	}
+	if !u.expanded(n) {
+		delete(u.collapsed, n.key)
+		u.refreshRows()
+		return
+	}
+	if len(n.children) > 0 {
+		u.move(1)
+	}
+}
+
+func (u *ui) collapseAll(nodes []*node) {
+	for _, n := range nodes {
+		if n.container() {
+			u.collapsed[n.key] = true
+			u.collapseAll(n.children)
+		}
+	}
+}
+
+// open opens the selected TODO, or the first TODO of the selected file, in
+// the editor
+func (u *ui) open() {
+	n := u.selected()
+	if n != nil && n.kind == fileNode && len(n.children) > 0 {
+		n = n.children[0]
+	}
+	if n == nil || n.kind != todoNode {
+		u.message = "Select a file or a TODO to open it"
+		return
+	}
+
+	if !u.keys.stop() {
+		return
+	}
+	u.term.leave()
+	err := runEditor(n.path, n.todo.LineNumber)
+	if enterErr := u.term.enter(); enterErr != nil && err == nil {
+		err = enterErr
+	}
+	u.keys.start()
+
+	delete(u.files, n.path)
+	if err != nil {
+		u.message = fmt.Sprintf("Could not open the editor: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"Ttracker/internal/config"
+	"Ttracker/internal/store"
+)
+
+// Track registers a new project in the config, starts watching it, or polling
+// it with poll, and scans it in the background
+func (pw *ProjectWatcher) Track(name, path string, poll bool) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("could not resolve %s: %v", path, err)
+	}
+	if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory does not exist: %s", absPath)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+	if cfg.Projects == nil {
+		cfg.Projects = make(map[string]string)
+	}
+	if _, exists := cfg.Projects[name]; exists {
+		return fmt.Errorf("project name %s already exists", name)
+	}
+	for _, tracked := range cfg.Projects {
+		if tracked == absPath {
+			return fmt.Errorf("project is already being tracked: %s", absPath)
+		}
+	}
+
+	cfg.Projects[name] = absPath
+	if poll {
+		cfg.SetPoll(name, true)
+	}
+	if cfg.Active == "" {
+		cfg.Active = absPath
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+
+	pw.mutex.Lock()
+	pw.cfg = cfg
+	pw.mutex.Unlock()
+
+	if err := pw.AddProject(name, absPath); err != nil {
+		return err
+	}
+	pw.scheduler.NotifyNow(name)
+	return nil
+}
+
+// Untrack removes a project from the config, stops watching it and removes
+// its TODOs from the store
+func (pw *ProjectWatcher) Untrack(name string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+	path, exists := cfg.Projects[name]
+	if !exists {
+		return fmt.Errorf("project %s is not tracked", name)
+	}
+
+	delete(cfg.Projects, name)
+	if cfg.Active == path || cfg.Active == name {
+		cfg.Active = ""
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+
+	pw.mutex.Lock()
+	pw.cfg = cfg
+	pw.mutex.Unlock()
+
+	pw.RemoveProject(name)
+
+	// Wait for a running scan so it does not write the project back
+	pw.storeLock.Lock()
+	defer pw.storeLock.Unlock()
+
+	st, err := store.LoadStore(pw.storeFile)
+	if err != nil {
+		return nil // nothing stored yet
+	}
+	st.RemoveProject(name)
+	return st.Save(pw.storeFile)
+}
+
+// ScanProject scans a watched project and waits for the scan to finish
+func (pw *ProjectWatcher) ScanProject(name string) error {
+	pw.mutex.Lock()
+	path, ok := pw.projects[name]
+	pw.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("project %s is not being watched", name)
+	}
+	return pw.runScan(name, path)
+}
+
+// ScanAll queues a scan of every watched project. The scans go through the
+// scheduler, so they run in the background and never overlap the scans of
+// file changes.
+func (pw *ProjectWatcher) ScanAll() {
+	pw.mutex.Lock()
+	names := make([]string, 0, len(pw.projects))
+	for name := range pw.projects {
+		names = append(names, name)
+	}
+	pw.mutex.Unlock()
+
+	for _, name := range names {
+		pw.scheduler.NotifyNow(name)
+	}
+}
+
+// StoreFile returns the path of the TODO store the watcher updates
+func (pw *ProjectWatcher) StoreFile() string {
+	return pw.storeFile
+}
@@ -0,0 +1,163 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inotifyLimitFile holds the number of inotify watches a user may create
+const inotifyLimitFile = "/proc/sys/fs/inotify/max_user_watches"
+
+// inotifyWatchLimit returns the inotify watch limit, if the system has one
+func inotifyWatchLimit() (int, bool) {
+	data, err := os.ReadFile(inotifyLimitFile)
+	if err != nil {
+		return 0, false
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return limit, err == nil && limit > 0
+}
+
+// suggestedWatchLimit returns a watch limit leaving room to grow for needed
+// watches, rounded up to a power of two
+func suggestedWatchLimit(needed int) int {
+	limit := 8192
+	for limit < 2*needed {
+		limit *= 2
+	}
+	return limit
+}
+
+// poller checks a project for changes by comparing snapshots of the
+// modification times of its files
+type poller struct {
+	reason string // why the project is polled
+	stop   chan struct{}
+}
+
+// fileState is what a snapshot records about a file or directory
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// startPolling stops watching a project with inotify and polls it instead
+func (pw *ProjectWatcher) startPolling(name, path, reason string) {
+	pw.pollMutex.Lock()
+	if _, ok := pw.pollers[name]; ok {
+		pw.pollMutex.Unlock()
+		return
+	}
+	p := &poller{reason: reason, stop: make(chan struct{})}
+	pw.pollers[name] = p
+	pw.pollMutex.Unlock()
+
+	pw.unwatchProject(name, path)
+
+	pw.mutex.Lock()
+	interval := pw.cfg.PollInterval()
+	pw.mutex.Unlock()
+	fmt.Printf("Polling project '%s' every %s: %s\n", name, interval, reason)
+
+	go pw.poll(name, path, p)
+}
+
+// stopPolling stops polling a project
+func (pw *ProjectWatcher) stopPolling(name string) {
+	pw.pollMutex.Lock()
+	defer pw.pollMutex.Unlock()
+
+	if p, ok := pw.pollers[name]; ok {
+		close(p.stop)
+		delete(pw.pollers, name)
+	}
+}
+
+// isPolled reports whether a project is polled instead of watched
+func (pw *ProjectWatcher) isPolled(name string) bool {
+	_, polled := pw.pollReason(name)
+	return polled
+}
+
+// pollReason returns why a project is polled
+func (pw *ProjectWatcher) pollReason(name string) (string, bool) {
+	pw.pollMutex.Lock()
+	defer pw.pollMutex.Unlock()
+
+	p, ok := pw.pollers[name]
+	if !ok {
+		return "", false
+	}
+	return p.reason, true
+}
+
+// poll compares a snapshot of a project with the previous one every poll
+// interval and schedules a scan when they differ
+func (pw *ProjectWatcher) poll(name, path string, p *poller) {
+	previous := pw.snapshot(name, path)
+	for {
+		pw.mutex.Lock()
+		interval := pw.cfg.PollInterval()
+		pw.mutex.Unlock()
+
+		select {
+		case <-p.stop:
+			return
+		case <-pw.stopChan:
+			return
+		case <-time.After(interval):
+		}
+
+		current := pw.snapshot(name, path)
+		changed, ignoreChanged := diffSnapshots(previous, current)
+		previous = current
+		switch {
+		case ignoreChanged:
+			pw.reloadIgnore(name, path)
+		case changed:
+			pw.scheduler.Notify(name)
+		}
+	}
+}
+
+// snapshot records the files and directories of a project that are not ignored
+func (pw *ProjectWatcher) snapshot(name, root string) map[string]fileState {
+	ignoreMgr := pw.ignoreFor(name)
+	files := make(map[string]fileState)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip inaccessible paths
+		}
+		if path != root && ignoreMgr != nil && ignoreMgr.IsIgnored(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files
+}
+
+// diffSnapshots reports whether anything changed between two snapshots, and
+// whether an ignore file was among the changes
+func diffSnapshots(previous, current map[string]fileState) (changed, ignoreChanged bool) {
+	for path, state := range current {
+		if old, ok := previous[path]; !ok || !old.modTime.Equal(state.modTime) || old.size != state.size {
+			changed = true
+			ignoreChanged = ignoreChanged || isIgnoreFile(path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changed = true
+			ignoreChanged = ignoreChanged || isIgnoreFile(path)
+		}
+	}
+	return changed, ignoreChanged
+}
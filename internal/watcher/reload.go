@@ -0,0 +1,185 @@
+package watcher
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"Ttracker/internal/config"
+	"Ttracker/internal/events"
+	plugin "Ttracker/internal/plugins"
+)
+
+// reloadDelay batches the events produced by a single save of the config
+const reloadDelay = 200 * time.Millisecond
+
+// watchSelfFiles watches the config and plugin list so changes made by other
+// tt commands, like tt track or tt plugins, are picked up without a restart.
+// Their directories are watched since the files are replaced when saved.
+func (pw *ProjectWatcher) watchSelfFiles() {
+	pw.selfFiles = make(map[string]bool)
+	for _, file := range []string{config.Path(), plugin.PluginConfigsPath} {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		pw.selfFiles[abs] = true
+		if err := pw.watcher.Add(filepath.Dir(abs)); err != nil {
+			log.Printf("Warning: Changes to %s will not be reloaded: %v", file, err)
+		}
+	}
+}
+
+// isSelfFile reports whether path is the config or plugin list
+func (pw *ProjectWatcher) isSelfFile(path string) bool {
+	abs, err := filepath.Abs(path)
+	return err == nil && pw.selfFiles[abs]
+}
+
+// isSelfDir reports whether dir holds the config or plugin list
+func (pw *ProjectWatcher) isSelfDir(dir string) bool {
+	for file := range pw.selfFiles {
+		if filepath.Dir(file) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleReload reloads the daemon's configuration once changes to path settle
+func (pw *ProjectWatcher) scheduleReload(path string) {
+	pw.mutex.Lock()
+	defer pw.mutex.Unlock()
+
+	// A new plugin list changes which files can be parsed, so every project is rescanned
+	abs, _ := filepath.Abs(plugin.PluginConfigsPath)
+	rescanAll := path == abs
+
+	if pw.reloadTimer != nil && pw.reloadTimer.Stop() {
+		rescanAll = rescanAll || pw.reloadAll
+	}
+	pw.reloadAll = rescanAll
+	pw.reloadTimer = time.AfterFunc(reloadDelay, func() {
+		if err := pw.reload(rescanAll); err != nil {
+			log.Printf("Error reloading configuration: %v", err)
+		}
+	})
+}
+
+// Reload re-reads the config, starts and stops watching projects to match it,
+// reloads every project's ignore rules and rescans them
+func (pw *ProjectWatcher) Reload() error {
+	return pw.reload(true)
+}
+
+// reload brings the watched projects in line with the config. Projects whose
+// settings changed are rescanned, or every project with rescanAll.
+func (pw *ProjectWatcher) reload(rescanAll bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+
+	pw.scheduler.Configure(cfg.QuietPeriod(), cfg.MinScanInterval())
+
+	pw.mutex.Lock()
+	previous := pw.cfg
+	current := maps.Clone(pw.projects)
+	pw.cfg = cfg
+	pw.mutex.Unlock()
+
+	// Stop watching untracked or moved projects, and restart projects
+	// switching between polling and inotify
+	restart := make(map[string]bool)
+	for name, path := range current {
+		if previous.ForcePoll(name) != cfg.ForcePoll(name) {
+			restart[name] = true
+		}
+		if newPath, ok := cfg.Projects[name]; !ok || newPath != path || restart[name] {
+			if err := pw.RemoveProject(name); err != nil {
+				log.Printf("Warning: Could not stop watching project %s: %v", name, err)
+				continue
+			}
+			fmt.Printf("Stopped watching project '%s'\n", name)
+		}
+	}
+
+	// Rescans go through the scheduler, so they are serialized and rate
+	// limited like the scans of file changes
+	for name, path := range cfg.Projects {
+		if oldPath, ok := current[name]; ok && oldPath == path && !restart[name] {
+			switch {
+			case rescanAll || previous.UseGitIgnore(name) != cfg.UseGitIgnore(name):
+				pw.reloadIgnore(name, path)
+			case !reflect.DeepEqual(previous.KeywordsFor(name), cfg.KeywordsFor(name)):
+				pw.scheduler.Notify(name)
+			}
+			continue
+		}
+
+		if err := pw.AddProject(name, path); err != nil {
+			log.Printf("Warning: Could not watch project %s: %v", name, err)
+			continue
+		}
+		fmt.Printf("Started watching project '%s'\n", name)
+		pw.scheduler.NotifyNow(name)
+	}
+	return nil
+}
+
+// RemoveProject stops watching a project. Directories shared with another
+// watched project, such as a nested project, stay watched.
+func (pw *ProjectWatcher) RemoveProject(name string) error {
+	pw.mutex.Lock()
+	path, ok := pw.projects[name]
+	if !ok {
+		pw.mutex.Unlock()
+		return fmt.Errorf("project %s is not being watched", name)
+	}
+	delete(pw.projects, name)
+	delete(pw.scans, name)
+	pw.mutex.Unlock()
+	pw.scheduler.Forget(name)
+	pw.stopPolling(name)
+
+	pw.ignoreMutex.Lock()
+	delete(pw.ignoreMgrs, name)
+	pw.ignoreMutex.Unlock()
+
+	pw.unwatchProject(name, path)
+
+	pw.Events.Publish(events.Event{Type: events.ProjectRemoved, Project: name, Path: path})
+	return nil
+}
+
+// unwatchProject removes the watches of a project's directories. Directories
+// shared with another watched project, such as a nested project, stay watched.
+func (pw *ProjectWatcher) unwatchProject(name, path string) {
+	pw.mutex.Lock()
+	others := make([]string, 0, len(pw.projects))
+	for other, otherPath := range pw.projects {
+		if other != name && !pw.isPolled(other) {
+			others = append(others, otherPath)
+		}
+	}
+	pw.mutex.Unlock()
+
+	for _, watched := range pw.watcher.WatchList() {
+		if !isInDirectory(watched, path) || pw.isSelfDir(watched) {
+			continue
+		}
+		shared := false
+		for _, other := range others {
+			if isInDirectory(watched, other) {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			pw.watcher.Remove(watched)
+		}
+	}
+}
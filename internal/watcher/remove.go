@@ -0,0 +1,185 @@
+package watcher
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"Ttracker/internal/events"
+	"Ttracker/internal/store"
+)
+
+// renameWindow is how long a renamed path waits for the event creating its
+// new name. inotify sends both right after each other; a rename without a
+// matching create moved the path out of the project and is a removal.
+const renameWindow = 100 * time.Millisecond
+
+// pendingRename is a path renamed away whose new name is not known yet
+type pendingRename struct {
+	project string
+	path    string
+	timer   Timer
+}
+
+// handleRemove handles a path that was removed or renamed away. Watches on it
+// and its subdirectories are dropped. A removed path's TODOs are purged right
+// away, a renamed one's once it is clear the rename has no new name in the
+// same project.
+func (pw *ProjectWatcher) handleRemove(path string, renamed bool) {
+	if isDataFile(path) {
+		return
+	}
+	pw.unwatchTree(path)
+
+	pw.mutex.Lock()
+	projectName, _ := pw.projectFor(path)
+	pw.mutex.Unlock()
+	if projectName == "" {
+		return
+	}
+
+	if !renamed {
+		pw.purge(projectName, path)
+		return
+	}
+
+	pw.mutex.Lock()
+	defer pw.mutex.Unlock()
+	if previous := pw.renamed; previous != nil && previous.timer.Stop() {
+		// The previous rename got no new name either
+		pw.purge(previous.project, previous.path)
+	}
+	pending := &pendingRename{project: projectName, path: path}
+	pending.timer = pw.clock.AfterFunc(renameWindow, func() {
+		pw.mutex.Lock()
+		if pw.renamed == pending {
+			pw.renamed = nil
+		}
+		pw.mutex.Unlock()
+		pw.purge(pending.project, pending.path)
+	})
+	pw.renamed = pending
+}
+
+// takeRename returns the path renamed to path, if any, when it was in the
+// same project
+func (pw *ProjectWatcher) takeRename(projectName string) (string, bool) {
+	pw.mutex.Lock()
+	defer pw.mutex.Unlock()
+
+	pending := pw.renamed
+	if pending == nil || !pending.timer.Stop() {
+		return "", false
+	}
+	pw.renamed = nil
+	if pending.project != projectName {
+		pw.purge(pending.project, pending.path)
+		return "", false
+	}
+	return pending.path, true
+}
+
+// unwatchTree removes the watches of a directory that is gone and of its
+// subdirectories
+func (pw *ProjectWatcher) unwatchTree(path string) {
+	for _, watched := range pw.watcher.WatchList() {
+		if isInDirectory(watched, path) && !pw.isSelfDir(watched) {
+			pw.watcher.Remove(watched)
+		}
+	}
+}
+
+// storeUpdate is a change to the TODOs of a project in the store
+type storeUpdate struct {
+	project string
+	apply   func(st *store.Store) store.TodoDiff
+}
+
+// purge queues the removal of the TODOs of a deleted file, or of every file
+// under a deleted directory, from the store
+func (pw *ProjectWatcher) purge(projectName, path string) {
+	pw.queueUpdate(projectName, func(st *store.Store) store.TodoDiff {
+		return store.TodoDiff{Removed: st.RemoveFile(projectName, path)}
+	})
+}
+
+// rename queues moving the TODOs of a renamed file, or of every file under a
+// renamed directory, to the new path in the store
+func (pw *ProjectWatcher) rename(projectName, oldPath, newPath string) {
+	pw.queueUpdate(projectName, func(st *store.Store) store.TodoDiff {
+		return store.TodoDiff{Changed: st.RenameFile(projectName, oldPath, newPath)}
+	})
+}
+
+// queueUpdate hands a store update to the store worker. It never blocks, so
+// the watch loop does not wait for a scan holding the store.
+func (pw *ProjectWatcher) queueUpdate(projectName string, apply func(st *store.Store) store.TodoDiff) {
+	pw.updateMutex.Lock()
+	pw.updates = append(pw.updates, storeUpdate{project: projectName, apply: apply})
+	pw.updateMutex.Unlock()
+
+	select {
+	case pw.updateSignal <- struct{}{}:
+	default: // the worker is already woken up
+	}
+}
+
+// pendingUpdates returns the number of store updates waiting for the worker
+func (pw *ProjectWatcher) pendingUpdates() int {
+	pw.updateMutex.Lock()
+	defer pw.updateMutex.Unlock()
+	return len(pw.updates)
+}
+
+// storeLoop applies the queued store updates in order
+func (pw *ProjectWatcher) storeLoop() {
+	for {
+		select {
+		case <-pw.updateSignal:
+		case <-pw.stopChan:
+			return
+		}
+
+		pw.applyUpdates()
+	}
+}
+
+// applyUpdates applies the queued store updates until none is left
+func (pw *ProjectWatcher) applyUpdates() {
+	for {
+		pw.updateMutex.Lock()
+		if len(pw.updates) == 0 {
+			pw.updateMutex.Unlock()
+			return
+		}
+		update := pw.updates[0]
+		pw.updates = pw.updates[1:]
+		pw.updateMutex.Unlock()
+
+		pw.updateStore(update.project, update.apply)
+	}
+}
+
+// updateStore applies update to the store and publishes the TODOs it changed
+func (pw *ProjectWatcher) updateStore(projectName string, update func(st *store.Store) store.TodoDiff) {
+	pw.storeLock.Lock()
+	defer pw.storeLock.Unlock()
+
+	st, err := store.LoadStore(pw.storeFile)
+	if err != nil {
+		return // nothing stored yet
+	}
+	diff := update(st)
+	if diff.Empty() {
+		return
+	}
+	if err := st.Save(pw.storeFile); err != nil {
+		log.Printf("Error saving store: %v", err)
+		return
+	}
+
+	fmt.Printf("Updated project '%s' (%d removed, %d moved)\n", projectName, len(diff.Removed), len(diff.Changed))
+	for _, event := range events.FromDiff(projectName, diff) {
+		pw.Events.Publish(event)
+	}
+}
@@ -0,0 +1,216 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"Ttracker/internal/store"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// storeTodos stores a TODO for each file, given relative to root
+func (tw *testWatcher) storeTodos(project, root string, files ...string) {
+	tw.t.Helper()
+	st, err := store.LoadStore(tw.storeFile)
+	if err != nil {
+		st = store.NewStore()
+	}
+	todos := make([]store.Todo, len(files))
+	for i, rel := range files {
+		todos[i] = store.Todo{Comment: "TODO: " + rel, FilePath: filepath.Join(root, filepath.FromSlash(rel)), LineNumber: 1}
+	}
+	st.AddProject(project, todos)
+	if err := st.Save(tw.storeFile); err != nil {
+		tw.t.Fatal(err)
+	}
+}
+
+// expectStored applies the queued store updates and checks the files of a
+// project's TODOs, relative to root
+func (tw *testWatcher) expectStored(project, root string, want ...string) {
+	tw.t.Helper()
+	tw.applyUpdates()
+	st, err := store.LoadStore(tw.storeFile)
+	if err != nil {
+		tw.t.Fatal(err)
+	}
+	todos, _ := st.GetProject(project)
+	var got []string
+	for _, todo := range todos {
+		rel, err := filepath.Rel(root, todo.FilePath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = todo.FilePath
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		tw.t.Fatalf("%s has TODOs in %v, want %v", project, got, want)
+	}
+}
+
+func move(t *testing.T, from, to string) {
+	t.Helper()
+	if err := os.Rename(from, to); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemovePurgesFile(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{"a.go": "", "b.go": ""})
+	tw.storeTodos("p", root, "a.go", "b.go")
+
+	if err := os.Remove(filepath.Join(root, "a.go")); err != nil {
+		t.Fatal(err)
+	}
+	tw.send(fsnotify.Remove, filepath.Join(root, "a.go"))
+	tw.expectStored("p", root, "b.go")
+}
+
+func TestRenamePairedWithCreate(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{"a.go": "", "b.go": ""})
+	tw.storeTodos("p", root, "a.go", "b.go")
+
+	move(t, filepath.Join(root, "a.go"), filepath.Join(root, "c.go"))
+	tw.send(fsnotify.Rename, filepath.Join(root, "a.go"))
+	tw.send(fsnotify.Create, filepath.Join(root, "c.go"))
+	tw.expectStored("p", root, "b.go", "c.go")
+
+	// The rename window passing does not purge the renamed file
+	tw.clock.Advance(renameWindow)
+	tw.expectStored("p", root, "b.go", "c.go")
+}
+
+func TestRenameWithoutCreatePurges(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{"a.go": "", "b.go": ""})
+	tw.storeTodos("p", root, "a.go", "b.go")
+
+	move(t, filepath.Join(root, "a.go"), filepath.Join(t.TempDir(), "a.go"))
+	tw.send(fsnotify.Rename, filepath.Join(root, "a.go"))
+	tw.clock.Advance(renameWindow - time.Millisecond)
+	tw.expectStored("p", root, "a.go", "b.go")
+
+	tw.clock.Advance(time.Millisecond)
+	tw.expectStored("p", root, "b.go")
+
+	// A create after the window is a new file, not the renamed one
+	writeFile(t, filepath.Join(root, "c.go"), "")
+	tw.send(fsnotify.Create, filepath.Join(root, "c.go"))
+	tw.expectStored("p", root, "b.go")
+}
+
+func TestRenameFollowedByRename(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{"a.go": "", "b.go": ""})
+	tw.storeTodos("p", root, "a.go", "b.go")
+
+	// The first rename got no new name when the second one arrives
+	move(t, filepath.Join(root, "a.go"), filepath.Join(t.TempDir(), "a.go"))
+	move(t, filepath.Join(root, "b.go"), filepath.Join(root, "c.go"))
+	tw.send(fsnotify.Rename, filepath.Join(root, "a.go"))
+	tw.send(fsnotify.Rename, filepath.Join(root, "b.go"))
+	tw.expectStored("p", root, "b.go")
+
+	tw.send(fsnotify.Create, filepath.Join(root, "c.go"))
+	tw.expectStored("p", root, "c.go")
+}
+
+func TestRenameToOtherProject(t *testing.T) {
+	tw := newTestWatcher(t)
+	p := tw.addProject("p", map[string]string{"a.go": "", "b.go": ""})
+	q := tw.addProject("q", map[string]string{"q.go": ""})
+	tw.storeTodos("p", p, "a.go", "b.go")
+	tw.storeTodos("q", q, "q.go")
+
+	// The TODOs of the file leave p, q finds them when it is scanned
+	move(t, filepath.Join(p, "a.go"), filepath.Join(q, "a.go"))
+	tw.send(fsnotify.Rename, filepath.Join(p, "a.go"))
+	tw.send(fsnotify.Create, filepath.Join(q, "a.go"))
+	tw.expectStored("p", p, "b.go")
+	tw.expectStored("q", q, "q.go")
+	if _, ok := tw.takeRename("p"); ok {
+		t.Error("the rename is still pending")
+	}
+
+	tw.clock.Advance(time.Second)
+	expectScans(t, tw.scans, "p")
+	expectScans(t, tw.scans, "q", time.Second)
+}
+
+func TestTakeRename(t *testing.T) {
+	tw := newTestWatcher(t)
+	p := tw.addProject("p", map[string]string{"a.go": ""})
+	tw.addProject("q", nil)
+	tw.storeTodos("p", p, "a.go")
+
+	if _, ok := tw.takeRename("p"); ok {
+		t.Fatal("takeRename found a rename before any")
+	}
+	tw.send(fsnotify.Rename, filepath.Join(p, "a.go"))
+	if path, ok := tw.takeRename("p"); !ok || path != filepath.Join(p, "a.go") {
+		t.Fatalf("takeRename(p) = %q, %v", path, ok)
+	}
+	if _, ok := tw.takeRename("p"); ok {
+		t.Fatal("a rename was taken twice")
+	}
+
+	// Taking the rename from another project purges it
+	tw.send(fsnotify.Rename, filepath.Join(p, "a.go"))
+	if _, ok := tw.takeRename("q"); ok {
+		t.Fatal("takeRename(q) took a rename of p")
+	}
+	tw.expectStored("p", p)
+
+	// A rename whose window passed is gone
+	tw.send(fsnotify.Rename, filepath.Join(p, "a.go"))
+	tw.clock.Advance(renameWindow)
+	if _, ok := tw.takeRename("p"); ok {
+		t.Fatal("takeRename took a rename after its window")
+	}
+}
+
+func TestDirectoryMovedOut(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{"top.go": "", "dir/x.go": "", "dir/sub/y.go": "", "dirt.go": ""})
+	tw.storeTodos("p", root, "top.go", "dir/x.go", "dir/sub/y.go", "dirt.go")
+
+	dir := filepath.Join(root, "dir")
+	move(t, dir, filepath.Join(t.TempDir(), "dir"))
+	tw.send(fsnotify.Rename, dir)
+	for _, watched := range tw.watcher.WatchList() {
+		if isInDirectory(watched, dir) {
+			t.Errorf("%s is still watched", watched)
+		}
+	}
+
+	tw.clock.Advance(renameWindow)
+	tw.expectStored("p", root, "top.go", "dirt.go")
+}
+
+func TestDirectoryRenamed(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{"top.go": "", "dir/x.go": "", "dir/sub/y.go": ""})
+	tw.storeTodos("p", root, "top.go", "dir/x.go", "dir/sub/y.go")
+
+	move(t, filepath.Join(root, "dir"), filepath.Join(root, "lib"))
+	tw.send(fsnotify.Rename, filepath.Join(root, "dir"))
+	tw.send(fsnotify.Create, filepath.Join(root, "lib"))
+	tw.expectStored("p", root, "top.go", "lib/x.go", "lib/sub/y.go")
+	for _, dir := range []string{"lib", filepath.Join("lib", "sub")} {
+		if !slices.Contains(tw.watcher.WatchList(), filepath.Join(root, dir)) {
+			t.Errorf("%s is not watched", dir)
+		}
+	}
+
+	tw.clock.Advance(renameWindow)
+	tw.expectStored("p", root, "top.go", "lib/x.go", "lib/sub/y.go")
+}
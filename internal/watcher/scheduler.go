@@ -0,0 +1,227 @@
+package watcher
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and runs functions later. The scheduler uses it so its
+// timing can be driven by a fake clock.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a function scheduled by a Clock
+type Timer interface {
+	Stop() bool
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// maxDelayFactor bounds how long a project changing without pause postpones
+// its scan, as a multiple of the quiet period
+const maxDelayFactor = 10
+
+// Scheduler decides when to scan projects whose files changed. Changes to a
+// project are coalesced until it has been quiet for the quiet period, and a
+// project is scanned at most once per minimum interval. A project has at most
+// one scan running and one queued: changes made during a scan queue a single
+// scan that starts once the running one finished.
+type Scheduler struct {
+	clock Clock
+	scan  func(name string)
+
+	mutex       sync.Mutex
+	quiet       time.Duration
+	minInterval time.Duration
+	projects    map[string]*projectSchedule
+	stopped     bool
+}
+
+// projectSchedule is the scheduling state of one project
+type projectSchedule struct {
+	timer       Timer     // pending start of a scan
+	generation  int       // identifies the pending timer
+	firstChange time.Time // first change not scanned yet, zero if none
+	lastStart   time.Time // start of the last scan
+	running     bool
+	queued      bool // a scan is due once the running one finished
+}
+
+// NewScheduler creates a scheduler calling scan to scan a project. Scans of
+// different projects may run at the same time.
+func NewScheduler(clock Clock, quiet, minInterval time.Duration, scan func(name string)) *Scheduler {
+	return &Scheduler{
+		clock:       clock,
+		scan:        scan,
+		quiet:       quiet,
+		minInterval: minInterval,
+		projects:    make(map[string]*projectSchedule),
+	}
+}
+
+// Configure changes the quiet period and minimum interval of later scans
+func (s *Scheduler) Configure(quiet, minInterval time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.quiet = quiet
+	s.minInterval = minInterval
+}
+
+// Notify records a change to a project's files
+func (s *Scheduler) Notify(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return
+	}
+	p := s.project(name)
+	if p.running {
+		p.queued = true
+		return
+	}
+
+	now := s.clock.Now()
+	if p.firstChange.IsZero() {
+		p.firstChange = now
+	}
+
+	// Wait for the project to be quiet, but not longer than the maximum delay
+	// after the first change
+	due := now.Add(s.quiet)
+	if limit := p.firstChange.Add(maxDelayFactor * s.quiet); due.After(limit) {
+		due = limit
+	}
+	s.scheduleLocked(name, p, due)
+}
+
+// NotifyNow schedules a scan of a project without waiting for it to be
+// quiet, for projects that were just added. The minimum interval still applies
+// and a project being scanned is scanned again once the scan finished.
+func (s *Scheduler) NotifyNow(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return
+	}
+	p := s.project(name)
+	if p.running {
+		p.queued = true
+		return
+	}
+
+	now := s.clock.Now()
+	if p.firstChange.IsZero() {
+		p.firstChange = now
+	}
+	s.scheduleLocked(name, p, now)
+}
+
+// Forget drops the state of a project that is no longer watched. A scan
+// already running finishes, but no further scan starts.
+func (s *Scheduler) Forget(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if p, ok := s.projects[name]; ok {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		delete(s.projects, name)
+	}
+}
+
+// Stop cancels every pending scan and ignores later changes
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopped = true
+	for _, p := range s.projects {
+		if p.timer != nil {
+			p.timer.Stop()
+			p.timer = nil
+		}
+		p.queued = false
+	}
+}
+
+// Pending returns the number of projects waiting for a scan
+func (s *Scheduler) Pending() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pending := 0
+	for _, p := range s.projects {
+		if p.timer != nil || p.queued {
+			pending++
+		}
+	}
+	return pending
+}
+
+// project returns the state of a project, creating it if needed. The caller
+// holds the mutex.
+func (s *Scheduler) project(name string) *projectSchedule {
+	p, ok := s.projects[name]
+	if !ok {
+		p = &projectSchedule{}
+		s.projects[name] = p
+	}
+	return p
+}
+
+// scheduleLocked starts a scan of a project at due, or later when the
+// minimum interval since the last scan has not passed. The caller holds the
+// mutex.
+func (s *Scheduler) scheduleLocked(name string, p *projectSchedule, due time.Time) {
+	if !p.lastStart.IsZero() {
+		if earliest := p.lastStart.Add(s.minInterval); due.Before(earliest) {
+			due = earliest
+		}
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+
+	p.generation++
+	generation := p.generation
+	p.timer = s.clock.AfterFunc(due.Sub(s.clock.Now()), func() {
+		s.start(name, generation)
+	})
+}
+
+// start runs a scan of a project when its timer fires
+func (s *Scheduler) start(name string, generation int) {
+	s.mutex.Lock()
+	p, ok := s.projects[name]
+	// The timer may have been replaced or the project forgotten since it fired
+	if !ok || p.generation != generation || p.timer == nil {
+		s.mutex.Unlock()
+		return
+	}
+	p.timer = nil
+	p.firstChange = time.Time{}
+	p.running = true
+	p.lastStart = s.clock.Now()
+	s.mutex.Unlock()
+
+	s.scan(name)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p.running = false
+	if p.queued && !s.stopped && s.projects[name] == p {
+		p.queued = false
+		p.firstChange = s.clock.Now()
+		s.scheduleLocked(name, p, p.firstChange.Add(s.quiet))
+	}
+}
@@ -0,0 +1,281 @@
+package watcher
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves with Advance, which runs the
+// functions that became due in order
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	f     func()
+	done  bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	pending := !t.done
+	t.done = true
+	return pending
+}
+
+// Advance moves the clock forward by d, running the timers due meanwhile. A
+// timer function advancing the clock itself may move it further.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if !t.done && !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		next.done = true
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		c.mutex.Unlock()
+		next.f()
+		c.mutex.Lock()
+	}
+	if end.After(c.now) {
+		c.now = end
+	}
+	c.mutex.Unlock()
+}
+
+// scanLog records the scans started by a scheduler
+type scanLog struct {
+	clock  *fakeClock
+	mutex  sync.Mutex
+	scans  map[string][]time.Duration // project -> start of its scans since the start of the test
+	start  time.Time
+	during func(name string) // called while a scan runs
+}
+
+func newScanLog(clock *fakeClock) *scanLog {
+	return &scanLog{clock: clock, scans: make(map[string][]time.Duration), start: clock.Now()}
+}
+
+func (l *scanLog) scan(name string) {
+	l.mutex.Lock()
+	l.scans[name] = append(l.scans[name], l.clock.Now().Sub(l.start))
+	during := l.during
+	l.mutex.Unlock()
+	if during != nil {
+		during(name)
+	}
+}
+
+func (l *scanLog) get(name string) []time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]time.Duration(nil), l.scans[name]...)
+}
+
+func expectScans(t *testing.T, l *scanLog, name string, want ...time.Duration) {
+	t.Helper()
+	got := l.get(name)
+	if len(got) != len(want) {
+		t.Fatalf("%s scanned at %v, want %v", name, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s scanned at %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestSchedulerCoalescesBurst(t *testing.T) {
+	clock := newFakeClock()
+	scans := newScanLog(clock)
+	s := NewScheduler(clock, time.Second, 0, scans.scan)
+
+	// A save producing events for half a second
+	for i := 0; i < 5; i++ {
+		s.Notify("p")
+		clock.Advance(100 * time.Millisecond)
+	}
+	clock.Advance(899 * time.Millisecond)
+	expectScans(t, scans, "p")
+
+	clock.Advance(time.Millisecond)
+	expectScans(t, scans, "p", 1400*time.Millisecond)
+
+	clock.Advance(time.Minute)
+	expectScans(t, scans, "p", 1400*time.Millisecond)
+}
+
+func TestSchedulerMaxDelay(t *testing.T) {
+	clock := newFakeClock()
+	scans := newScanLog(clock)
+	s := NewScheduler(clock, time.Second, 0, scans.scan)
+
+	// Changes every half second never leave the project quiet, the scan
+	// starts after 10 quiet periods anyway
+	for i := 0; i < 30; i++ {
+		s.Notify("p")
+		clock.Advance(500 * time.Millisecond)
+	}
+	expectScans(t, scans, "p", 10*time.Second)
+}
+
+func TestSchedulerMinInterval(t *testing.T) {
+	clock := newFakeClock()
+	scans := newScanLog(clock)
+	s := NewScheduler(clock, 100*time.Millisecond, 5*time.Second, scans.scan)
+
+	s.Notify("p")
+	clock.Advance(200 * time.Millisecond)
+	expectScans(t, scans, "p", 100*time.Millisecond)
+
+	// The next scan waits for the minimum interval since the last one
+	s.Notify("p")
+	clock.Advance(4800 * time.Millisecond)
+	expectScans(t, scans, "p", 100*time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
+	expectScans(t, scans, "p", 100*time.Millisecond, 5100*time.Millisecond)
+
+	// Once the interval passed, the quiet period alone applies
+	clock.Advance(10 * time.Second)
+	s.Notify("p")
+	clock.Advance(100 * time.Millisecond)
+	expectScans(t, scans, "p", 100*time.Millisecond, 5100*time.Millisecond, 15200*time.Millisecond)
+}
+
+func TestSchedulerOneRunningOneQueued(t *testing.T) {
+	clock := newFakeClock()
+	scans := newScanLog(clock)
+	s := NewScheduler(clock, time.Second, 0, scans.scan)
+
+	running := 0
+	scans.during = func(name string) {
+		running++
+		defer func() { running-- }()
+		if running > 1 {
+			t.Errorf("%d scans of %s running at once", running, name)
+		}
+		if len(scans.get(name)) > 1 {
+			return
+		}
+		// Changes made during the first scan queue a single scan
+		for i := 0; i < 3; i++ {
+			s.Notify(name)
+			s.NotifyNow(name)
+		}
+		if pending := s.Pending(); pending != 1 {
+			t.Errorf("Pending() = %d during the scan, want 1", pending)
+		}
+		clock.Advance(5 * time.Second) // the scan takes a while
+	}
+
+	s.Notify("p")
+	clock.Advance(time.Second)
+	expectScans(t, scans, "p", time.Second)
+
+	// The queued scan waits for the quiet period after the running one
+	clock.Advance(999 * time.Millisecond)
+	expectScans(t, scans, "p", time.Second)
+	clock.Advance(time.Millisecond)
+	expectScans(t, scans, "p", time.Second, 7*time.Second)
+
+	clock.Advance(time.Minute)
+	expectScans(t, scans, "p", time.Second, 7*time.Second)
+	if pending := s.Pending(); pending != 0 {
+		t.Errorf("Pending() = %d, want 0", pending)
+	}
+}
+
+func TestSchedulerNotifyNow(t *testing.T) {
+	clock := newFakeClock()
+	scans := newScanLog(clock)
+	s := NewScheduler(clock, time.Second, 5*time.Second, scans.scan)
+
+	s.NotifyNow("p")
+	clock.Advance(0)
+	expectScans(t, scans, "p", 0)
+
+	// The minimum interval still applies
+	s.NotifyNow("p")
+	clock.Advance(4 * time.Second)
+	expectScans(t, scans, "p", 0)
+	clock.Advance(time.Second)
+	expectScans(t, scans, "p", 0, 5*time.Second)
+}
+
+func TestSchedulerForgetAndStop(t *testing.T) {
+	clock := newFakeClock()
+	scans := newScanLog(clock)
+	s := NewScheduler(clock, time.Second, 0, scans.scan)
+
+	s.Notify("a")
+	s.Notify("b")
+	s.Forget("a")
+	clock.Advance(time.Second)
+	expectScans(t, scans, "a")
+	expectScans(t, scans, "b", time.Second)
+
+	// A forgotten project is scheduled again when it changes
+	s.Notify("a")
+	clock.Advance(time.Second)
+	expectScans(t, scans, "a", 2*time.Second)
+
+	s.Notify("a")
+	s.Notify("b")
+	s.Stop()
+	if pending := s.Pending(); pending != 0 {
+		t.Errorf("Pending() = %d after Stop, want 0", pending)
+	}
+	s.Notify("b")
+	s.NotifyNow("b")
+	clock.Advance(time.Minute)
+	expectScans(t, scans, "a", 2*time.Second)
+	expectScans(t, scans, "b", time.Second)
+}
+
+func TestSchedulerIndependentProjects(t *testing.T) {
+	clock := newFakeClock()
+	scans := newScanLog(clock)
+	s := NewScheduler(clock, time.Second, 0, scans.scan)
+
+	s.Notify("a")
+	clock.Advance(500 * time.Millisecond)
+	s.Notify("b")
+	clock.Advance(500 * time.Millisecond)
+	s.Notify("b") // postpones b only
+	clock.Advance(2 * time.Second)
+	expectScans(t, scans, "a", time.Second)
+	expectScans(t, scans, "b", 2*time.Second)
+}
@@ -1,17 +1,22 @@
 package watcher
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"Ttracker/internal/config"
+	"Ttracker/internal/events"
 	"Ttracker/internal/ignore"
 	"Ttracker/internal/scan"
+	"Ttracker/internal/store"
 
 	"github.com/fsnotify/fsnotify"
 )
@@ -19,15 +24,42 @@ import (
 // ProjectWatcher watches for file changes in tracked projects
 type ProjectWatcher struct {
 	watcher      *fsnotify.Watcher
+	fileEvents   <-chan fsnotify.Event // events of the watcher, or of a test
+	fileErrors   <-chan error
+	clock        Clock
 	projects     map[string]string // name -> path
-	debounceTime time.Duration
-	changes      map[string]time.Time
+	scheduler    *Scheduler        // decides when changed projects are scanned
 	mutex        sync.Mutex
-	scanningLock sync.Mutex
+	scanLocks    map[string]*sync.Mutex // project name -> held while the project is scanned
+	storeLock    sync.Mutex             // serializes writes to the store, which is rewritten as a whole
 	storeFile    string
 	pluginConfig string
 	stopChan     chan struct{}
-	ignoreMgr    *ignore.IgnoreManager
+	ping         chan chan struct{}               // answered by the watch loop, see Alive
+	ignoreMgrs   map[string]*ignore.IgnoreManager // project name -> ignore rules
+	ignoreMutex  sync.Mutex
+	cfg          config.Config // config the watched projects were last loaded from
+	selfFiles    map[string]bool
+	reloadTimer  *time.Timer
+	reloadAll    bool                  // the pending reload rescans every project
+	scans        map[string]ScanResult // project name -> last scan
+	queued       int                   // scans waiting for the running one
+	renamed      *pendingRename        // path renamed away, waiting for its new name
+	updates      []storeUpdate         // purges and renames waiting for the store worker
+	updateMutex  sync.Mutex
+	updateSignal chan struct{}      // wakes the store worker
+	pollers      map[string]*poller // project name -> poller of a project not watched with inotify
+	pollMutex    sync.Mutex
+
+	// Events receives scans, projects being added or removed and the TODOs
+	// added, removed or changed by each scan
+	Events *events.Broker
+}
+
+// ScanResult records the last scan of a project
+type ScanResult struct {
+	Time  time.Time
+	Error string
 }
 
 // NewProjectWatcher creates a new watcher for tracking projects
@@ -37,22 +69,32 @@ func NewProjectWatcher(storeFile, pluginConfig string) (*ProjectWatcher, error)
 		return nil, fmt.Errorf("failed to create file watcher: %v", err)
 	}
 
-	// Initialize ignore manager with default patterns
-	ignoreMgr := ignore.NewIgnoreManager()
-	for _, pattern := range ignore.GetDefaultPatterns() {
-		ignoreMgr.AddPattern(pattern.Pattern, pattern.IsDir)
-	}
+	pw := newProjectWatcher(fsWatcher, fsWatcher.Events, fsWatcher.Errors, realClock{}, storeFile, pluginConfig)
+	return pw, nil
+}
 
-	return &ProjectWatcher{
+// newProjectWatcher creates a watcher reading file events from fileEvents
+// and fileErrors instead of fsWatcher, with its timing driven by clock
+func newProjectWatcher(fsWatcher *fsnotify.Watcher, fileEvents <-chan fsnotify.Event, fileErrors <-chan error, clock Clock, storeFile, pluginConfig string) *ProjectWatcher {
+	pw := &ProjectWatcher{
 		watcher:      fsWatcher,
+		fileEvents:   fileEvents,
+		fileErrors:   fileErrors,
+		clock:        clock,
 		projects:     make(map[string]string),
-		debounceTime: 2 * time.Second,
-		changes:      make(map[string]time.Time),
+		scanLocks:    make(map[string]*sync.Mutex),
 		storeFile:    storeFile,
 		pluginConfig: pluginConfig,
 		stopChan:     make(chan struct{}),
-		ignoreMgr:    ignoreMgr,
-	}, nil
+		ping:         make(chan chan struct{}),
+		updateSignal: make(chan struct{}, 1),
+		ignoreMgrs:   make(map[string]*ignore.IgnoreManager),
+		scans:        make(map[string]ScanResult),
+		pollers:      make(map[string]*poller),
+		Events:       events.NewBroker(),
+	}
+	pw.scheduler = NewScheduler(clock, config.DefaultQuietPeriod, config.DefaultMinScanInterval, pw.scheduledScan)
+	return pw
 }
 
 // Start begins watching all tracked projects
@@ -64,15 +106,28 @@ func (pw *ProjectWatcher) Start() error {
 	}
 
 	// Watch each project
+	pw.scheduler.Configure(cfg.QuietPeriod(), cfg.MinScanInterval())
+
+	pw.mutex.Lock()
+	pw.cfg = cfg
 	for name, path := range cfg.Projects {
 		pw.projects[name] = path
+	}
+	pw.mutex.Unlock()
+
+	for name, path := range cfg.Projects {
 		if err := pw.watchProject(name, path); err != nil {
 			log.Printf("Warning: Could not watch project %s: %v", name, err)
 		}
 	}
 
-	// Start the watcher goroutine
+	// Reload when the config or plugin list is changed by another tt command
+	pw.watchSelfFiles()
+
+	// Start the watcher goroutine, and the one updating the store for
+	// removed and renamed files so the watcher never waits for a scan
 	go pw.watchLoop()
+	go pw.storeLoop()
 
 	fmt.Println("Ttracker file watcher started. Monitoring", len(pw.projects), "projects for changes.")
 	return nil
@@ -80,6 +135,7 @@ func (pw *ProjectWatcher) Start() error {
 
 // Stop gracefully stops the watcher
 func (pw *ProjectWatcher) Stop() error {
+	pw.scheduler.Stop()
 	close(pw.stopChan)
 	return pw.watcher.Close()
 }
@@ -87,10 +143,14 @@ func (pw *ProjectWatcher) Stop() error {
 // AddProject adds a new project to the watcher
 func (pw *ProjectWatcher) AddProject(name, path string) error {
 	pw.mutex.Lock()
-	defer pw.mutex.Unlock()
-
 	pw.projects[name] = path
-	return pw.watchProject(name, path)
+	pw.mutex.Unlock()
+
+	if err := pw.watchProject(name, path); err != nil {
+		return err
+	}
+	pw.Events.Publish(events.Event{Type: events.ProjectAdded, Project: name, Path: path})
+	return nil
 }
 
 // watchProject recursively watches a project directory
@@ -105,174 +165,406 @@ func (pw *ProjectWatcher) watchProject(name, path string) error {
 		return fmt.Errorf("project path is not a directory")
 	}
 
-	// Try to load project-specific ignore file
-	ignoreFile := filepath.Join(path, ".ttignore")
-	if err := pw.ignoreMgr.LoadFromFile(ignoreFile); err != nil {
-		// Not an error if file doesn't exist
-		if !os.IsNotExist(err) {
-			log.Printf("Warning: Failed to load ignore file for project %s: %v", name, err)
-		}
+	ignoreMgr := pw.loadIgnore(name, path)
+
+	pw.mutex.Lock()
+	forcePoll := pw.cfg.ForcePoll(name)
+	pw.mutex.Unlock()
+	if forcePoll {
+		pw.startPolling(name, path, "polling is set for the project")
+		return nil
 	}
 
-	// Add watcher to the root directory
-	if err := pw.watcher.Add(path); err != nil {
-		return fmt.Errorf("failed to watch directory: %v", err)
+	return pw.watchTree(name, path, path, ignoreMgr)
+}
+
+// watchTree watches root and the directories under it that are not ignored.
+// When the inotify watch limit does not leave room for them, the project is
+// polled instead.
+func (pw *ProjectWatcher) watchTree(name, projectPath, root string, ignoreMgr *ignore.IgnoreManager) error {
+	if pw.isPolled(name) {
+		return nil // the poller finds new directories itself
 	}
 
-	// Recursively add watchers to subdirectories
-	return filepath.Walk(path, func(subpath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip inaccessible paths
+	watched := make(map[string]bool)
+	for _, dir := range pw.watcher.WatchList() {
+		watched[dir] = true
+	}
+	var dirs []string
+	for _, dir := range treeDirs(root, ignoreMgr) {
+		if !watched[dir] {
+			dirs = append(dirs, dir)
 		}
+	}
 
-		// Check if path should be ignored
-		if pw.ignoreMgr.ShouldIgnore(subpath) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
+	if limit, ok := inotifyWatchLimit(); ok && len(watched)+len(dirs) > limit {
+		log.Printf("Warning: Project %s needs %d more inotify watches but only %d of the limit of %d are left. "+
+			"Raise the limit with: sudo sysctl fs.inotify.max_user_watches=%d",
+			name, len(dirs), max(limit-len(watched), 0), limit, suggestedWatchLimit(len(watched)+len(dirs)))
+		pw.startPolling(name, projectPath, fmt.Sprintf("needs %d inotify watches, over the limit of %d", len(dirs), limit))
+		return nil
+	}
+
+	for i, dir := range dirs {
+		err := pw.watcher.Add(dir)
+		if errors.Is(err, syscall.ENOSPC) {
+			// Other programs use up the watches of the same user
+			log.Printf("Warning: The inotify watch limit was reached after %d of the %d directories of project %s. "+
+				"Raise the limit with: sudo sysctl fs.inotify.max_user_watches=%d",
+				i, len(dirs), name, suggestedWatchLimit(len(watched)+len(dirs)))
+			pw.startPolling(name, projectPath, "the inotify watch limit was reached")
 			return nil
 		}
-
-		// Add watcher to directories
-		if info.IsDir() {
-			if err := pw.watcher.Add(subpath); err != nil {
-				log.Printf("Warning: Failed to watch subdirectory %s: %v", subpath, err)
+		if err != nil {
+			if dir == root {
+				return fmt.Errorf("failed to watch directory: %v", err)
 			}
+			log.Printf("Warning: Failed to watch subdirectory %s: %v", dir, err)
 		}
+	}
+	return nil
+}
+
+// treeDirs returns root and the directories under it that are not ignored
+func treeDirs(root string, ignoreMgr *ignore.IgnoreManager) []string {
+	var dirs []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil // Skip inaccessible paths
+		}
+		if path != root && ignoreMgr.IsIgnored(path, true) {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
 		return nil
 	})
+	return dirs
+}
+
+// loadIgnore (re)loads the ignore rules of a project. Each project has its own
+// rules so one project's .ttignore never applies to another.
+func (pw *ProjectWatcher) loadIgnore(name, path string) *ignore.IgnoreManager {
+	useGit := true
+	if cfg, err := config.LoadConfig(); err == nil {
+		useGit = cfg.UseGitIgnore(name)
+	}
+	ignoreMgr, err := ignore.NewProjectIgnoreManager(path, useGit)
+	if err != nil {
+		log.Printf("Warning: Failed to load ignore file for project %s: %v", name, err)
+	}
+
+	pw.ignoreMutex.Lock()
+	pw.ignoreMgrs[name] = ignoreMgr
+	pw.ignoreMutex.Unlock()
+	return ignoreMgr
+}
+
+// ignoreFor returns the ignore rules of a project
+func (pw *ProjectWatcher) ignoreFor(name string) *ignore.IgnoreManager {
+	pw.ignoreMutex.Lock()
+	defer pw.ignoreMutex.Unlock()
+	return pw.ignoreMgrs[name]
+}
+
+// isIgnoreFile reports whether a changed file holds ignore rules
+func isIgnoreFile(path string) bool {
+	base := filepath.Base(path)
+	return base == ignore.IgnoreFile || base == ignore.GitIgnoreFile
+}
+
+// reloadIgnore reloads a project's ignore rules. Directories that are no
+// longer ignored start being watched and the project is rescanned once the
+// scheduler finds it quiet, like for any other change.
+func (pw *ProjectWatcher) reloadIgnore(name, path string) {
+	fmt.Printf("Reloading ignore rules for project '%s'\n", name)
+	ignoreMgr := pw.loadIgnore(name, path)
+	if err := pw.watchTree(name, path, path, ignoreMgr); err != nil {
+		log.Printf("Warning: Failed to watch project %s: %v", name, err)
+	}
+	pw.scheduler.Notify(name)
+}
+
+// projectFor returns the project owning path, the deepest one for nested
+// projects. The caller must hold pw.mutex.
+func (pw *ProjectWatcher) projectFor(path string) (string, string) {
+	projectName := ""
+	projectPath := ""
+	for name, projPath := range pw.projects {
+		if isInDirectory(path, projPath) && len(projPath) > len(projectPath) {
+			projectName = name
+			projectPath = projPath
+		}
+	}
+	return projectName, projectPath
 }
 
 // watchLoop processes events from the file watcher
 func (pw *ProjectWatcher) watchLoop() {
 	for {
 		select {
-		case event := <-pw.watcher.Events:
-			pw.handleFileChange(event.Name)
-		case err := <-pw.watcher.Errors:
+		case event := <-pw.fileEvents:
+			if pw.isSelfFile(event.Name) {
+				pw.scheduleReload(event.Name)
+				continue
+			}
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				pw.handleRemove(event.Name, event.Has(fsnotify.Rename))
+				continue
+			}
+			pw.handleFileChange(event.Name, event.Has(fsnotify.Create))
+		case err := <-pw.fileErrors:
 			log.Printf("Error watching files: %v", err)
+		case reply := <-pw.ping:
+			close(reply)
 		case <-pw.stopChan:
 			return
 		}
 	}
 }
 
-func (pw *ProjectWatcher) handleFileChange(path string) {
-	// Ignore changes to our own data files
-	if filepath.Base(path) == "todos.json" || filepath.Base(path) == "config.json" {
-		return
+// Alive reports whether the watch loop handles events, waiting for it at
+// most timeout
+func (pw *ProjectWatcher) Alive(timeout time.Duration) bool {
+	reply := make(chan struct{})
+	select {
+	case pw.ping <- reply:
+	case <-pw.stopChan:
+		return false
+	case <-time.After(timeout):
+		return false
 	}
+	<-reply
+	return true
+}
 
-	// Check if path should be ignored
-	if pw.ignoreMgr.ShouldIgnore(path) {
+// isDataFile reports whether path is one of our own data files or their
+// temporary copies
+func isDataFile(path string) bool {
+	base := strings.TrimSuffix(filepath.Base(path), ".tmp")
+	return base == "todos.json" || base == "config.json"
+}
+
+// handleFileChange handles a file or directory that was written or created.
+// A path created right after another one was renamed away is its new name.
+func (pw *ProjectWatcher) handleFileChange(path string, created bool) {
+	if isDataFile(path) {
 		return
 	}
 
-	// Get file info
+	// Find which project this file belongs to
+	pw.mutex.Lock()
+	projectName, projectPath := pw.projectFor(path)
+	pw.mutex.Unlock()
+
+	if projectName != "" {
+		ignoreMgr := pw.ignoreFor(projectName)
+
+		// Reload the project's rules when one of its ignore files changes
+		if isIgnoreFile(path) && (ignoreMgr == nil || !ignoreMgr.IsIgnored(filepath.Dir(path), true)) {
+			pw.reloadIgnore(projectName, projectPath)
+			return
+		}
+
+		// Check if path should be ignored
+		if ignoreMgr != nil && ignoreMgr.ShouldIgnore(path) {
+			return
+		}
+	}
+
+	// Get file info, a path removed meanwhile is handled by its remove event
 	info, err := os.Stat(path)
 	if err != nil {
-		// File might have been deleted
-		fmt.Printf("Warning: Could not stat file %s: %v\n", path, err)
 		return
 	}
 
-	// If it's a directory, add a watcher
+	// Keep the TODOs of a renamed file or directory under their new path
+	if created && projectName != "" {
+		if oldPath, ok := pw.takeRename(projectName); ok {
+			pw.rename(projectName, oldPath, path)
+		}
+	}
+
 	if info.IsDir() {
-		if err := pw.watcher.Add(path); err != nil {
-			log.Printf("Warning: Failed to watch new directory %s: %v\n", path, err)
+		if projectName == "" {
+			if err := pw.watcher.Add(path); err != nil {
+				log.Printf("Warning: Failed to watch new directory %s: %v\n", path, err)
+			}
+			return
+		}
+
+		// A directory tree moved into the project is watched as a whole
+		// and scanned for the files it brought along
+		if ignoreMgr := pw.ignoreFor(projectName); ignoreMgr != nil {
+			if err := pw.watchTree(projectName, projectPath, path, ignoreMgr); err != nil {
+				log.Printf("Warning: Failed to watch new directory %s: %v\n", path, err)
+			}
+			pw.scheduler.Notify(projectName)
 		}
 		return
 	}
 
 	// Check file extension - only process source code files
 	ext := strings.ToLower(filepath.Ext(path))
-	if ext == "" {
-		return // No extension, probably not source code
+	if ext == "" || projectName == "" {
+		return
 	}
 
-	// Find which project this file belongs to
-	projectName := ""
-	projectPath := ""
-	for name, projPath := range pw.projects {
-		if isInDirectory(path, projPath) {
-			projectName = name
-			projectPath = projPath
-			break
-		}
+	// A single save produces several events, the scheduler waits for the
+	// project to be quiet before scanning it once
+	pw.scheduler.Notify(projectName)
+}
+
+// scheduledScan scans a project when the scheduler decides it is due
+func (pw *ProjectWatcher) scheduledScan(name string) {
+	pw.mutex.Lock()
+	path, ok := pw.projects[name]
+	pw.mutex.Unlock()
+	if ok {
+		pw.scanProject(name, path)
 	}
+}
 
-	// If we can't identify the project, just record the change
-	// The processChanges method will figure it out later
-	if projectName == "" {
-		// Record the change time
-		pw.mutex.Lock()
-		pw.changes[path] = time.Now()
-		pw.mutex.Unlock()
-		return
+// scanProject runs a scan for a specific project
+func (pw *ProjectWatcher) scanProject(name, path string) {
+	fmt.Printf("Change detected in project '%s'. Scanning for TODOs...\n", name)
+	pw.runScan(name, path)
+}
+
+// runScan scans a project once no other scan of it is running and records
+// the result. Scans of different projects run at the same time, only their
+// store writes are serialized.
+func (pw *ProjectWatcher) runScan(name, path string) error {
+	pw.mutex.Lock()
+	pw.queued++
+	lock, ok := pw.scanLocks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		pw.scanLocks[name] = lock
 	}
+	pw.mutex.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	pw.mutex.Lock()
+	pw.queued--
+	_, watched := pw.projects[name]
+	pw.mutex.Unlock()
 
-	// For known source code files in a known project, process them immediately
-	// instead of waiting for the debounce timer
-	fmt.Printf("Detected change in source file: %s (project: %s)\n", path, projectName)
+	// The project may have been untracked while the scan was queued
+	if !watched {
+		return fmt.Errorf("project %s is not being watched", name)
+	}
 
-	// Only do immediate processing for certain extensions like .go
-	if ext == ".go" {
-		go pw.scanProject(projectName, projectPath)
+	pw.Events.Publish(events.Event{Type: events.ScanStarted, Project: name, Path: path})
+	todos, err := scan.FindTodos(path, name, pw.pluginConfig)
+	var diff store.TodoDiff
+	if err == nil {
+		diff, err = pw.saveScan(name, todos)
+	}
+	if err != nil {
+		log.Printf("Error scanning project %s: %v", name, err)
 	} else {
-		// For other file types, use the normal debounce mechanism
-		pw.mutex.Lock()
-		pw.changes[path] = time.Now()
-		pw.mutex.Unlock()
+		fmt.Printf("Scan complete for project '%s' (%d added, %d removed, %d changed)\n",
+			name, len(diff.Added), len(diff.Removed), len(diff.Changed))
+		for _, event := range events.FromDiff(name, diff) {
+			pw.Events.Publish(event)
+		}
 	}
+	pw.RecordScan(name, time.Now(), err)
+
+	completed := events.Event{Type: events.ScanCompleted, Project: name, Path: path}
+	if err != nil {
+		completed.Error = err.Error()
+	}
+	pw.Events.Publish(completed)
+	return err
 }
 
-// processChanges handles any pending file changes
-func (pw *ProjectWatcher) processChanges() {
-	pw.mutex.Lock()
-	defer pw.mutex.Unlock()
+// saveScan replaces the TODOs of a project in the store with the ones a scan
+// found and returns what changed
+func (pw *ProjectWatcher) saveScan(name string, todos []store.Todo) (store.TodoDiff, error) {
+	pw.storeLock.Lock()
+	defer pw.storeLock.Unlock()
 
-	if len(pw.changes) == 0 {
-		return
+	// Untrack removes the project from the store once it is not watched,
+	// a scan finishing later must not write it back
+	pw.mutex.Lock()
+	_, watched := pw.projects[name]
+	pw.mutex.Unlock()
+	if !watched {
+		return store.TodoDiff{}, fmt.Errorf("project %s is not being watched", name)
 	}
 
-	now := time.Now()
-	projectsToScan := make(map[string]bool)
+	before := pw.storedTodos(name)
+	if err := scan.SaveTodos(name, todos, pw.storeFile); err != nil {
+		return store.TodoDiff{}, err
+	}
+	return store.Diff(before, pw.storedTodos(name)), nil
+}
 
-	// Find changes that have settled (not modified recently)
-	for path, lastChange := range pw.changes {
-		if now.Sub(lastChange) >= pw.debounceTime {
-			// Find which project this file belongs to
-			for name, projectPath := range pw.projects {
-				if isInDirectory(path, projectPath) {
-					projectsToScan[name] = true
-					break
-				}
-			}
-			delete(pw.changes, path)
-		}
+// storedTodos returns the TODOs of a project in the store, the caller holds
+// the store lock
+func (pw *ProjectWatcher) storedTodos(name string) []store.Todo {
+	st, err := store.LoadStore(pw.storeFile)
+	if err != nil {
+		return nil
 	}
+	todos, _ := st.GetProject(name)
+	return todos
+}
 
-	// Trigger scans for affected projects
-	for name := range projectsToScan {
-		projectPath := pw.projects[name]
-		go pw.scanProject(name, projectPath)
+// RecordScan records the outcome of a scan of a project
+func (pw *ProjectWatcher) RecordScan(name string, at time.Time, err error) {
+	result := ScanResult{Time: at}
+	if err != nil {
+		result.Error = err.Error()
 	}
+
+	pw.mutex.Lock()
+	pw.scans[name] = result
+	pw.mutex.Unlock()
 }
 
-// scanProject runs a scan for a specific project
-func (pw *ProjectWatcher) scanProject(name, path string) {
-	// Use a lock to prevent multiple simultaneous scans of the same project
-	pw.scanningLock.Lock()
-	defer pw.scanningLock.Unlock()
+// ProjectStatus describes a watched project
+type ProjectStatus struct {
+	Name      string     `json:"name"`
+	Path      string     `json:"path"`
+	LastScan  *time.Time `json:"last_scan,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	Polled    bool       `json:"polled,omitempty"`     // checked by polling instead of inotify
+	PollCause string     `json:"poll_cause,omitempty"` // why the project is polled
+}
 
-	fmt.Printf("Change detected in project '%s'. Scanning for TODOs...\n", name)
+// Projects returns the watched projects sorted by name
+func (pw *ProjectWatcher) Projects() []ProjectStatus {
+	pw.mutex.Lock()
+	defer pw.mutex.Unlock()
 
-	err := scan.RunScan(path, name, pw.pluginConfig, pw.storeFile)
-	if err != nil {
-		log.Printf("Error scanning project %s: %v", name, err)
-	} else {
-		fmt.Printf("Scan complete for project '%s'\n", name)
+	projects := make([]ProjectStatus, 0, len(pw.projects))
+	for name, path := range pw.projects {
+		status := ProjectStatus{Name: name, Path: path}
+		status.PollCause, status.Polled = pw.pollReason(name)
+		if result, ok := pw.scans[name]; ok {
+			scanTime := result.Time
+			status.LastScan = &scanTime
+			status.LastError = result.Error
+		}
+		projects = append(projects, status)
 	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+	return projects
+}
+
+// QueueDepth returns the number of projects, scans and store updates waiting
+// to be processed
+func (pw *ProjectWatcher) QueueDepth() int {
+	pending := pw.scheduler.Pending() + pw.pendingUpdates()
+	pw.mutex.Lock()
+	defer pw.mutex.Unlock()
+	return pending + pw.queued
 }
 
 // isInDirectory checks if a path is inside another directory
@@ -0,0 +1,189 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// testWatcher is a watcher fed with events by the test, on a fake clock.
+// Scans are recorded instead of run.
+type testWatcher struct {
+	*ProjectWatcher
+	t      *testing.T
+	events chan fsnotify.Event
+	clock  *fakeClock
+	scans  *scanLog
+}
+
+func newTestWatcher(t *testing.T) *testWatcher {
+	t.Helper()
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan fsnotify.Event)
+	clock := newFakeClock()
+	scans := newScanLog(clock)
+
+	pw := newProjectWatcher(fsWatcher, events, make(chan error), clock, filepath.Join(t.TempDir(), "todos.json"), "")
+	pw.scheduler = NewScheduler(clock, time.Second, 0, scans.scan)
+	go pw.watchLoop()
+	t.Cleanup(func() { pw.Stop() })
+	return &testWatcher{ProjectWatcher: pw, t: t, events: events, clock: clock, scans: scans}
+}
+
+// addProject watches a new project holding files, keyed by their path
+// relative to the project, and returns its directory
+func (tw *testWatcher) addProject(name string, files map[string]string) string {
+	tw.t.Helper()
+	root := tw.t.TempDir()
+	for rel, content := range files {
+		writeFile(tw.t, filepath.Join(root, filepath.FromSlash(rel)), content)
+	}
+	tw.mutex.Lock()
+	tw.projects[name] = root
+	tw.mutex.Unlock()
+	if err := tw.watchProject(name, root); err != nil {
+		tw.t.Fatal(err)
+	}
+	return root
+}
+
+// send hands the watch loop an event and waits until it was handled
+func (tw *testWatcher) send(op fsnotify.Op, path string) {
+	tw.t.Helper()
+	tw.events <- fsnotify.Event{Name: path, Op: op}
+	if !tw.Alive(time.Second) {
+		tw.t.Fatal("the watch loop stopped")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherCoalescesEvents(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+
+	// Saving a file and then another one produces a burst of events
+	tw.send(fsnotify.Create, filepath.Join(root, "a.go"))
+	for i := 0; i < 3; i++ {
+		tw.clock.Advance(200 * time.Millisecond)
+		tw.send(fsnotify.Write, filepath.Join(root, "a.go"))
+	}
+	tw.send(fsnotify.Write, filepath.Join(root, "b.go"))
+	tw.clock.Advance(999 * time.Millisecond)
+	expectScans(t, tw.scans, "p")
+
+	// The project is scanned once it has been quiet for the quiet period
+	tw.clock.Advance(time.Millisecond)
+	expectScans(t, tw.scans, "p", 1600*time.Millisecond)
+	tw.clock.Advance(time.Minute)
+	expectScans(t, tw.scans, "p", 1600*time.Millisecond)
+}
+
+func TestWatcherSkipsIgnoredFiles(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{
+		".ttignore":  "gen/\n",
+		"gen/x.go":   "package gen\n",
+		"Makefile":   "all:\n",
+		"todos.json": "{}\n",
+	})
+
+	for _, rel := range []string{"gen/x.go", "Makefile", "todos.json", "todos.json.tmp", "deleted.go"} {
+		tw.send(fsnotify.Write, filepath.Join(root, filepath.FromSlash(rel)))
+	}
+	tw.clock.Advance(time.Minute)
+	expectScans(t, tw.scans, "p")
+
+	// Files outside of the projects are not scanned either
+	outside := filepath.Join(t.TempDir(), "x.go")
+	writeFile(t, outside, "package x\n")
+	tw.send(fsnotify.Write, outside)
+	tw.clock.Advance(time.Minute)
+	expectScans(t, tw.scans, "p")
+}
+
+func TestWatcherReloadsIgnoreFile(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", map[string]string{
+		".ttignore": "",
+		"gen/x.go":  "package gen\n",
+	})
+
+	tw.send(fsnotify.Write, filepath.Join(root, "gen", "x.go"))
+	tw.clock.Advance(time.Second)
+	expectScans(t, tw.scans, "p", time.Second)
+
+	// Ignoring gen rescans the project once and stops watching gen
+	writeFile(t, filepath.Join(root, ".ttignore"), "gen/\n")
+	tw.send(fsnotify.Write, filepath.Join(root, ".ttignore"))
+	if !tw.ignoreFor("p").IsIgnored(filepath.Join(root, "gen"), true) {
+		t.Fatal("gen is not ignored after the reload")
+	}
+	tw.clock.Advance(time.Second)
+	expectScans(t, tw.scans, "p", time.Second, 2*time.Second)
+
+	// Changes under gen, ignore files included, are skipped from now on
+	tw.send(fsnotify.Write, filepath.Join(root, "gen", "x.go"))
+	writeFile(t, filepath.Join(root, "gen", ".ttignore"), "!x.go\n")
+	tw.send(fsnotify.Create, filepath.Join(root, "gen", ".ttignore"))
+	tw.clock.Advance(time.Minute)
+	expectScans(t, tw.scans, "p", time.Second, 2*time.Second)
+	if !tw.ignoreFor("p").IsIgnored(filepath.Join(root, "gen", "x.go"), false) {
+		t.Error("an ignore file in an ignored directory was loaded")
+	}
+
+	// Removing the rule watches and scans gen again
+	writeFile(t, filepath.Join(root, ".ttignore"), "")
+	tw.send(fsnotify.Write, filepath.Join(root, ".ttignore"))
+	tw.clock.Advance(time.Second)
+	expectScans(t, tw.scans, "p", time.Second, 2*time.Second, 63*time.Second)
+	if !slices.Contains(tw.watcher.WatchList(), filepath.Join(root, "gen")) {
+		t.Error("gen is not watched after the rule was removed")
+	}
+}
+
+func TestWatcherWatchesNewDirectories(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("p", nil)
+
+	// A directory tree moved into the project is watched and scanned
+	writeFile(t, filepath.Join(root, "sub", "deep", "x.go"), "package deep\n")
+	tw.send(fsnotify.Create, filepath.Join(root, "sub"))
+	for _, dir := range []string{"sub", filepath.Join("sub", "deep")} {
+		if !slices.Contains(tw.watcher.WatchList(), filepath.Join(root, dir)) {
+			t.Errorf("%s is not watched", dir)
+		}
+	}
+	tw.clock.Advance(time.Second)
+	expectScans(t, tw.scans, "p", time.Second)
+}
+
+func TestWatcherScanAll(t *testing.T) {
+	tw := newTestWatcher(t)
+	root := tw.addProject("a", map[string]string{"x.go": "package x\n"})
+	tw.addProject("b", nil)
+
+	// A change waiting for the quiet period is scanned with the others
+	tw.send(fsnotify.Write, filepath.Join(root, "x.go"))
+	tw.ScanAll()
+	tw.clock.Advance(0)
+	expectScans(t, tw.scans, "a", 0)
+	expectScans(t, tw.scans, "b", 0)
+	tw.clock.Advance(time.Minute)
+	expectScans(t, tw.scans, "a", 0)
+}
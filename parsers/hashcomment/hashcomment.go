@@ -0,0 +1,135 @@
+// Package hashcomment is a compiled-in parser for languages using # line
+// comments (shell scripts, YAML and TOML). Build tt with -tags hashcomment to
+// include it.
+package hashcomment
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"Ttracker/pkg/ttapi"
+)
+
+func init() {
+	ttapi.Register("hash-comment", func() ttapi.Scanner { return &Parser{} })
+}
+
+// Parser finds TODOs in # comments
+type Parser struct {
+	Matcher *ttapi.Matcher
+}
+
+// SupportedExtensions returns file extensions supported by this parser
+func (p *Parser) SupportedExtensions() []string {
+	return []string{".sh", ".bash", ".zsh", ".yaml", ".yml", ".toml"}
+}
+
+// SetMatcher sets the keywords the parser looks for
+func (p *Parser) SetMatcher(m *ttapi.Matcher) {
+	p.Matcher = m
+}
+
+// ParseFile scans the given file and returns the TODOs in its comments. A
+// TODO on a line of its own continues over the following comment lines until
+// a blank comment line or another keyword.
+func (p *Parser) ParseFile(filePath string) ([]ttapi.Todo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %v", filePath, err)
+	}
+	defer file.Close()
+
+	var todos []ttapi.Todo
+	var current *ttapi.Todo
+	var continues bool
+
+	flush := func() {
+		if current != nil {
+			todos = append(todos, *current)
+			current = nil
+		}
+	}
+
+	lineNumber := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if lineNumber == 1 && strings.HasPrefix(line, "#!") {
+			continue
+		}
+
+		start := commentStart(line)
+		if start < 0 {
+			flush()
+			continue
+		}
+		trailing := strings.TrimSpace(line[:start]) != ""
+		text := strings.TrimSpace(strings.TrimLeft(line[start:], "#"))
+
+		if kw, found := p.matcher().Match(text); found {
+			flush()
+			current = &ttapi.Todo{
+				Comment:    text,
+				LineNumber: lineNumber,
+				Keyword:    kw.Name,
+				Severity:   kw.Severity,
+			}
+			continues = !trailing
+			if trailing {
+				flush()
+			}
+			continue
+		}
+
+		if current == nil || !continues || trailing || text == "" {
+			flush()
+			continue
+		}
+		current.EndLine = lineNumber
+		current.Comment += "\n" + text
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", filePath, err)
+	}
+	return todos, nil
+}
+
+func (p *Parser) matcher() *ttapi.Matcher {
+	if p.Matcher == nil {
+		p.Matcher = ttapi.DefaultMatcher()
+	}
+	return p.Matcher
+}
+
+// commentStart returns the index of the # starting a comment on line, or -1.
+// A # inside quotes or in the middle of a word (as in ${#var}) does not start
+// a comment.
+func commentStart(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '\\':
+			i++
+		case c == '#':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return i
+			}
+		}
+	}
+	return -1
+}
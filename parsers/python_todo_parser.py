@@ -2,7 +2,7 @@
 """
 Python TODO Parser for Ttracker
 
-This script parses Python files for TODO and FIXME comments and outputs them
+This script parses Python files for TODO comments and outputs them
 in the format expected by Ttracker's plugin system.
 
 Usage:
@@ -16,8 +16,10 @@ import ast
 import tokenize
 from io import BytesIO
 
-# Regular expression to match TODO and FIXME comments
-TODO_PATTERN = re.compile(r'^\s*#\s*(TODO|FIXME)[:]*\s*(.*)', re.IGNORECASE)
+# Regular expression to match TODO keywords. Ttracker passes the configured
+# keywords in TT_KEYWORD_PATTERN so every parser matches the same way; the
+# first group captures the keyword, which must start the comment text.
+TODO_PATTERN = re.compile(os.environ.get("TT_KEYWORD_PATTERN", r'(?i)^[\s/*#;!<%-]*\b(TODO|FIXME)\b'))
 
 class TodoVisitor(ast.NodeVisitor):
     """AST visitor that tracks function and class contexts."""
@@ -82,28 +84,81 @@ def parse_file(file_path):
         function_ranges = []
     
     # Now tokenize the file to get comments
+    comments = []  # List of (line_number, comment_text, is_full_line)
     with open(file_path, 'rb') as f:
         for tok in tokenize.tokenize(f.readline):
             # Only process comments
             if tok.type == tokenize.COMMENT:
-                line_num = tok.start[0]
-                comment = tok.string.lstrip('#').strip()
-                
-                # Check if it's a TODO/FIXME comment
-                match = TODO_PATTERN.match(f"#{comment}")
-                if match:
-                    todo_type = match.group(1)
-                    todo_text = match.group(2).strip()
-                    
-                    # Find which function this comment belongs to
-                    function_name = get_function_for_line(function_ranges, line_num)
-                    
-                    # Output in format: <lineNumber>: <comment> [function]
-                    output = f"{line_num}: # {todo_type}: {todo_text}"
-                    if function_name:
-                        output += f" [in function {function_name}]"
-                    
-                    print(output)
+                full_line = tok.line.strip().startswith('#')
+                comments.append((tok.start[0], tok.string.lstrip('#').strip(), full_line))
+
+    for start, end, lines in merge_todo_comments(comments):
+        # Find which function this comment belongs to
+        function_name = get_function_for_line(function_ranges, start)
+
+        # Output in format: <line>[-<endLine>]: <comment> [function]
+        # Line breaks and backslashes are escaped so each TODO stays on one line
+        text = "\n".join(f"# {line}" for line in lines)
+        text = text.replace("\\", "\\\\").replace("\n", "\\n")
+        line_range = f"{start}-{end}" if end > start else f"{start}"
+        output = f"{line_range}: {text}"
+        if function_name:
+            output += f" [in function {function_name}]"
+
+        print(output)
+
+def merge_todo_comments(comments):
+    """Group TODO comments with the full-line comments that continue them.
+
+    A TODO continues on the following consecutive comment lines until a
+    blank comment line or a line holding another keyword. TODOs trailing
+    code on the same line are never continued. A keyword that starts a line
+    in the middle of a sentence is a wrapped word, unless a colon or
+    parentheses follow it.
+    """
+    todos = []
+    current = None
+    previous = None
+    for line_num, comment, full_line in comments:
+        match = TODO_PATTERN.match(comment)
+        if match and continues_sentence(previous, line_num, full_line) and not is_tag(comment[match.end(1):]):
+            match = None
+        previous = (line_num, comment, full_line)
+        if match:
+            if current:
+                todos.append(current)
+            current = (line_num, line_num, [comment])
+            if not full_line:
+                todos.append(current)
+                current = None
+            continue
+
+        if current is None:
+            continue
+
+        start, end, lines = current
+        if not full_line or line_num != end + 1 or not comment:
+            todos.append(current)
+            current = None
+            continue
+        current = (start, line_num, lines + [comment])
+
+    if current:
+        todos.append(current)
+    return todos
+
+def continues_sentence(previous, line_num, full_line):
+    """Whether a comment line carries on the sentence of the one before it."""
+    if previous is None or not full_line:
+        return False
+    prev_line, prev_comment, prev_full_line = previous
+    return (prev_full_line and prev_line == line_num - 1 and prev_comment != ""
+            and prev_comment[-1] not in ".:;!?")
+
+def is_tag(rest):
+    """Whether the text after a keyword makes it a tag such as TODO: or TODO(alice)."""
+    rest = rest.lstrip(" \t")
+    return rest == "" or rest[0] in ":(["
 
 if __name__ == "__main__":
     if len(sys.argv) != 2:
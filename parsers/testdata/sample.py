@@ -0,0 +1,25 @@
+"""Fixture for the python-standard parser conformance tests."""
+
+# TODO: module level
+import os
+
+
+class Greeter:
+    # FIXME: class body comment
+
+    def greet(self, name):
+        # TODO: handle empty names
+        #   and names with only whitespace
+        return f"hello {name}"
+
+    def farewell(self):
+        x = 1  # todo: trailing comment
+        return x
+
+
+def helper():
+    # Just a note, nothing to track here
+    # TODO(alice): nested function below
+    def inner():
+        pass
+    return inner
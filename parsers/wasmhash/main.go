@@ -0,0 +1,42 @@
+// Command wasmhash is the hash-comment parser packaged as a WebAssembly
+// plugin, an example of a parser that runs sandboxed in process. Build it
+// with:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -o parsers/wasmhash/hashcomment.wasm ./parsers/wasmhash
+//
+// and install it with tt plugins install parsers/wasmhash.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"Ttracker/parsers/hashcomment"
+	"Ttracker/pkg/ttapi"
+)
+
+// escaper keeps each TODO on one line of output
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s <file_path>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	parser := &hashcomment.Parser{Matcher: ttapi.MatcherFromEnv()}
+	todos, err := parser.ParseFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for _, todo := range todos {
+		lines := fmt.Sprint(todo.LineNumber)
+		if todo.EndLine > todo.LineNumber {
+			lines = fmt.Sprintf("%d-%d", todo.LineNumber, todo.EndLine)
+		}
+		fmt.Printf("%s: %s\n", lines, escaper.Replace(todo.Comment))
+	}
+}
@@ -0,0 +1,189 @@
+// Package ttapi is the public API for parsers compiled into Ttracker.
+//
+// A parser implements Scanner and registers a function creating it from an
+// init function:
+//
+//	func init() {
+//		ttapi.Register("shell-hash", func() ttapi.Scanner { return &Parser{} })
+//	}
+//
+// The package holding the parser is then linked into the tt binary with a
+// blank import, usually in a file guarded by a build tag so that it is only
+// included on request (see plugins_hashcomment.go at the repository root).
+// Every scan creates its own parsers, so a parser's state such as its
+// keywords is never shared between scans. Registered parsers run in process
+// and are preferred over external plugins handling the same extensions.
+//
+// The types of this package are Ttracker's contract with parsers: they only
+// change in backward compatible ways, whatever Ttracker stores internally.
+package ttapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"Ttracker/internal/keywords"
+)
+
+// Todo is a TODO found by a parser
+type Todo struct {
+	// LineNumber is the line the TODO starts on, counted from 1
+	LineNumber int
+	// EndLine is the last line of a TODO spanning several lines, 0 otherwise
+	EndLine int
+	// Comment is the text of the TODO, its lines joined with "\n"
+	Comment string
+	// Function is the function the TODO is in, empty to let Ttracker find it
+	Function string
+	// Keyword and Severity are those of the keyword that marks the TODO
+	Keyword  string
+	Severity string
+}
+
+// Keyword is a TODO keyword and its severity
+type Keyword struct {
+	Name     string
+	Severity string
+}
+
+// Config lists the keywords to look for and how they are matched
+type Config struct {
+	Keywords      []Keyword // the default keywords when empty
+	CaseSensitive bool
+	WordBoundary  bool // keywords must stand alone as a word
+}
+
+// Matcher finds the configured TODO keywords in comment text
+type Matcher struct {
+	m *keywords.Matcher
+}
+
+// NewMatcher compiles a matcher for cfg
+func NewMatcher(cfg Config) (*Matcher, error) {
+	kc := keywords.Config{CaseSensitive: &cfg.CaseSensitive, WordBoundary: &cfg.WordBoundary}
+	for _, kw := range cfg.Keywords {
+		kc.Keywords = append(kc.Keywords, keywords.Keyword{Name: kw.Name, Severity: kw.Severity})
+	}
+	m, err := keywords.NewMatcher(kc)
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{m: m}, nil
+}
+
+// DefaultMatcher returns a matcher for the default keywords, for parsers
+// used before SetMatcher is called
+func DefaultMatcher() *Matcher {
+	return &Matcher{m: keywords.DefaultMatcher()}
+}
+
+// MatcherFromEnv returns a matcher for the keywords Ttracker passes to
+// external parsers in their environment, for parsers built as separate
+// programs or WebAssembly modules. It falls back to the default keywords.
+func MatcherFromEnv() *Matcher {
+	var cfg keywords.Config
+	if err := json.Unmarshal([]byte(os.Getenv(keywords.EnvConfig)), &cfg); err != nil {
+		return DefaultMatcher()
+	}
+	m, err := keywords.NewMatcher(cfg)
+	if err != nil {
+		return DefaultMatcher()
+	}
+	return &Matcher{m: m}
+}
+
+// Match returns the keyword comment text starts with, after its comment
+// markers and whitespace
+func (m *Matcher) Match(text string) (Keyword, bool) {
+	kw, ok := m.m.Match(text)
+	return Keyword{Name: kw.Name, Severity: kw.Severity}, ok
+}
+
+// Config returns the configuration the matcher was built from
+func (m *Matcher) Config() Config {
+	kc := m.m.Config()
+	cfg := Config{CaseSensitive: kc.IsCaseSensitive(), WordBoundary: kc.IsWordBoundary()}
+	for _, kw := range kc.Keywords {
+		cfg.Keywords = append(cfg.Keywords, Keyword{Name: kw.Name, Severity: kw.Severity})
+	}
+	return cfg
+}
+
+// Scanner is the interface that every compiled-in parser must implement
+type Scanner interface {
+	// ParseFile scans the given file and returns a slice of todo items
+	ParseFile(filePath string) ([]Todo, error)
+
+	// SupportedExtensions returns the file extensions, with their leading
+	// dot, that the parser can handle
+	SupportedExtensions() []string
+}
+
+// KeywordScanner is implemented by parsers that want to match the keywords
+// configured for the project being scanned instead of the defaults
+type KeywordScanner interface {
+	Scanner
+
+	// SetMatcher is called before a scan with the keywords to look for
+	SetMatcher(m *Matcher)
+}
+
+// Factory creates a parser
+type Factory func() Scanner
+
+// Registration is a parser added with Register
+type Registration struct {
+	Name string
+	New  Factory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a parser available to Ttracker under name. It is meant to
+// be called from init and panics if name is empty, factory is nil or a parser
+// with the same name is already registered.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("ttapi: Register called with an empty name")
+	}
+	if factory == nil {
+		panic("ttapi: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("ttapi: Register called twice for parser %s", name))
+	}
+	registry[name] = factory
+}
+
+// Lookup returns the factory of the parser registered under name
+func Lookup(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// Registered lists the registered parsers sorted by name
+func Registered() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	regs := make([]Registration, 0, len(registry))
+	for name, factory := range registry {
+		regs = append(regs, Registration{Name: name, New: factory})
+	}
+	sort.Slice(regs, func(i, j int) bool {
+		return regs[i].Name < regs[j].Name
+	})
+	return regs
+}
@@ -0,0 +1,6 @@
+//go:build hashcomment
+
+package main
+
+// Compiled-in parser for # comments, included with -tags hashcomment
+import _ "Ttracker/parsers/hashcomment"